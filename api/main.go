@@ -1,57 +1,139 @@
 package main
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"log/slog"
+	"os"
 	"weather/project/client"
 	"weather/project/config"
 	"weather/project/handler"
+	"weather/project/logging"
+	"weather/project/ratelimit"
 	"weather/project/repository"
 	"weather/project/server"
 	"weather/project/service"
+	"weather/project/telegram"
+	"weather/project/tracing"
 )
 
-func main() {
-	log.SetFlags(log.LstdFlags | log.Lshortfile)
+func fatal(msg string, err error) {
+	slog.Error(msg, slog.Any("error", err))
+	os.Exit(1)
+}
 
+func main() {
 	cfg, err := config.LoadConfig(".")
 	if err != nil {
-		log.Fatalf("FATAL: Could not load .env config: %v", err)
+		fatal("Could not load .env config", err)
+	}
+
+	logger, err := logging.New(os.Stdout, cfg.LogLevel, cfg.LogFormat, cfg.Secrets()...)
+	if err != nil {
+		fatal("Could not configure logging", err)
 	}
-	log.Println("Configuration loaded successfully.")
+	slog.SetDefault(logger)
+	slog.Info("Configuration loaded successfully.")
+
+	shutdownTracing, err := tracing.Init(context.Background(), cfg)
+	if err != nil {
+		fatal("Could not initialize tracing", err)
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			slog.Error("Failed to flush traces", slog.Any("error", err))
+		}
+	}()
 
 	db, err := repository.InitDB(cfg)
 	if err != nil {
-		log.Fatalf("FATAL: Could not initialize database: %v", err)
+		fatal("Could not initialize database", err)
 	}
-	log.Println("Database initialized successfully.")
+	slog.Info("Database initialized successfully.")
 
 	if err := repository.MigrateDB(db); err != nil {
-		log.Fatalf("FATAL: Could not migrate database: %v", err)
+		fatal("Could not migrate database", err)
 	}
-	log.Println("Database migration completed successfully.")
+	slog.Info("Database migration completed successfully.")
 
 	weatherAPIClient := client.NewWeatherAPIClient(cfg)
 
 	subscriptionRepo := repository.NewSubscriptionRepository(db)
+	apiKeyRepo := repository.NewAPIKeyRepository(db)
+	privacyRepo := repository.NewPrivacyRepository(db)
+	observationRepo := repository.NewObservationRepository(db)
+	webhookDeliveryRepo := repository.NewWebhookDeliveryRepository(db)
 
 	tokenSvc := service.NewTokenService()
 	emailSvc := service.NewEmailService(cfg) // Pass cfg for AppBaseURL etc.
-	subscriptionSvc := service.NewSubscriptionService(subscriptionRepo, tokenSvc, emailSvc)
-	weatherSvc := service.NewWeatherService(weatherAPIClient)
+	locationSvc := service.NewLocationService(weatherAPIClient)
+	notifiers := []service.Notifier{
+		service.NewEmailNotifier(emailSvc),
+		service.NewWebhookNotifier(cfg, webhookDeliveryRepo, tokenSvc),
+		service.NewSlackNotifier(cfg),
+		service.NewDiscordNotifier(cfg),
+	}
+	var telegramClient *client.TelegramClient
+	if cfg.TelegramBotToken != "" {
+		telegramClient = client.NewTelegramClient(cfg)
+		notifiers = append(notifiers, service.NewTelegramNotifier(telegramClient))
+	}
+	notificationSvc := service.NewNotificationService(cfg, notifiers...)
+	subscriptionSvc := service.NewSubscriptionService(subscriptionRepo, tokenSvc, notificationSvc, locationSvc)
+	weatherSvc := service.NewWeatherService(weatherAPIClient, observationRepo, cfg.WeatherBatchConcurrency, cfg.HistoryArchiveMinHours, cfg.WeatherCacheTTL)
+	weatherStream := service.NewWeatherStream(weatherSvc, cfg.WeatherStreamPollInterval)
+	astronomySvc := service.NewAstronomyService(weatherAPIClient, observationRepo)
+	subscriptionAdminSvc := service.NewSubscriptionAdminService(subscriptionRepo, tokenSvc, webhookDeliveryRepo)
+	apiKeySvc := service.NewAPIKeyService(apiKeyRepo, tokenSvc, cfg.APIKeyDefaultDailyQuota)
+	privacySvc := service.NewPrivacyService(privacyRepo, tokenSvc, emailSvc, cfg.PrivacyTokenTTL)
+	retentionSvc := service.NewRetentionService(privacyRepo, cfg.DataRetentionPeriod)
+	updateScheduler := service.NewWeatherUpdateScheduler(subscriptionRepo, weatherSvc, notificationSvc, cfg.DailyUpdateHour, cfg.WeatherUpdateWorkers)
+	healthSvc := service.NewHealthService(cfg.HealthCheckTimeout,
+		service.NewHealthCheck("database", func(ctx context.Context) error { return repository.PingDB(ctx, db) }),
+		service.NewCachedHealthCheck(service.NewHealthCheck("weather_provider", weatherAPIClient.Ping), cfg.HealthWeatherCacheTTL),
+		service.NewHealthCheck("mail", emailSvc.Ping),
+	)
 
 	weatherHdlr := handler.NewWeatherHandler(weatherSvc)
+	weatherStreamHdlr := handler.NewWeatherStreamHandler(weatherStream)
+	locationHdlr := handler.NewLocationHandler(locationSvc)
+	astronomyHdlr := handler.NewAstronomyHandler(astronomySvc)
 	subscriptionHdlr := handler.NewSubscriptionHandler(subscriptionSvc)
-	log.Println("Dependencies initialized.")
+	healthHdlr := handler.NewHealthHandler(healthSvc)
+	apiKeyHdlr := handler.NewAPIKeyHandler(apiKeySvc)
+	subscriptionAdminHdlr := handler.NewSubscriptionAdminHandler(subscriptionAdminSvc)
+	privacyHdlr := handler.NewPrivacyHandler(privacySvc)
+	slog.Info("Dependencies initialized.")
 
-	router := server.SetupRouter(weatherHdlr, subscriptionHdlr)
-	log.Println("HTTP router setup complete.")
+	router, err := server.SetupRouter(cfg, server.RouterDeps{
+		WeatherHandler:      weatherHdlr,
+		WeatherStream:       weatherStreamHdlr,
+		LocationHandler:     locationHdlr,
+		AstronomyHandler:    astronomyHdlr,
+		SubscriptionHandler: subscriptionHdlr,
+		HealthHandler:       healthHdlr,
+		APIKeyHandler:       apiKeyHdlr,
+		APIKeyService:       apiKeySvc,
+		SubscriptionAdmin:   subscriptionAdminHdlr,
+		PrivacyHandler:      privacyHdlr,
+		RateLimitStore:      ratelimit.NewMemoryStore(),
+	})
+	if err != nil {
+		fatal("Could not set up HTTP router", err)
+	}
+	slog.Info("HTTP router setup complete.")
+
+	go retentionSvc.Run(context.Background(), cfg.DataPurgeInterval)
+	go updateScheduler.Run(context.Background(), cfg.WeatherUpdateInterval)
+	if telegramClient != nil {
+		go telegram.NewBot(telegramClient, weatherSvc, subscriptionSvc).Run(context.Background())
+	}
 
 	appAddress := fmt.Sprintf(":%s", cfg.AppPort)
-	log.Printf("Starting Weather API server on %s", appAddress)
-	log.Printf("API Documentation available at http://localhost:%s/swagger.yaml", cfg.AppPort)
+	slog.Info("Starting Weather API server", slog.String("address", appAddress))
+	slog.Info(fmt.Sprintf("API Documentation available at http://localhost:%s/docs", cfg.AppPort))
 
 	if err := router.Run(appAddress); err != nil {
-		log.Fatalf("FATAL: Could not start server on %s: %v", appAddress, err)
+		fatal(fmt.Sprintf("Could not start server on %s", appAddress), err)
 	}
 }
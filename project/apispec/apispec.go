@@ -0,0 +1,74 @@
+package apispec
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// CurrentPrefix is where the public API is documented.
+	CurrentPrefix = "/api/v1"
+	// LegacyPrefix serves the same operations, deprecated.
+	LegacyPrefix = "/api"
+)
+
+// Spec is the OpenAPI document describing every route of the server. It is
+// the contract the validation middleware enforces, so keep it in sync. Only
+// versioned paths are written down; Load derives the legacy aliases.
+//
+//go:embed openapi.yaml
+var Spec []byte
+
+// SwaggerUI is a page rendering the served document with Swagger UI. Its
+// assets are pinned to one swagger-ui-dist release; go generate writes
+// their integrity digests.
+//
+//go:generate go run sri_gen.go
+//go:embed swagger_ui.html
+var SwaggerUI []byte
+
+func Load() (*openapi3.T, error) {
+	loader := openapi3.NewLoader()
+	doc, err := loader.LoadFromData(Spec)
+	if err != nil {
+		return nil, fmt.Errorf("apispec.Load: failed to parse OpenAPI document: %w", err)
+	}
+	addLegacyAliases(doc)
+	if err := doc.Validate(context.Background()); err != nil {
+		return nil, fmt.Errorf("apispec.Load: invalid OpenAPI document: %w", err)
+	}
+	return doc, nil
+}
+
+// Render serializes doc, aliases included, for serving to clients.
+func Render(doc *openapi3.T) ([]byte, error) {
+	data, err := yaml.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("apispec.Render: %w", err)
+	}
+	return data, nil
+}
+
+// addLegacyAliases documents every CurrentPrefix path again under
+// LegacyPrefix with its operations marked deprecated.
+func addLegacyAliases(doc *openapi3.T) {
+	for _, path := range doc.Paths.InMatchingOrder() {
+		if !strings.HasPrefix(path, CurrentPrefix+"/") {
+			continue
+		}
+		item := doc.Paths.Value(path)
+		alias := *item
+		for method, op := range item.Operations() {
+			legacy := *op
+			legacy.Deprecated = true
+			legacy.OperationID = op.OperationID + "Legacy"
+			alias.SetOperation(method, &legacy)
+		}
+		doc.Paths.Set(LegacyPrefix+strings.TrimPrefix(path, CurrentPrefix), &alias)
+	}
+}
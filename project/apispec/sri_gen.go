@@ -0,0 +1,61 @@
+//go:build ignore
+
+// sri_gen pins the Subresource Integrity of the Swagger UI assets: it
+// downloads every unpkg asset referenced by swagger_ui.html and writes its
+// sha384 digest into the tag's integrity attribute. Run it after changing
+// the pinned swagger-ui-dist version.
+package main
+
+import (
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+)
+
+const page = "swagger_ui.html"
+
+var asset = regexp.MustCompile(`((?:src|href)="(https://unpkg\.com/[^"]+)")(?: integrity="[^"]*")?`)
+
+func main() {
+	html, err := os.ReadFile(page)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var fetchErr error
+	html = asset.ReplaceAllFunc(html, func(match []byte) []byte {
+		groups := asset.FindSubmatch(match)
+		digest, err := digest(string(groups[2]))
+		if err != nil {
+			fetchErr = err
+			return match
+		}
+		return fmt.Appendf(nil, `%s integrity="sha384-%s"`, groups[1], digest)
+	})
+	if fetchErr != nil {
+		log.Fatal(fetchErr)
+	}
+	if err := os.WriteFile(page, html, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func digest(url string) (string, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return "", fmt.Errorf("fetching %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("fetching %s: %s", url, resp.Status)
+	}
+	sum := sha512.New384()
+	if _, err := io.Copy(sum, resp.Body); err != nil {
+		return "", fmt.Errorf("reading %s: %w", url, err)
+	}
+	return base64.StdEncoding.EncodeToString(sum.Sum(nil)), nil
+}
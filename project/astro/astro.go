@@ -0,0 +1,75 @@
+// Package astro computes sunrise, sunset and the moon phase locally. The
+// results are accurate to a few minutes, which is enough to stand in for the
+// weather provider's astronomy data while it is unavailable.
+package astro
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	unixEpochJD  = 2440587.5
+	j2000JD      = 2451545.0
+	secondsInDay = 86400
+
+	// synodicMonth is the mean time between two new moons, in days.
+	synodicMonth = 29.530588853
+	// referenceNewMoonJD is the new moon of 6 January 2000, 18:14 UTC.
+	referenceNewMoonJD = 2451550.1
+)
+
+func julianDay(t time.Time) float64 {
+	return float64(t.Unix())/secondsInDay + unixEpochJD
+}
+
+func fromJulianDay(jd float64) time.Time {
+	return time.Unix(int64(math.Round((jd-unixEpochJD)*secondsInDay)), 0).UTC()
+}
+
+func sin(deg float64) float64 { return math.Sin(deg * math.Pi / 180) }
+func cos(deg float64) float64 { return math.Cos(deg * math.Pi / 180) }
+
+// SunTimes returns the UTC sunrise and sunset on the given calendar day at
+// lat/lon (degrees, east positive). ok is false during polar day or night,
+// when the sun does not cross the horizon.
+func SunTimes(date time.Time, lat, lon float64) (sunrise, sunset time.Time, ok bool) {
+	midnight := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	n := math.Ceil(julianDay(midnight) - j2000JD + 0.0008)
+
+	meanNoon := n - lon/360
+	anomaly := math.Mod(357.5291+0.98560028*meanNoon, 360)
+	center := 1.9148*sin(anomaly) + 0.0200*sin(2*anomaly) + 0.0003*sin(3*anomaly)
+	eclipticLon := math.Mod(anomaly+center+180+102.9372, 360)
+	transit := j2000JD + meanNoon + 0.0053*sin(anomaly) - 0.0069*sin(2*eclipticLon)
+
+	sinDeclination := sin(eclipticLon) * sin(23.4397)
+	cosDeclination := math.Cos(math.Asin(sinDeclination))
+	// -0.833° accounts for refraction and the size of the solar disc.
+	cosHourAngle := (sin(-0.833) - sin(lat)*sinDeclination) / (cos(lat) * cosDeclination)
+	if cosHourAngle < -1 || cosHourAngle > 1 {
+		return time.Time{}, time.Time{}, false
+	}
+	hourAngle := math.Acos(cosHourAngle) * 180 / math.Pi
+
+	return fromJulianDay(transit - hourAngle/360), fromJulianDay(transit + hourAngle/360), true
+}
+
+var moonPhases = []string{
+	"New Moon", "Waxing Crescent", "First Quarter", "Waxing Gibbous",
+	"Full Moon", "Waning Gibbous", "Last Quarter", "Waning Crescent",
+}
+
+// MoonPhase returns the phase name, as the weather provider spells it, and
+// the illuminated fraction of the disc in percent at t.
+func MoonPhase(t time.Time) (phase string, illumination float64) {
+	age := math.Mod(julianDay(t)-referenceNewMoonJD, synodicMonth)
+	if age < 0 {
+		age += synodicMonth
+	}
+	illumination = (1 - math.Cos(2*math.Pi*age/synodicMonth)) / 2 * 100
+
+	// Each named phase is centred on its eighth of the cycle.
+	index := int(math.Floor(age/synodicMonth*8+0.5)) % len(moonPhases)
+	return moonPhases[index], math.Round(illumination)
+}
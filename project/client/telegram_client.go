@@ -0,0 +1,118 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"weather/project/config"
+	"weather/project/domain"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// telegramRequestTimeout bounds Bot API calls on top of the long-poll
+// timeout of getUpdates.
+const telegramRequestTimeout = 10 * time.Second
+
+type TelegramClient struct {
+	baseURL     string
+	pollTimeout time.Duration
+	httpClient  *http.Client
+}
+
+func NewTelegramClient(cfg config.Config) *TelegramClient {
+	transport := otelhttp.NewTransport(
+		&botTokenTransport{token: cfg.TelegramBotToken, base: http.DefaultTransport},
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			return "Telegram " + r.URL.Path
+		}),
+	)
+	return &TelegramClient{
+		baseURL:     strings.TrimRight(cfg.TelegramAPIURL, "/"),
+		pollTimeout: cfg.TelegramPollTimeout,
+		httpClient: &http.Client{
+			Timeout:   cfg.TelegramPollTimeout + telegramRequestTimeout,
+			Transport: transport,
+		},
+	}
+}
+
+// botTokenTransport turns ".../sendMessage" into ".../bot<token>/sendMessage"
+// below the tracing transport, so the token never shows up in spans or
+// transport errors.
+type botTokenTransport struct {
+	token string
+	base  http.RoundTripper
+}
+
+func (t *botTokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	authorized := req.Clone(req.Context())
+	path := authorized.URL.Path
+	method := strings.LastIndex(path, "/")
+	authorized.URL.Path = path[:method] + "/bot" + t.token + path[method:]
+	authorized.URL.RawPath = ""
+	return t.base.RoundTrip(authorized)
+}
+
+// GetUpdates long-polls for messages sent to the bot, starting at offset,
+// the ID after the last update handled.
+func (c *TelegramClient) GetUpdates(ctx context.Context, offset int64) ([]domain.TelegramUpdate, error) {
+	params := map[string]any{
+		"offset":          offset,
+		"timeout":         int(c.pollTimeout / time.Second),
+		"allowed_updates": []string{"message"},
+	}
+	var updates []domain.TelegramUpdate
+	if err := c.call(ctx, "getUpdates", params, &updates); err != nil {
+		return nil, fmt.Errorf("client.GetUpdates: %w", err)
+	}
+	return updates, nil
+}
+
+func (c *TelegramClient) SendMessage(ctx context.Context, chatID int64, text string) error {
+	params := map[string]any{
+		"chat_id": chatID,
+		"text":    text,
+	}
+	if err := c.call(ctx, "sendMessage", params, nil); err != nil {
+		return fmt.Errorf("client.SendMessage: %w", err)
+	}
+	return nil
+}
+
+func (c *TelegramClient) call(ctx context.Context, method string, params any, result any) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("error encoding %s request: %w", method, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/"+method, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error creating %s request: %w", method, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error performing %s request to Telegram: %w", method, err)
+	}
+	defer resp.Body.Close()
+
+	var apiResp domain.TelegramResponse
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return fmt.Errorf("error decoding %s response (status %s): %w", method, resp.Status, err)
+	}
+	if !apiResp.OK {
+		return fmt.Errorf("telegram %s failed with code %d: %s", method, apiResp.ErrorCode, apiResp.Description)
+	}
+	if result == nil {
+		return nil
+	}
+	if err := json.Unmarshal(apiResp.Result, result); err != nil {
+		return fmt.Errorf("error decoding %s result: %w", method, err)
+	}
+	return nil
+}
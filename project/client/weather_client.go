@@ -1,56 +1,167 @@
 package client
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/url"
+	"sync/atomic"
 	"time"
 	"weather/project/config"
 	"weather/project/domain"
+	"weather/project/i18n"
+	"weather/project/metrics"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
-const weatherAPIURL = "http://api.weatherapi.com/v1/current.json"
+const (
+	weatherAPIURL = "http://api.weatherapi.com/v1/current.json"
+	searchAPIURL  = "http://api.weatherapi.com/v1/search.json"
+	historyAPIURL = "http://api.weatherapi.com/v1/history.json"
+	astronomyURL  = "http://api.weatherapi.com/v1/astronomy.json"
+	forecastURL   = "http://api.weatherapi.com/v1/forecast.json"
+
+	// pingQuery is a city the provider is guaranteed to know, used to verify
+	// that the API key is accepted and the upstream is reachable.
+	pingQuery = "London"
+)
 
 type WeatherAPIClient struct {
 	apiKey     string
 	httpClient *http.Client
+	// lastAnswered is when the provider last answered 2xx, in Unix
+	// nanoseconds; Ping trusts it for pingFreshness.
+	lastAnswered  *atomic.Int64
+	pingFreshness time.Duration
 }
 
 func NewWeatherAPIClient(cfg config.Config) *WeatherAPIClient {
 	if cfg.WeatherAPIKey == "" {
-		log.Println("Warning: WeatherAPIKey is not set in config. Weather functionality will be disabled.")
+		slog.Warn("WeatherAPIKey is not set in config. Weather functionality will be disabled.")
 	}
+	lastAnswered := &atomic.Int64{}
+	transport := otelhttp.NewTransport(
+		&apiKeyTransport{apiKey: cfg.WeatherAPIKey, base: http.DefaultTransport, lastAnswered: lastAnswered},
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			return "WeatherAPI " + r.URL.Path
+		}),
+	)
 	return &WeatherAPIClient{
 		apiKey: cfg.WeatherAPIKey,
 		httpClient: &http.Client{
-			Timeout: 10 * time.Second,
+			Timeout:   10 * time.Second,
+			Transport: transport,
 		},
+		lastAnswered:  lastAnswered,
+		pingFreshness: cfg.HealthWeatherCacheTTL,
+	}
+}
+
+// apiKeyTransport adds the API key below the tracing transport, so the key is
+// never recorded as part of the request URL in spans or transport errors. It
+// also notes every 2xx answer for Ping.
+type apiKeyTransport struct {
+	apiKey       string
+	base         http.RoundTripper
+	lastAnswered *atomic.Int64
+}
+
+func (t *apiKeyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	authorized := req.Clone(req.Context())
+	query := authorized.URL.Query()
+	query.Set("key", t.apiKey)
+	authorized.URL.RawQuery = query.Encode()
+	resp, err := t.base.RoundTrip(authorized)
+	if err == nil && resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+		t.lastAnswered.Store(time.Now().UnixNano())
+	}
+	return resp, err
+}
+
+// GetCurrentObservation returns the current conditions at location in the
+// form they are archived in.
+func (c *WeatherAPIClient) GetCurrentObservation(ctx context.Context, location domain.Location, opts domain.WeatherOptions) (*domain.WeatherObservation, error) {
+	apiResp, err := c.fetchCurrent(ctx, location, opts)
+	if err != nil {
+		return nil, fmt.Errorf("client.GetCurrentObservation: %w", err)
+	}
+
+	place := resolvedLocation(apiResp.Location)
+	observedAt := time.Now().UTC()
+	if apiResp.Current.LastUpdatedEpoch > 0 {
+		observedAt = time.Unix(apiResp.Current.LastUpdatedEpoch, 0).UTC()
+	}
+	localDate := observedAt.Format(time.DateOnly)
+	if len(apiResp.Location.Localtime) >= len(time.DateOnly) {
+		localDate = apiResp.Location.Localtime[:len(time.DateOnly)]
+	}
+
+	return &domain.WeatherObservation{
+		LocationQuery: domain.ArchiveQuery(location),
+		PlaceKey:      domain.PlaceKeyOf(place),
+		Location:      place,
+		LocalDate:     localDate,
+		ObservedAt:    observedAt,
+		Conditions:    apiResp.Current.Normalize(),
+	}, nil
+}
+
+// addLanguage asks the provider for condition texts in the language of the
+// request; English is the provider's default.
+func addLanguage(ctx context.Context, params url.Values) {
+	if lang := i18n.FromContext(ctx); lang != i18n.English {
+		params.Add("lang", string(lang))
 	}
 }
 
-func (c *WeatherAPIClient) GetCurrentWeather(city string) (*domain.WeatherResponse, error) {
+func resolvedLocation(l domain.ExternalLocation) domain.ResolvedLocation {
+	return domain.ResolvedLocation{Name: l.Name, Region: l.Region, Country: l.Country, Lat: l.Lat, Lon: l.Lon}
+}
+
+// LookupLocation reports the place the provider resolves location to. The
+// current conditions endpoint does not return a provider ID.
+func (c *WeatherAPIClient) LookupLocation(ctx context.Context, location domain.Location) (*domain.ResolvedLocation, error) {
+	apiResp, err := c.fetchCurrent(ctx, location, domain.WeatherOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("client.LookupLocation: %w", err)
+	}
+	place := resolvedLocation(apiResp.Location)
+	return &place, nil
+}
+
+func (c *WeatherAPIClient) fetchCurrent(ctx context.Context, location domain.Location, opts domain.WeatherOptions) (apiResp *domain.ExternalWeatherAPIResponse, err error) {
 	if c.apiKey == "" {
-		log.Println("WeatherAPIClient: API key not configured.")
+		slog.ErrorContext(ctx, "WeatherAPIClient: API key not configured")
 		return nil, fmt.Errorf("weather API key is not configured")
 	}
 
+	started := time.Now()
+	defer func() { metrics.ObserveUpstream("current", started, err) }()
+
 	params := url.Values{}
-	params.Add("key", c.apiKey)
-	params.Add("q", city)
+	params.Add("q", location.Query())
+	if opts.AirQuality {
+		params.Add("aqi", "yes")
+	}
+	if opts.Pollen {
+		params.Add("pollen", "yes")
+	}
+	addLanguage(ctx, params)
 
 	fullURL := fmt.Sprintf("%s?%s", weatherAPIURL, params.Encode())
-	log.Printf("Fetching weather from: %s", fullURL)
+	slog.DebugContext(ctx, "Fetching weather from WeatherAPI", slog.String("url", weatherAPIURL), slog.Any("location", location))
 
-	req, err := http.NewRequest("GET", fullURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", fullURL, nil)
 	if err != nil {
-		return nil, fmt.Errorf("client.GetCurrentWeather: error creating request: %w", err)
+		return nil, fmt.Errorf("error creating request: %w", err)
 	}
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("client.GetCurrentWeather: error performing request to WeatherAPI: %w", err)
+		return nil, fmt.Errorf("error performing request to WeatherAPI: %w", err)
 	}
 	defer resp.Body.Close()
 
@@ -59,20 +170,276 @@ func (c *WeatherAPIClient) GetCurrentWeather(city string) (*domain.WeatherRespon
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		log.Printf("WeatherAPI request for city %s failed with status %s", city, resp.Status)
-		return nil, fmt.Errorf("client.GetCurrentWeather: WeatherAPI request failed with status %s", resp.Status)
+		slog.WarnContext(ctx, "WeatherAPI request failed", slog.Any("location", location), slog.String("status", resp.Status))
+		return nil, fmt.Errorf("WeatherAPI request failed with status %s", resp.Status)
+	}
+
+	apiResp = &domain.ExternalWeatherAPIResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(apiResp); err != nil {
+		return nil, fmt.Errorf("error decoding WeatherAPI response: %w", err)
+	}
+	return apiResp, nil
+}
+
+// GetForecast returns the outlook for the current local day at location.
+func (c *WeatherAPIClient) GetForecast(ctx context.Context, location domain.Location) (forecast *domain.WeatherForecast, err error) {
+	if c.apiKey == "" {
+		return nil, fmt.Errorf("client.GetForecast: weather API key is not configured")
+	}
+
+	started := time.Now()
+	defer func() { metrics.ObserveUpstream("forecast", started, err) }()
+
+	params := url.Values{}
+	params.Add("q", location.Query())
+	params.Add("days", "1")
+	addLanguage(ctx, params)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s?%s", forecastURL, params.Encode()), nil)
+	if err != nil {
+		return nil, fmt.Errorf("client.GetForecast: error creating request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("client.GetForecast: error performing request to WeatherAPI: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusBadRequest {
+		return nil, domain.ErrCityNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("client.GetForecast: WeatherAPI request failed with status %s", resp.Status)
+	}
+
+	var apiResp domain.ExternalForecastResponse
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return nil, fmt.Errorf("client.GetForecast: error decoding WeatherAPI response: %w", err)
+	}
+	if len(apiResp.Forecast.ForecastDay) == 0 {
+		return nil, fmt.Errorf("client.GetForecast: WeatherAPI returned no forecast days")
+	}
+	return apiResp.Forecast.ForecastDay[0].Normalize(), nil
+}
+
+// GetHistory returns the weather of one local day (YYYY-MM-DD) at location.
+// Dates outside the provider's history window yield ErrHistoryUnavailable.
+func (c *WeatherAPIClient) GetHistory(ctx context.Context, location domain.Location, date string) (history *domain.WeatherHistory, err error) {
+	if c.apiKey == "" {
+		return nil, fmt.Errorf("client.GetHistory: weather API key is not configured")
+	}
+
+	started := time.Now()
+	defer func() { metrics.ObserveUpstream("history", started, err) }()
+
+	params := url.Values{}
+	params.Add("q", location.Query())
+	params.Add("dt", date)
+	addLanguage(ctx, params)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s?%s", historyAPIURL, params.Encode()), nil)
+	if err != nil {
+		return nil, fmt.Errorf("client.GetHistory: error creating request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("client.GetHistory: error performing request to WeatherAPI: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusNotFound {
+		var apiErr domain.ExternalError
+		if json.NewDecoder(resp.Body).Decode(&apiErr) == nil && apiErr.Error.Code == domain.ExternalErrorNoLocation {
+			return nil, domain.ErrCityNotFound
+		}
+		return nil, domain.ErrHistoryUnavailable
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("client.GetHistory: WeatherAPI request failed with status %s", resp.Status)
+	}
+
+	var apiResp domain.ExternalHistoryResponse
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return nil, fmt.Errorf("client.GetHistory: error decoding WeatherAPI response: %w", err)
+	}
+	if len(apiResp.Forecast.ForecastDay) == 0 {
+		return nil, domain.ErrHistoryUnavailable
+	}
+
+	day := apiResp.Forecast.ForecastDay[0]
+	totalPrecip := day.Day.TotalPrecipMm
+	condition, severity := domain.ConditionFromWeatherAPI(day.Day.Condition.Code)
+	history = &domain.WeatherHistory{
+		Date:     day.Date,
+		Source:   domain.HistorySourceProvider,
+		Location: resolvedLocation(apiResp.Location),
+		Day: domain.DaySummary{
+			MinTempC:      day.Day.MinTempC,
+			MaxTempC:      day.Day.MaxTempC,
+			AvgTempC:      day.Day.AvgTempC,
+			AvgHumidity:   day.Day.AvgHumidity,
+			MaxWindKph:    day.Day.MaxWindKph,
+			TotalPrecipMm: &totalPrecip,
+			Description:   day.Day.Condition.Text,
+			Condition:     condition,
+			Severity:      severity,
+		},
+		Hours: make([]domain.HourlyConditions, len(day.Hour)),
+	}
+	for i, hour := range day.Hour {
+		history.Hours[i] = domain.HourlyConditions{Time: time.Unix(hour.TimeEpoch, 0).UTC(), Conditions: hour.Normalize()}
+		if c := history.Hours[i].Conditions; c.Severity > history.Day.Severity {
+			history.Day.Condition, history.Day.Severity = c.Condition, c.Severity
+		}
+	}
+	return history, nil
+}
+
+// GetAstronomy returns the sun and moon data of one local day (YYYY-MM-DD)
+// at location; an empty date means today at the location.
+func (c *WeatherAPIClient) GetAstronomy(ctx context.Context, location domain.Location, date string) (astronomy *domain.Astronomy, err error) {
+	if c.apiKey == "" {
+		return nil, fmt.Errorf("client.GetAstronomy: weather API key is not configured")
+	}
+
+	started := time.Now()
+	defer func() { metrics.ObserveUpstream("astronomy", started, err) }()
+
+	params := url.Values{}
+	params.Add("q", location.Query())
+	if date != "" {
+		params.Add("dt", date)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s?%s", astronomyURL, params.Encode()), nil)
+	if err != nil {
+		return nil, fmt.Errorf("client.GetAstronomy: error creating request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("client.GetAstronomy: error performing request to WeatherAPI: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusNotFound {
+		var apiErr domain.ExternalError
+		if json.NewDecoder(resp.Body).Decode(&apiErr) == nil && apiErr.Error.Code == domain.ExternalErrorNoLocation {
+			return nil, domain.ErrCityNotFound
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("client.GetAstronomy: WeatherAPI request failed with status %s", resp.Status)
 	}
 
-	var apiResp domain.ExternalWeatherAPIResponse
+	var apiResp domain.ExternalAstronomyResponse
 	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
-		return nil, fmt.Errorf("client.GetCurrentWeather: error decoding WeatherAPI response: %w", err)
+		return nil, fmt.Errorf("client.GetAstronomy: error decoding WeatherAPI response: %w", err)
+	}
+
+	if date == "" && len(apiResp.Location.Localtime) >= len(time.DateOnly) {
+		date = apiResp.Location.Localtime[:len(time.DateOnly)]
+	}
+	zone := apiResp.Location.Zone()
+	localTime := func(clock string) *time.Time {
+		t, err := time.ParseInLocation("2006-01-02 03:04 PM", date+" "+clock, zone)
+		if err != nil {
+			return nil
+		}
+		return &t
+	}
+
+	astro := apiResp.Astronomy.Astro
+	illumination, _ := astro.MoonIllumination.Float64()
+	return &domain.Astronomy{
+		Date:             date,
+		Source:           domain.AstronomySourceProvider,
+		Location:         resolvedLocation(apiResp.Location),
+		Sunrise:          localTime(astro.Sunrise),
+		Sunset:           localTime(astro.Sunset),
+		Moonrise:         localTime(astro.Moonrise),
+		Moonset:          localTime(astro.Moonset),
+		MoonPhase:        astro.MoonPhase,
+		MoonIllumination: illumination,
+	}, nil
+}
+
+// SearchLocations returns the provider's matches for a partial place name,
+// best match first.
+func (c *WeatherAPIClient) SearchLocations(ctx context.Context, query string) (matches []domain.ResolvedLocation, err error) {
+	if c.apiKey == "" {
+		return nil, fmt.Errorf("client.SearchLocations: weather API key is not configured")
+	}
+
+	started := time.Now()
+	defer func() { metrics.ObserveUpstream("search", started, err) }()
+
+	params := url.Values{}
+	params.Add("q", query)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s?%s", searchAPIURL, params.Encode()), nil)
+	if err != nil {
+		return nil, fmt.Errorf("client.SearchLocations: error creating request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("client.SearchLocations: error performing request to WeatherAPI: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusBadRequest {
+		// Rejected queries simply have no matches.
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("client.SearchLocations: WeatherAPI request failed with status %s", resp.Status)
+	}
+
+	var results []domain.ExternalLocationSearchResult
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		return nil, fmt.Errorf("client.SearchLocations: error decoding WeatherAPI response: %w", err)
 	}
 
-	weather := &domain.WeatherResponse{
-		Temperature: apiResp.Current.TempC,
-		Humidity:    float64(apiResp.Current.Humidity),
-		Description: apiResp.Current.Condition.Text,
+	matches = make([]domain.ResolvedLocation, len(results))
+	for i, r := range results {
+		matches[i] = domain.ResolvedLocation{ProviderID: r.ID, Name: r.Name, Region: r.Region, Country: r.Country, Lat: r.Lat, Lon: r.Lon}
+	}
+	return matches, nil
+}
+
+// Ping spends a billed request only when no other request was answered
+// within pingFreshness; regular traffic proves the key and the upstream just
+// as well.
+func (c *WeatherAPIClient) Ping(ctx context.Context) (err error) {
+	if c.apiKey == "" {
+		return fmt.Errorf("weather API key is not configured")
+	}
+	if time.Since(time.Unix(0, c.lastAnswered.Load())) < c.pingFreshness {
+		return nil
+	}
+
+	started := time.Now()
+	defer func() { metrics.ObserveUpstream("ping", started, err) }()
+
+	params := url.Values{}
+	params.Add("q", pingQuery)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s?%s", weatherAPIURL, params.Encode()), nil)
+	if err != nil {
+		return fmt.Errorf("client.Ping: error creating request: %w", err)
 	}
 
-	return weather, nil
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("client.Ping: error performing request to WeatherAPI: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("client.Ping: WeatherAPI responded with status %s", resp.Status)
+	}
+	return nil
 }
@@ -1,8 +1,12 @@
 package config
 
 import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/go-viper/mapstructure/v2"
 	"github.com/spf13/viper"
-	"log"
 )
 
 type Config struct {
@@ -16,6 +20,71 @@ type Config struct {
 	AppBaseURL string `mapstructure:"APP_BASE_URL"`
 
 	WeatherAPIKey string `mapstructure:"WEATHER_API_KEY"`
+
+	LogLevel             string        `mapstructure:"LOG_LEVEL"`
+	LogFormat            string        `mapstructure:"LOG_FORMAT"`
+	DBSlowQueryThreshold time.Duration `mapstructure:"DB_SLOW_QUERY_THRESHOLD"`
+
+	AdminToken string `mapstructure:"ADMIN_TOKEN"`
+
+	APIKeyAuthEnabled       bool `mapstructure:"API_KEY_AUTH_ENABLED"`
+	APIKeyDefaultDailyQuota int  `mapstructure:"API_KEY_DEFAULT_DAILY_QUOTA"`
+
+	PrivacyTokenTTL     time.Duration `mapstructure:"PRIVACY_TOKEN_TTL"`
+	DataRetentionPeriod time.Duration `mapstructure:"DATA_RETENTION_PERIOD"`
+	DataPurgeInterval   time.Duration `mapstructure:"DATA_PURGE_INTERVAL"`
+
+	TrustedProxies []string `mapstructure:"TRUSTED_PROXIES"`
+
+	RateLimitEnabled    bool          `mapstructure:"RATE_LIMIT_ENABLED"`
+	RateLimitIPEvery    time.Duration `mapstructure:"RATE_LIMIT_IP_EVERY"`
+	RateLimitIPBurst    int           `mapstructure:"RATE_LIMIT_IP_BURST"`
+	RateLimitEmailEvery time.Duration `mapstructure:"RATE_LIMIT_EMAIL_EVERY"`
+	RateLimitEmailBurst int           `mapstructure:"RATE_LIMIT_EMAIL_BURST"`
+
+	OTelEnabled          bool    `mapstructure:"OTEL_ENABLED"`
+	OTelServiceName      string  `mapstructure:"OTEL_SERVICE_NAME"`
+	OTelExporterEndpoint string  `mapstructure:"OTEL_EXPORTER_OTLP_ENDPOINT"`
+	OTelExporterInsecure bool    `mapstructure:"OTEL_EXPORTER_OTLP_INSECURE"`
+	OTelSampleRatio      float64 `mapstructure:"OTEL_TRACES_SAMPLER_RATIO"`
+
+	HealthCheckTimeout    time.Duration `mapstructure:"HEALTH_CHECK_TIMEOUT"`
+	HealthWeatherCacheTTL time.Duration `mapstructure:"HEALTH_WEATHER_CACHE_TTL"`
+
+	OpenAPIValidateRequests  bool `mapstructure:"OPENAPI_VALIDATE_REQUESTS"`
+	OpenAPIValidateResponses bool `mapstructure:"OPENAPI_VALIDATE_RESPONSES"`
+
+	LegacyAPIDeprecatedAt time.Time `mapstructure:"LEGACY_API_DEPRECATED_AT"`
+	LegacyAPISunset       time.Time `mapstructure:"LEGACY_API_SUNSET"`
+
+	WeatherBatchConcurrency int `mapstructure:"WEATHER_BATCH_CONCURRENCY"`
+	// HistoryArchiveMinHours is how many hours of a day the archive must hold
+	// before it answers history requests in place of the provider.
+	HistoryArchiveMinHours int `mapstructure:"HISTORY_ARCHIVE_MIN_HOURS"`
+	// WeatherStreamPollInterval is how often streamed locations are polled.
+	WeatherStreamPollInterval time.Duration `mapstructure:"WEATHER_STREAM_POLL_INTERVAL"`
+	// WeatherCacheTTL is how long a current reading is reused by streams
+	// before the provider is asked again.
+	WeatherCacheTTL time.Duration `mapstructure:"WEATHER_CACHE_TTL"`
+
+	// Scheduled weather updates: daily ones go out at DailyUpdateHour local
+	// time at the subscribed location.
+	DailyUpdateHour       int           `mapstructure:"DAILY_UPDATE_HOUR"`
+	WeatherUpdateInterval time.Duration `mapstructure:"WEATHER_UPDATE_INTERVAL"`
+	WeatherUpdateWorkers  int           `mapstructure:"WEATHER_UPDATE_WORKERS"`
+
+	WebhookMaxAttempts    int           `mapstructure:"WEBHOOK_MAX_ATTEMPTS"`
+	WebhookInitialBackoff time.Duration `mapstructure:"WEBHOOK_INITIAL_BACKOFF"`
+	WebhookTimeout        time.Duration `mapstructure:"WEBHOOK_TIMEOUT"`
+	// WebhookAllowPrivateTargets lets webhooks reach loopback and private
+	// networks; only meant for local development.
+	WebhookAllowPrivateTargets bool `mapstructure:"WEBHOOK_ALLOW_PRIVATE_TARGETS"`
+
+	// TelegramBotToken enables the Telegram bot; TelegramAPIURL can point
+	// it at a local fake of the Bot API.
+	TelegramBotToken    string        `mapstructure:"TELEGRAM_BOT_TOKEN"`
+	TelegramAPIURL      string        `mapstructure:"TELEGRAM_API_URL"`
+	TelegramPollTimeout time.Duration `mapstructure:"TELEGRAM_POLL_TIMEOUT"`
 }
 
 func LoadConfig(path string) (config Config, err error) {
@@ -29,30 +98,119 @@ func LoadConfig(path string) (config Config, err error) {
 	viper.SetDefault("DB_PORT", "3306") // Default MySQL port
 	viper.SetDefault("APP_PORT", "8080")
 	viper.SetDefault("APP_BASE_URL", "http://localhost:8080")
+	viper.SetDefault("LOG_LEVEL", "info")
+	viper.SetDefault("LOG_FORMAT", "json")
+	viper.SetDefault("DB_SLOW_QUERY_THRESHOLD", "200ms")
+	viper.SetDefault("ADMIN_TOKEN", "")
+	viper.SetDefault("API_KEY_AUTH_ENABLED", true)
+	viper.SetDefault("API_KEY_DEFAULT_DAILY_QUOTA", 1000)
+	viper.SetDefault("PRIVACY_TOKEN_TTL", "24h")
+	viper.SetDefault("DATA_RETENTION_PERIOD", "720h")
+	viper.SetDefault("DATA_PURGE_INTERVAL", "1h")
+	viper.SetDefault("TRUSTED_PROXIES", []string{})
+	viper.SetDefault("RATE_LIMIT_ENABLED", true)
+	viper.SetDefault("RATE_LIMIT_IP_EVERY", "6s")
+	viper.SetDefault("RATE_LIMIT_IP_BURST", 10)
+	viper.SetDefault("RATE_LIMIT_EMAIL_EVERY", "20m")
+	viper.SetDefault("RATE_LIMIT_EMAIL_BURST", 3)
+	viper.SetDefault("OTEL_ENABLED", false)
+	viper.SetDefault("OTEL_SERVICE_NAME", "weather-api")
+	viper.SetDefault("OTEL_EXPORTER_OTLP_ENDPOINT", "http://localhost:4318")
+	viper.SetDefault("OTEL_EXPORTER_OTLP_INSECURE", true)
+	viper.SetDefault("OTEL_TRACES_SAMPLER_RATIO", 1.0)
+	viper.SetDefault("HEALTH_CHECK_TIMEOUT", "2s")
+	viper.SetDefault("HEALTH_WEATHER_CACHE_TTL", "5m")
+	viper.SetDefault("OPENAPI_VALIDATE_REQUESTS", true)
+	viper.SetDefault("OPENAPI_VALIDATE_RESPONSES", false)
+	viper.SetDefault("LEGACY_API_DEPRECATED_AT", "2026-10-19")
+	viper.SetDefault("LEGACY_API_SUNSET", "2027-04-30")
+	viper.SetDefault("WEATHER_BATCH_CONCURRENCY", 5)
+	viper.SetDefault("HISTORY_ARCHIVE_MIN_HOURS", 24)
+	viper.SetDefault("WEATHER_STREAM_POLL_INTERVAL", "1m")
+	viper.SetDefault("WEATHER_CACHE_TTL", "10m")
+	viper.SetDefault("DAILY_UPDATE_HOUR", 7)
+	viper.SetDefault("WEATHER_UPDATE_INTERVAL", "1m")
+	viper.SetDefault("WEATHER_UPDATE_WORKERS", 5)
+	viper.SetDefault("WEBHOOK_MAX_ATTEMPTS", 5)
+	viper.SetDefault("WEBHOOK_INITIAL_BACKOFF", "1s")
+	viper.SetDefault("WEBHOOK_TIMEOUT", "10s")
+	viper.SetDefault("WEBHOOK_ALLOW_PRIVATE_TARGETS", false)
+	viper.SetDefault("TELEGRAM_BOT_TOKEN", "")
+	viper.SetDefault("TELEGRAM_API_URL", "https://api.telegram.org")
+	viper.SetDefault("TELEGRAM_POLL_TIMEOUT", "30s")
 
 	err = viper.ReadInConfig()
 	if err != nil {
 		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
 
-			log.Println("INFO: Config file .env not found. Using environment variables and defaults.")
+			slog.Info("Config file .env not found. Using environment variables and defaults.")
 		} else {
 
-			log.Printf("ERROR: Failed to read config file: %v\n", err)
+			slog.Error("Failed to read config file", slog.Any("error", err))
 			return Config{}, err // Return empty config and the error
 		}
 	}
 
-	err = viper.Unmarshal(&config)
+	err = viper.Unmarshal(&config, viper.DecodeHook(mapstructure.ComposeDecodeHookFunc(
+		mapstructure.StringToTimeDurationHookFunc(),
+		mapstructure.StringToSliceHookFunc(","),
+		mapstructure.StringToTimeHookFunc(time.DateOnly),
+	)))
 	if err != nil {
-		log.Printf("ERROR: Unable to unmarshal config: %v\n", err)
+		slog.Error("Unable to unmarshal config", slog.Any("error", err))
 		return Config{}, err
 	}
 
+	if config.RateLimitEnabled && (config.RateLimitIPEvery <= 0 || config.RateLimitEmailEvery <= 0) {
+		return Config{}, fmt.Errorf("config.LoadConfig: RATE_LIMIT_IP_EVERY and RATE_LIMIT_EMAIL_EVERY must be positive durations")
+	}
+
+	if config.DataPurgeInterval <= 0 {
+		return Config{}, fmt.Errorf("config.LoadConfig: DATA_PURGE_INTERVAL must be a positive duration")
+	}
+
+	if config.DailyUpdateHour < 0 || config.DailyUpdateHour > 23 {
+		return Config{}, fmt.Errorf("config.LoadConfig: DAILY_UPDATE_HOUR must be between 0 and 23")
+	}
+
+	if config.WeatherUpdateInterval <= 0 {
+		return Config{}, fmt.Errorf("config.LoadConfig: WEATHER_UPDATE_INTERVAL must be a positive duration")
+	}
+
+	if config.HistoryArchiveMinHours < 1 || config.HistoryArchiveMinHours > 24 {
+		return Config{}, fmt.Errorf("config.LoadConfig: HISTORY_ARCHIVE_MIN_HOURS must be between 1 and 24")
+	}
+
+	if config.WeatherCacheTTL <= 0 {
+		return Config{}, fmt.Errorf("config.LoadConfig: WEATHER_CACHE_TTL must be a positive duration")
+	}
+
+	if config.HealthCheckTimeout <= 0 {
+		return Config{}, fmt.Errorf("config.LoadConfig: HEALTH_CHECK_TIMEOUT must be a positive duration")
+	}
+
+	if config.WebhookMaxAttempts < 1 {
+		return Config{}, fmt.Errorf("config.LoadConfig: WEBHOOK_MAX_ATTEMPTS must be at least 1")
+	}
+
+	if config.WebhookInitialBackoff <= 0 || config.WebhookTimeout <= 0 {
+		return Config{}, fmt.Errorf("config.LoadConfig: WEBHOOK_INITIAL_BACKOFF and WEBHOOK_TIMEOUT must be positive durations")
+	}
+
+	if config.TelegramBotToken != "" && config.TelegramPollTimeout <= 0 {
+		return Config{}, fmt.Errorf("config.LoadConfig: TELEGRAM_POLL_TIMEOUT must be a positive duration")
+	}
+
 	if config.WeatherAPIKey == "" {
-		log.Println("WARNING: WEATHER_API_KEY is not set in the configuration.")
+		slog.Warn("WEATHER_API_KEY is not set in the configuration.")
 
 	}
 
-	log.Println("Configuration loaded.")
+	slog.Info("Configuration loaded.")
 	return config, nil
 }
+
+// Secrets lists configuration values that must never appear in logs.
+func (c Config) Secrets() []string {
+	return []string{c.DBPassword, c.WeatherAPIKey, c.AdminToken, c.TelegramBotToken}
+}
@@ -0,0 +1,54 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// APIKey is an issued credential for the weather endpoints. Only the SHA-256
+// hash of the key is stored; the plaintext is shown once, when it is issued.
+type APIKey struct {
+	ID         uuid.UUID  `gorm:"type:char(36);primary_key;" json:"id"`
+	Name       string     `gorm:"type:varchar(100);not null" json:"name"`
+	Prefix     string     `gorm:"type:varchar(16);not null" json:"prefix"`
+	KeyHash    string     `gorm:"type:char(64);uniqueIndex;not null" json:"-"`
+	DailyQuota int        `gorm:"not null" json:"daily_quota"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+
+	UsageToday int `gorm:"-" json:"usage_today"`
+}
+
+func (k *APIKey) BeforeCreate(tx *gorm.DB) (err error) {
+	if k.ID == uuid.Nil {
+		k.ID = uuid.New()
+	}
+	return
+}
+
+func (k *APIKey) Revoked() bool {
+	return k.RevokedAt != nil
+}
+
+// APIKeyUsage counts requests made with a key during one UTC day. Day is kept
+// as a YYYY-MM-DD string so it is not shifted by the connection time zone.
+type APIKeyUsage struct {
+	APIKeyID uuid.UUID `gorm:"type:char(36);primaryKey"`
+	Day      string    `gorm:"type:char(10);primaryKey"`
+	Count    int       `gorm:"not null;default:0"`
+}
+
+// IssuedAPIKey is returned only when a key is created or rotated.
+type IssuedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+type APIKeyInput struct {
+	Name       string `form:"name" json:"name" binding:"required,min=1,max=100"`
+	DailyQuota int    `form:"daily_quota" json:"daily_quota" binding:"omitempty,min=1"`
+}
@@ -0,0 +1,51 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type AstronomySource string
+
+const (
+	AstronomySourceProvider AstronomySource = "provider"
+	// AstronomySourceComputed marks data calculated locally because the
+	// provider was unavailable; times are then reported in UTC.
+	AstronomySourceComputed AstronomySource = "computed"
+)
+
+type AstronomyInput struct {
+	LocationInput
+	Date string `form:"date" binding:"omitempty,datetime=2006-01-02"`
+}
+
+// Astronomy is the sun and moon data of one local calendar day. Sunrise and
+// Sunset are nil when the sun does not cross the horizon that day; moonrise
+// and moonset are only known from the provider.
+type Astronomy struct {
+	Date             string           `json:"date"`
+	Source           AstronomySource  `json:"source"`
+	Location         ResolvedLocation `json:"location"`
+	Sunrise          *time.Time       `json:"sunrise"`
+	Sunset           *time.Time       `json:"sunset"`
+	Moonrise         *time.Time       `json:"moonrise,omitempty"`
+	Moonset          *time.Time       `json:"moonset,omitempty"`
+	MoonPhase        string           `json:"moon_phase"`
+	MoonIllumination float64          `json:"moon_illumination"`
+}
+
+// ExternalAstronomyResponse is the provider's astronomy.json. Times are local
+// "hh:mm AM" strings, or phrases such as "No moonrise".
+type ExternalAstronomyResponse struct {
+	Location  ExternalLocation `json:"location"`
+	Astronomy struct {
+		Astro struct {
+			Sunrise          string      `json:"sunrise"`
+			Sunset           string      `json:"sunset"`
+			Moonrise         string      `json:"moonrise"`
+			Moonset          string      `json:"moonset"`
+			MoonPhase        string      `json:"moon_phase"`
+			MoonIllumination json.Number `json:"moon_illumination"`
+		} `json:"astro"`
+	} `json:"astronomy"`
+}
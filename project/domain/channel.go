@@ -0,0 +1,156 @@
+package domain
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DeliveryChannel is a transport for messages to a subscriber. The email
+// address or Telegram chat identifies a subscription, whichever channels its
+// updates go to.
+type DeliveryChannel string
+
+const (
+	ChannelEmail   DeliveryChannel = "email"
+	ChannelWebhook DeliveryChannel = "webhook"
+	// ChannelTelegram targets a chat ID. Only the bot creates it, since
+	// the chat is what the subscriber proves by talking to the bot.
+	ChannelTelegram DeliveryChannel = "telegram"
+	// ChannelSlack and ChannelDiscord post to an incoming webhook URL.
+	ChannelSlack   DeliveryChannel = "slack"
+	ChannelDiscord DeliveryChannel = "discord"
+)
+
+const MaxChannelsPerSubscription = 5
+
+// SubscriptionChannel is one destination of a subscription's weather
+// updates.
+type SubscriptionChannel struct {
+	ID             uint64          `gorm:"primaryKey;autoIncrement" json:"-"`
+	SubscriptionID uuid.UUID       `gorm:"type:char(36);not null;index" json:"-"`
+	Kind           DeliveryChannel `gorm:"type:varchar(16);not null" json:"kind"`
+	// Target is the transport's address, such as a webhook URL or a chat
+	// ID. Email channels leave it empty and use the subscription's address.
+	Target    string    `gorm:"type:varchar(2048)" json:"target,omitempty"`
+	Secret    string    `gorm:"type:varchar(256)" json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ChannelInput struct {
+	Kind   string `json:"kind"`
+	Target string `json:"target"`
+	Secret string `json:"secret"`
+}
+
+const (
+	maxWebhookURLLength    = 2048
+	minWebhookSecretLength = 16
+	maxWebhookSecretLength = 256
+)
+
+// Channels validates where updates should go. Without a channels list the
+// single-channel fields channel, webhook_url and webhook_secret are used, and
+// without those, email.
+func (in SubscriptionInput) Channels() ([]SubscriptionChannel, error) {
+	if len(in.ChannelList) == 0 {
+		if in.Channel == "" || DeliveryChannel(in.Channel) == ChannelEmail {
+			if in.WebhookURL != "" || in.WebhookSecret != "" {
+				return nil, NewFieldError("channel", "must be webhook, slack or discord when webhook_url or webhook_secret is given")
+			}
+			return []SubscriptionChannel{{Kind: ChannelEmail}}, nil
+		}
+		ch, fields := validateChannel(ChannelInput{Kind: in.Channel, Target: in.WebhookURL, Secret: in.WebhookSecret}, "channel", "webhook_url", "webhook_secret")
+		if len(fields) > 0 {
+			return nil, &ValidationError{Fields: fields}
+		}
+		return []SubscriptionChannel{ch}, nil
+	}
+
+	if in.Channel != "" || in.WebhookURL != "" || in.WebhookSecret != "" {
+		return nil, NewFieldError("channels", "cannot be combined with channel, webhook_url or webhook_secret")
+	}
+	if len(in.ChannelList) > MaxChannelsPerSubscription {
+		return nil, NewFieldError("channels", "must list at most 5 channels")
+	}
+	var fields []FieldError
+	channels := make([]SubscriptionChannel, 0, len(in.ChannelList))
+	seen := make(map[SubscriptionChannel]bool)
+	for i, input := range in.ChannelList {
+		prefix := fmt.Sprintf("channels[%d].", i)
+		ch, chFields := validateChannel(input, prefix+"kind", prefix+"target", prefix+"secret")
+		fields = append(fields, chFields...)
+		key := SubscriptionChannel{Kind: ch.Kind, Target: ch.Target}
+		if len(chFields) == 0 && seen[key] {
+			fields = append(fields, FieldError{Field: prefix + "target", Message: "must not repeat another channel"})
+		}
+		seen[key] = true
+		channels = append(channels, ch)
+	}
+	if len(fields) > 0 {
+		return nil, &ValidationError{Fields: fields}
+	}
+	return channels, nil
+}
+
+func validateChannel(in ChannelInput, kindField, targetField, secretField string) (SubscriptionChannel, []FieldError) {
+	ch := SubscriptionChannel{Kind: DeliveryChannel(in.Kind), Target: in.Target, Secret: in.Secret}
+	var fields []FieldError
+	switch ch.Kind {
+	case ChannelEmail:
+		if in.Target != "" || in.Secret != "" {
+			fields = append(fields, FieldError{Field: targetField, Message: "must be empty for the email channel"})
+		}
+	case ChannelWebhook:
+		target, err := url.Parse(in.Target)
+		switch {
+		case in.Target == "":
+			fields = append(fields, FieldError{Field: targetField, Message: "is required for the webhook channel"})
+		case err != nil || (target.Scheme != "https" && target.Scheme != "http") || target.Host == "" || target.User != nil:
+			fields = append(fields, FieldError{Field: targetField, Message: "must be an absolute http or https URL without credentials"})
+		case len(in.Target) > maxWebhookURLLength:
+			fields = append(fields, FieldError{Field: targetField, Message: "must be at most 2048 characters"})
+		}
+		if len(in.Secret) < minWebhookSecretLength || len(in.Secret) > maxWebhookSecretLength {
+			fields = append(fields, FieldError{Field: secretField, Message: "must be between 16 and 256 characters"})
+		}
+	case ChannelSlack, ChannelDiscord:
+		if !isIncomingWebhookURL(ch.Kind, in.Target) || len(in.Target) > maxWebhookURLLength {
+			message := "must be a Slack incoming webhook URL, https://hooks.slack.com/services/..."
+			if ch.Kind == ChannelDiscord {
+				message = "must be a Discord webhook URL, https://discord.com/api/webhooks/..."
+			}
+			fields = append(fields, FieldError{Field: targetField, Message: message})
+		}
+		if in.Secret != "" {
+			fields = append(fields, FieldError{Field: secretField, Message: "must be empty for the slack and discord channels"})
+		}
+	default:
+		fields = append(fields, FieldError{Field: kindField, Message: "must be one of: email, webhook, slack, discord"})
+	}
+	return ch, fields
+}
+
+// incomingWebhookURLs are where Slack and Discord issue incoming webhooks.
+// Accepting nothing else keeps these unsigned posts away from arbitrary
+// endpoints.
+var incomingWebhookURLs = map[DeliveryChannel][]struct{ host, pathPrefix string }{
+	ChannelSlack:   {{"hooks.slack.com", "/services/"}},
+	ChannelDiscord: {{"discord.com", "/api/webhooks/"}, {"discordapp.com", "/api/webhooks/"}},
+}
+
+func isIncomingWebhookURL(kind DeliveryChannel, target string) bool {
+	u, err := url.Parse(target)
+	if err != nil || u.Scheme != "https" || u.User != nil || u.RawQuery != "" {
+		return false
+	}
+	for _, allowed := range incomingWebhookURLs[kind] {
+		if u.Host == allowed.host && strings.HasPrefix(u.Path, allowed.pathPrefix) && len(u.Path) > len(allowed.pathPrefix) {
+			return true
+		}
+	}
+	return false
+}
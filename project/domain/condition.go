@@ -0,0 +1,154 @@
+package domain
+
+import "fmt"
+
+// Condition is a provider-independent category of weather, stable enough
+// for clients and alert rules to switch on instead of free text.
+type Condition string
+
+const (
+	ConditionClear        Condition = "clear"
+	ConditionPartlyCloudy Condition = "partly_cloudy"
+	ConditionCloudy       Condition = "cloudy"
+	ConditionFog          Condition = "fog"
+	ConditionDrizzle      Condition = "drizzle"
+	ConditionRain         Condition = "rain"
+	ConditionFreezingRain Condition = "freezing_rain"
+	ConditionSleet        Condition = "sleet"
+	ConditionSnow         Condition = "snow"
+	ConditionIcePellets   Condition = "ice_pellets"
+	ConditionThunderstorm Condition = "thunderstorm"
+	ConditionUnknown      Condition = "unknown"
+)
+
+var conditionLabels = map[Condition]string{
+	ConditionClear:        "Clear",
+	ConditionPartlyCloudy: "Partly cloudy",
+	ConditionCloudy:       "Cloudy",
+	ConditionFog:          "Fog",
+	ConditionDrizzle:      "Drizzle",
+	ConditionRain:         "Rain",
+	ConditionFreezingRain: "Freezing rain",
+	ConditionSleet:        "Sleet",
+	ConditionSnow:         "Snow",
+	ConditionIcePellets:   "Ice pellets",
+	ConditionThunderstorm: "Thunderstorm",
+	ConditionUnknown:      "Unknown",
+}
+
+// Label is the English name of the condition, for use in message text.
+func (c Condition) Label() string {
+	if label, ok := conditionLabels[c]; ok {
+		return label
+	}
+	return conditionLabels[ConditionUnknown]
+}
+
+// Severity orders conditions by how much they disrupt outdoor plans, so
+// rules can compare them.
+type Severity int
+
+const (
+	SeverityNone Severity = iota
+	SeverityMinor
+	SeverityModerate
+	SeveritySevere
+)
+
+var severityNames = []string{"none", "minor", "moderate", "severe"}
+
+func (s Severity) String() string {
+	if s < SeverityNone || s > SeveritySevere {
+		return fmt.Sprintf("Severity(%d)", int(s))
+	}
+	return severityNames[s]
+}
+
+func ParseSeverity(name string) (Severity, error) {
+	for i, n := range severityNames {
+		if n == name {
+			return Severity(i), nil
+		}
+	}
+	return SeverityNone, fmt.Errorf("unknown severity %q", name)
+}
+
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *Severity) UnmarshalText(text []byte) error {
+	parsed, err := ParseSeverity(string(text))
+	if err != nil {
+		return err
+	}
+	*s = parsed
+	return nil
+}
+
+type conditionMapping struct {
+	condition Condition
+	severity  Severity
+}
+
+// weatherAPIConditions maps WeatherAPI condition codes, see
+// https://www.weatherapi.com/docs/weather_conditions.json.
+var weatherAPIConditions = map[int]conditionMapping{
+	1000: {ConditionClear, SeverityNone},
+	1003: {ConditionPartlyCloudy, SeverityNone},
+	1006: {ConditionCloudy, SeverityNone},
+	1009: {ConditionCloudy, SeverityNone},
+	1030: {ConditionFog, SeverityMinor},
+	1063: {ConditionRain, SeverityMinor},
+	1066: {ConditionSnow, SeverityMinor},
+	1069: {ConditionSleet, SeverityMinor},
+	1072: {ConditionFreezingRain, SeverityModerate},
+	1087: {ConditionThunderstorm, SeverityModerate},
+	1114: {ConditionSnow, SeverityModerate},
+	1117: {ConditionSnow, SeveritySevere},
+	1135: {ConditionFog, SeverityModerate},
+	1147: {ConditionFog, SeverityModerate},
+	1150: {ConditionDrizzle, SeverityMinor},
+	1153: {ConditionDrizzle, SeverityMinor},
+	1168: {ConditionFreezingRain, SeverityModerate},
+	1171: {ConditionFreezingRain, SeveritySevere},
+	1180: {ConditionRain, SeverityMinor},
+	1183: {ConditionRain, SeverityMinor},
+	1186: {ConditionRain, SeverityModerate},
+	1189: {ConditionRain, SeverityModerate},
+	1192: {ConditionRain, SeveritySevere},
+	1195: {ConditionRain, SeveritySevere},
+	1198: {ConditionFreezingRain, SeverityModerate},
+	1201: {ConditionFreezingRain, SeveritySevere},
+	1204: {ConditionSleet, SeverityMinor},
+	1207: {ConditionSleet, SeverityModerate},
+	1210: {ConditionSnow, SeverityMinor},
+	1213: {ConditionSnow, SeverityMinor},
+	1216: {ConditionSnow, SeverityModerate},
+	1219: {ConditionSnow, SeverityModerate},
+	1222: {ConditionSnow, SeveritySevere},
+	1225: {ConditionSnow, SeveritySevere},
+	1237: {ConditionIcePellets, SeverityModerate},
+	1240: {ConditionRain, SeverityMinor},
+	1243: {ConditionRain, SeverityModerate},
+	1246: {ConditionRain, SeveritySevere},
+	1249: {ConditionSleet, SeverityMinor},
+	1252: {ConditionSleet, SeverityModerate},
+	1255: {ConditionSnow, SeverityMinor},
+	1258: {ConditionSnow, SeverityModerate},
+	1261: {ConditionIcePellets, SeverityMinor},
+	1264: {ConditionIcePellets, SeverityModerate},
+	1273: {ConditionThunderstorm, SeverityModerate},
+	1276: {ConditionThunderstorm, SeveritySevere},
+	1279: {ConditionThunderstorm, SeverityModerate},
+	1282: {ConditionThunderstorm, SeveritySevere},
+}
+
+// ConditionFromWeatherAPI normalizes a WeatherAPI condition code. Unknown
+// codes map to ConditionUnknown.
+func ConditionFromWeatherAPI(code int) (Condition, Severity) {
+	if m, ok := weatherAPIConditions[code]; ok {
+		return m.condition, m.severity
+	}
+	return ConditionUnknown, SeverityNone
+}
@@ -9,4 +9,17 @@ var (
 	ErrTokenInvalidOrExpired  = errors.New("token is invalid, expired, or not found")
 	ErrFailedToFetchWeather   = errors.New("failed to fetch weather data from external API")
 	ErrEmailSendingFailed     = errors.New("failed to send email")
+	ErrAPIKeyInvalid          = errors.New("API key is missing, invalid or revoked")
+	ErrAPIKeyNotFound         = errors.New("API key not found")
+	ErrAPIKeyQuotaExceeded    = errors.New("daily quota for API key exceeded")
+	ErrAPIKeyRevoked          = errors.New("API key has been revoked")
+	ErrRateLimited            = errors.New("too many requests, please retry later")
+	ErrAdminUnauthorized      = errors.New("admin authorization required")
+	ErrRouteNotFound          = errors.New("resource not found")
+	ErrPrivacyRequestInvalid  = errors.New("privacy request link is invalid, expired, or already used")
+	ErrHistoryUnavailable     = errors.New("no weather history available for this location and date")
+	ErrWebhookVerification    = errors.New("webhook endpoint did not echo the verification challenge")
+	ErrChatWebhookRejected    = errors.New("incoming webhook did not accept the test message")
+	ErrChatSubscriptionLimit  = errors.New("chat already follows the maximum number of cities")
+	ErrRequestTooLarge        = errors.New("request body is too large")
 )
@@ -0,0 +1,23 @@
+package domain
+
+import "time"
+
+type HealthStatus string
+
+const (
+	HealthStatusUp   HealthStatus = "UP"
+	HealthStatusDown HealthStatus = "DOWN"
+)
+
+type ComponentHealth struct {
+	Status    HealthStatus `json:"status"`
+	Error     string       `json:"error,omitempty"`
+	LatencyMs int64        `json:"latency_ms"`
+	CheckedAt time.Time    `json:"checked_at"`
+	Cached    bool         `json:"cached,omitempty"`
+}
+
+type HealthReport struct {
+	Status     HealthStatus               `json:"status"`
+	Components map[string]ComponentHealth `json:"components,omitempty"`
+}
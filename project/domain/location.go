@@ -0,0 +1,187 @@
+package domain
+
+import (
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type LocationKind string
+
+const (
+	LocationCity        LocationKind = "city"
+	LocationCoordinates LocationKind = "coordinates"
+	LocationZip         LocationKind = "zip"
+	LocationIATA        LocationKind = "iata"
+	LocationIP          LocationKind = "ip"
+)
+
+// AutoIP asks for the location of the caller's own IP address.
+const AutoIP = "auto"
+
+// Location is a place the weather provider can resolve. Value is normalized:
+// a city name, "lat,lon", a postal code, an IATA code or an IP address.
+// ProviderID, once known, pins the exact place the provider resolved.
+type Location struct {
+	Kind       LocationKind
+	Value      string
+	ProviderID int64
+}
+
+// ResolvedLocation is a place as canonicalized by the weather provider.
+// ProviderID is zero when the provider did not report one.
+type ResolvedLocation struct {
+	ProviderID int64   `json:"id,omitempty"`
+	Name       string  `gorm:"type:varchar(100)" json:"name"`
+	Region     string  `gorm:"type:varchar(100)" json:"region"`
+	Country    string  `gorm:"type:varchar(100)" json:"country"`
+	Lat        float64 `json:"lat"`
+	Lon        float64 `json:"lon"`
+}
+
+func NewCityLocation(city string) Location {
+	return Location{Kind: LocationCity, Value: strings.TrimSpace(city)}
+}
+
+// Query is the provider's "q" parameter for the location.
+func (l Location) Query() string {
+	switch {
+	case l.ProviderID != 0:
+		return "id:" + strconv.FormatInt(l.ProviderID, 10)
+	case l.Kind == LocationIATA:
+		return "iata:" + l.Value
+	default:
+		return l.Value
+	}
+}
+
+// Coordinates reports the latitude and longitude of a coordinates location.
+func (l Location) Coordinates() (lat, lon float64, ok bool) {
+	if l.Kind != LocationCoordinates {
+		return 0, 0, false
+	}
+	latText, lonText, found := strings.Cut(l.Value, ",")
+	lat, latErr := strconv.ParseFloat(latText, 64)
+	lon, lonErr := strconv.ParseFloat(lonText, 64)
+	return lat, lon, found && latErr == nil && lonErr == nil
+}
+
+func (l Location) String() string {
+	return l.Value
+}
+
+// LogValue keeps IP addresses, which identify a person, out of the logs.
+func (l Location) LogValue() slog.Value {
+	value := l.Value
+	if l.Kind == LocationIP {
+		value = "[REDACTED]"
+	}
+	return slog.GroupValue(slog.String("kind", string(l.Kind)), slog.String("value", value))
+}
+
+var (
+	zipPattern  = regexp.MustCompile(`^[A-Z0-9][A-Z0-9 -]{1,9}$`)
+	iataPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+)
+
+const locationChoices = "city, lat/lon, zip, iata or ip"
+
+// LocationInput accepts exactly one way of naming a place.
+type LocationInput struct {
+	City string   `form:"city" json:"city,omitempty"`
+	Lat  *float64 `form:"lat" json:"lat,omitempty"`
+	Lon  *float64 `form:"lon" json:"lon,omitempty"`
+	Zip  string   `form:"zip" json:"zip,omitempty"`
+	IATA string   `form:"iata" json:"iata,omitempty"`
+	IP   string   `form:"ip" json:"ip,omitempty"`
+}
+
+// Location validates the input and returns the normalized location, or a
+// *ValidationError naming the offending fields.
+func (in LocationInput) Location() (Location, error) {
+	var given []LocationKind
+	if strings.TrimSpace(in.City) != "" {
+		given = append(given, LocationCity)
+	}
+	if in.Lat != nil || in.Lon != nil {
+		given = append(given, LocationCoordinates)
+	}
+	if strings.TrimSpace(in.Zip) != "" {
+		given = append(given, LocationZip)
+	}
+	if strings.TrimSpace(in.IATA) != "" {
+		given = append(given, LocationIATA)
+	}
+	if strings.TrimSpace(in.IP) != "" {
+		given = append(given, LocationIP)
+	}
+	switch len(given) {
+	case 0:
+		return Location{}, NewFieldError("city", "is required, or one of "+locationChoices)
+	case 1:
+	default:
+		return Location{}, NewFieldError("location", "only one of "+locationChoices+" may be given")
+	}
+
+	switch given[0] {
+	case LocationCity:
+		loc := NewCityLocation(in.City)
+		if len([]rune(loc.Value)) < 2 || len(loc.Value) > 100 {
+			return Location{}, NewFieldError("city", "must be between 2 and 100 characters")
+		}
+		return loc, nil
+	case LocationCoordinates:
+		return coordinatesLocation(in.Lat, in.Lon)
+	case LocationZip:
+		zip := strings.ToUpper(strings.TrimSpace(in.Zip))
+		if !zipPattern.MatchString(zip) {
+			return Location{}, NewFieldError("zip", "must be a postal code of 2 to 10 letters, digits, spaces or dashes")
+		}
+		return Location{Kind: LocationZip, Value: zip}, nil
+	case LocationIATA:
+		code := strings.ToUpper(strings.TrimSpace(in.IATA))
+		if !iataPattern.MatchString(code) {
+			return Location{}, NewFieldError("iata", "must be a 3-letter airport code")
+		}
+		return Location{Kind: LocationIATA, Value: code}, nil
+	default:
+		addr, err := netip.ParseAddr(strings.TrimSpace(in.IP))
+		if err != nil {
+			return Location{}, NewFieldError("ip", "must be an IPv4 or IPv6 address")
+		}
+		if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+			return Location{}, NewFieldError("ip", "must be a public address")
+		}
+		return Location{Kind: LocationIP, Value: addr.Unmap().String()}, nil
+	}
+}
+
+func coordinatesLocation(lat, lon *float64) (Location, error) {
+	var fields []FieldError
+	switch {
+	case lat == nil:
+		fields = append(fields, FieldError{Field: "lat", Message: "is required together with lon"})
+	case *lat < -90 || *lat > 90:
+		fields = append(fields, FieldError{Field: "lat", Message: "must be between -90 and 90"})
+	}
+	switch {
+	case lon == nil:
+		fields = append(fields, FieldError{Field: "lon", Message: "is required together with lat"})
+	case *lon < -180 || *lon > 180:
+		fields = append(fields, FieldError{Field: "lon", Message: "must be between -180 and 180"})
+	}
+	if len(fields) > 0 {
+		return Location{}, &ValidationError{Fields: fields}
+	}
+	return CoordinatesOf(*lat, *lon), nil
+}
+
+func CoordinatesOf(lat, lon float64) Location {
+	return Location{
+		Kind:  LocationCoordinates,
+		Value: fmt.Sprintf("%s,%s", strconv.FormatFloat(lat, 'f', -1, 64), strconv.FormatFloat(lon, 'f', -1, 64)),
+	}
+}
@@ -0,0 +1,123 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+// Conditions is the provider-independent form of one weather reading.
+type Conditions struct {
+	TemperatureC  float64     `json:"temperature_c"`
+	FeelsLikeC    float64     `json:"feels_like_c"`
+	Humidity      float64     `json:"humidity"`
+	Description   string      `json:"description"`
+	ConditionCode int         `json:"condition_code"`
+	Condition     Condition   `json:"condition"`
+	Severity      Severity    `json:"severity"`
+	IsDay         bool        `json:"is_day"`
+	WindKph       float64     `json:"wind_kph"`
+	WindDegree    int         `json:"wind_degree"`
+	WindDir       string      `json:"wind_dir"`
+	GustKph       float64     `json:"gust_kph"`
+	PressureMb    float64     `json:"pressure_mb"`
+	PrecipMm      float64     `json:"precip_mm"`
+	Cloud         int         `json:"cloud"`
+	VisKm         float64     `json:"vis_km"`
+	UV            float64     `json:"uv"`
+	AirQuality    *AirQuality `json:"air_quality,omitempty"`
+	Pollen        *Pollen     `json:"pollen,omitempty"`
+}
+
+// WeatherObservation is one current-conditions reading kept in the local
+// archive, keyed by the resolved place rather than by how it was asked for.
+// Conditions are archived without Description, which is in the language of
+// the request; it is rebuilt from Condition when read. LocationQuery is only
+// recorded as a PlaceAlias.
+type WeatherObservation struct {
+	ID            uint64           `gorm:"primaryKey;autoIncrement" json:"-"`
+	LocationQuery string           `gorm:"-" json:"-"`
+	PlaceKey      string           `gorm:"type:varchar(255);not null;uniqueIndex:idx_observation_reading,priority:1;index:idx_observation_place_date,priority:1" json:"-"`
+	Location      ResolvedLocation `gorm:"embedded;embeddedPrefix:location_" json:"location"`
+	LocalDate     string           `gorm:"type:char(10);not null;index:idx_observation_place_date,priority:2" json:"local_date"`
+	ObservedAt    time.Time        `gorm:"not null;uniqueIndex:idx_observation_reading,priority:2" json:"observed_at"`
+	Conditions    Conditions       `gorm:"type:json;serializer:json" json:"conditions"`
+	CreatedAt     time.Time        `json:"-"`
+}
+
+// PlaceAlias remembers the place a location query last resolved to, so the
+// archive answers any spelling seen before. IP addresses are never recorded.
+type PlaceAlias struct {
+	Query     string `gorm:"type:varchar(100);primaryKey"`
+	PlaceKey  string `gorm:"type:varchar(255);not null"`
+	UpdatedAt time.Time
+}
+
+// ArchiveQuery is the PlaceAlias query under which location is looked up in
+// the archive; it is empty for IP addresses.
+func ArchiveQuery(location Location) string {
+	if location.Kind == LocationIP {
+		return ""
+	}
+	return strings.ToLower(location.Value)
+}
+
+// PlaceKeyOf identifies a resolved place independently of how it was asked for.
+func PlaceKeyOf(place ResolvedLocation) string {
+	return strings.ToLower(place.Name + "|" + place.Region + "|" + place.Country)
+}
+
+func (o *WeatherObservation) Summary() *WeatherResponse {
+	return &WeatherResponse{
+		Temperature: o.Conditions.TemperatureC,
+		Humidity:    o.Conditions.Humidity,
+		Description: o.Conditions.Description,
+		Condition:   o.Conditions.Condition,
+		Severity:    o.Conditions.Severity,
+		AirQuality:  o.Conditions.AirQuality,
+		Pollen:      o.Conditions.Pollen,
+	}
+}
+
+type HistorySource string
+
+const (
+	HistorySourceAuto     HistorySource = "auto"
+	HistorySourceArchive  HistorySource = "archive"
+	HistorySourceProvider HistorySource = "provider"
+)
+
+type WeatherHistoryInput struct {
+	LocationInput
+	Date   string `form:"date" binding:"required,datetime=2006-01-02"`
+	Source string `form:"source" binding:"omitempty,oneof=auto archive provider"`
+}
+
+type DaySummary struct {
+	MinTempC      float64  `json:"min_temp_c"`
+	MaxTempC      float64  `json:"max_temp_c"`
+	AvgTempC      float64  `json:"avg_temp_c"`
+	AvgHumidity   float64  `json:"avg_humidity"`
+	MaxWindKph    float64  `json:"max_wind_kph"`
+	TotalPrecipMm *float64 `json:"total_precip_mm,omitempty"`
+	Description   string   `json:"description"`
+	// Condition and Severity describe the worst weather of the day.
+	Condition Condition `json:"condition"`
+	Severity  Severity  `json:"severity"`
+}
+
+type HourlyConditions struct {
+	Time time.Time `json:"time"`
+	Conditions
+}
+
+// WeatherHistory is the weather of one local calendar day at a place.
+type WeatherHistory struct {
+	Date     string             `json:"date"`
+	Source   HistorySource      `json:"source"`
+	Location ResolvedLocation   `json:"location"`
+	Day      DaySummary         `json:"day"`
+	Hours    []HourlyConditions `json:"hours"`
+	// Partial is set when archived readings cover fewer hours of the day
+	// than required, so the summary may miss part of it.
+	Partial bool `json:"partial"`
+}
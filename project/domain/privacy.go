@@ -0,0 +1,71 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type PrivacyRequestKind string
+
+const (
+	PrivacyRequestExport  PrivacyRequestKind = "export"
+	PrivacyRequestErasure PrivacyRequestKind = "erasure"
+)
+
+// PrivacyRequest is a pending data export or erasure. It is only acted upon
+// once the owner of the address follows the token link sent to them.
+type PrivacyRequest struct {
+	ID          uuid.UUID          `gorm:"type:char(36);primary_key;" json:"id"`
+	Email       string             `gorm:"type:varchar(255);index;not null" json:"email"`
+	Kind        PrivacyRequestKind `gorm:"type:varchar(10);not null" json:"kind"`
+	Token       string             `gorm:"type:varchar(64);uniqueIndex;not null" json:"-"`
+	ExpiresAt   time.Time          `gorm:"index" json:"expires_at"`
+	CompletedAt *time.Time         `json:"completed_at,omitempty"`
+	CreatedAt   time.Time          `json:"created_at"`
+}
+
+func (r *PrivacyRequest) BeforeCreate(tx *gorm.DB) (err error) {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return
+}
+
+type PrivacyRequestInput struct {
+	Email string `form:"email" json:"email" binding:"required,email"`
+}
+
+// SubscriptionExport is a subscription as disclosed to its owner, including
+// subscriptions that were unsubscribed but not purged yet.
+type SubscriptionExport struct {
+	ID                uuid.UUID             `json:"id"`
+	City              string                `json:"city"`
+	LocationKind      LocationKind          `json:"location_kind"`
+	Location          ResolvedLocation      `json:"location"`
+	Frequency         SubscriptionFrequency `json:"frequency"`
+	IncludeAirQuality bool                  `json:"include_air_quality"`
+	IncludePollen     bool                  `json:"include_pollen"`
+	Language          string                `json:"language"`
+	Channels          []SubscriptionChannel `json:"channels"`
+	Confirmed         bool                  `json:"confirmed"`
+	CreatedAt         time.Time             `json:"created_at"`
+	UpdatedAt         time.Time             `json:"updated_at"`
+	UnsubscribedAt    *time.Time            `json:"unsubscribed_at,omitempty"`
+}
+
+type PersonalDataExport struct {
+	Email           string               `json:"email"`
+	GeneratedAt     time.Time            `json:"generated_at"`
+	Subscriptions   []SubscriptionExport `json:"subscriptions"`
+	PrivacyRequests []PrivacyRequest     `json:"privacy_requests"`
+	// WebhookDeliveries lists recorded deliveries to the subscriber's webhooks.
+	WebhookDeliveries []WebhookDelivery `json:"webhook_deliveries"`
+}
+
+// ErasureResult reports how many records were purged, per table.
+type ErasureResult struct {
+	Email   string           `json:"email"`
+	Deleted map[string]int64 `json:"deleted"`
+}
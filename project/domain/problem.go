@@ -0,0 +1,48 @@
+package domain
+
+import (
+	"errors"
+	"strings"
+)
+
+// Problem is an RFC 7807 problem details document. Code is a stable,
+// machine-readable identifier that clients can switch on.
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      string       `json:"code"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+var ErrInvalidInput = errors.New("invalid input")
+
+// ValidationError reports invalid request fields. It matches ErrInvalidInput
+// with errors.Is.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func NewFieldError(field, message string) *ValidationError {
+	return &ValidationError{Fields: []FieldError{{Field: field, Message: message}}}
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		messages[i] = f.Field + ": " + f.Message
+	}
+	return "invalid input: " + strings.Join(messages, "; ")
+}
+
+func (e *ValidationError) Unwrap() error {
+	return ErrInvalidInput
+}
@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"math"
 	"time"
 
 	"github.com/google/uuid"
@@ -14,17 +15,42 @@ const (
 	FrequencyDaily  SubscriptionFrequency = "daily"
 )
 
+// MaxSubscriptionsPerChat caps how many cities one Telegram chat follows.
+const MaxSubscriptionsPerChat = 10
+
+// Subscription.City holds the normalized location value, interpreted
+// according to LocationKind. For cities it is the provider's canonical name.
+//
+// A subscription is identified either by Email, which allows a single
+// subscription per address, or by TelegramChatID, which allows one per city.
 type Subscription struct {
-	ID        uuid.UUID             `gorm:"type:char(36);primary_key;" json:"-"`
-	Email     string                `gorm:"type:varchar(255);uniqueIndex;not null" json:"email"`
-	City      string                `gorm:"type:varchar(100);not null" json:"city"`
-	Frequency SubscriptionFrequency `gorm:"type:varchar(10);not null" json:"frequency"`
+	ID             uuid.UUID             `gorm:"type:char(36);primary_key;" json:"id"`
+	Email          *string               `gorm:"type:varchar(255);uniqueIndex" json:"email,omitempty"`
+	TelegramChatID *int64                `gorm:"index" json:"telegram_chat_id,omitempty"`
+	City           string                `gorm:"type:varchar(100);not null" json:"city"`
+	LocationKind   LocationKind          `gorm:"type:varchar(16);not null;default:city" json:"location_kind"`
+	Resolved       ResolvedLocation      `gorm:"embedded;embeddedPrefix:location_" json:"location"`
+	Frequency      SubscriptionFrequency `gorm:"type:varchar(10);not null" json:"frequency"`
+	// IncludeAirQuality adds an air quality section to weather update emails.
+	IncludeAirQuality bool `gorm:"not null;default:false" json:"include_air_quality"`
+	// IncludePollen adds pollen counts to weather updates.
+	IncludePollen bool `gorm:"not null;default:false" json:"include_pollen"`
+	// Language of the emails and weather descriptions sent to the subscriber.
+	Language string `gorm:"type:varchar(8);not null;default:en" json:"language"`
+	// Channels lists where weather updates go; there is at least one.
+	Channels  []SubscriptionChannel `gorm:"foreignKey:SubscriptionID;constraint:OnDelete:CASCADE" json:"channels"`
 	Confirmed bool                  `gorm:"default:false" json:"confirmed"`
+	// LastUpdateAt is when the scheduler last sent a weather update to every
+	// channel.
+	LastUpdateAt *time.Time `gorm:"index" json:"last_update_at,omitempty"`
+	// UpdateClaimedUntil is set while a scheduler replica sends the update,
+	// so the others skip the subscription until it passes.
+	UpdateClaimedUntil *time.Time `json:"-"`
 
 	ConfirmToken     *string        `gorm:"type:varchar(64);uniqueIndex" json:"-"`
 	UnsubscribeToken *string        `gorm:"type:varchar(64);uniqueIndex" json:"-"`
-	CreatedAt        time.Time      `json:"-"`
-	UpdatedAt        time.Time      `json:"-"`
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
 	DeletedAt        gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
@@ -35,8 +61,122 @@ func (s *Subscription) BeforeCreate(tx *gorm.DB) (err error) {
 	return
 }
 
+// EmailAddress is the subscriber's address, or "" for chat subscriptions.
+func (s *Subscription) EmailAddress() string {
+	if s.Email == nil {
+		return ""
+	}
+	return *s.Email
+}
+
+func (s *Subscription) Location() Location {
+	kind := s.LocationKind
+	if kind == "" {
+		kind = LocationCity
+	}
+	return Location{Kind: kind, Value: s.City, ProviderID: s.Resolved.ProviderID}
+}
+
+// WeatherOptions is what to fetch for the subscriber's weather updates. The
+// Slack and Discord posts carry the day's forecast.
+func (s *Subscription) WeatherOptions() WeatherOptions {
+	opts := WeatherOptions{AirQuality: s.IncludeAirQuality, Pollen: s.IncludePollen}
+	for _, channel := range s.Channels {
+		if channel.Kind == ChannelSlack || channel.Kind == ChannelDiscord {
+			opts.Forecast = true
+		}
+	}
+	return opts
+}
+
+// UpdateDue reports whether a weather update is due at now. Hourly updates
+// go out once per UTC hour, daily ones once the local clock at the location
+// reaches dailyHour. Local time is estimated from the longitude, one hour per
+// 15 degrees, so no time zone has to be stored. A slot that passed before the
+// subscription was created is skipped.
+func (s *Subscription) UpdateDue(now time.Time, dailyHour int) bool {
+	now = now.UTC()
+	slot := now.Truncate(time.Hour)
+	if s.Frequency == FrequencyDaily {
+		offset := time.Duration(math.Round(s.Resolved.Lon/15)) * time.Hour
+		local := now.Add(offset)
+		slot = time.Date(local.Year(), local.Month(), local.Day(), dailyHour, 0, 0, 0, time.UTC).Add(-offset)
+		if slot.After(now) {
+			return false
+		}
+	}
+	last := s.CreatedAt
+	if s.LastUpdateAt != nil {
+		last = *s.LastUpdateAt
+	}
+	return last.Before(slot)
+}
+
 type SubscriptionInput struct {
-	Email     string `form:"email" json:"email" binding:"required,email"`
-	City      string `form:"city" json:"city" binding:"required,min=2"`
-	Frequency string `form:"frequency" json:"frequency" binding:"required,oneof=hourly daily"`
+	Email string `form:"email" json:"email" binding:"required,email"`
+	LocationInput
+	Frequency         string `form:"frequency" json:"frequency" binding:"required,oneof=hourly daily"`
+	IncludeAirQuality bool   `form:"include_air_quality" json:"include_air_quality"`
+	IncludePollen     bool   `form:"include_pollen" json:"include_pollen"`
+	// Language defaults to the language of the request.
+	Language string `form:"language" json:"language" binding:"omitempty,oneof=en uk"`
+	// ChannelList takes precedence over the single-channel fields below,
+	// which also work in form posts.
+	ChannelList   []ChannelInput `form:"-" json:"channels"`
+	Channel       string         `form:"channel" json:"channel" binding:"omitempty,oneof=email webhook slack discord"`
+	WebhookURL    string         `form:"webhook_url" json:"webhook_url"`
+	WebhookSecret string         `form:"webhook_secret" json:"webhook_secret"`
+}
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// SubscriptionFilter narrows admin listings. Email and City match substrings;
+// zero values mean "any".
+type SubscriptionFilter struct {
+	Email     string `form:"email"`
+	City      string `form:"city"`
+	Frequency string `form:"frequency" binding:"omitempty,oneof=hourly daily"`
+	Confirmed *bool  `form:"confirmed"`
+	Page      int    `form:"page" binding:"omitempty,min=1"`
+	PageSize  int    `form:"page_size" binding:"omitempty,min=1,max=100"`
+}
+
+func (f *SubscriptionFilter) Normalize() {
+	if f.Page < 1 {
+		f.Page = 1
+	}
+	if f.PageSize < 1 {
+		f.PageSize = DefaultPageSize
+	}
+	if f.PageSize > MaxPageSize {
+		f.PageSize = MaxPageSize
+	}
+}
+
+type SubscriptionPage struct {
+	Items    []Subscription `json:"items"`
+	Total    int64          `json:"total"`
+	Page     int            `json:"page"`
+	PageSize int            `json:"page_size"`
+}
+
+type BulkAction string
+
+const (
+	BulkActionConfirm     BulkAction = "confirm"
+	BulkActionUnsubscribe BulkAction = "unsubscribe"
+	BulkActionDelete      BulkAction = "delete"
+)
+
+type BulkSubscriptionInput struct {
+	Action BulkAction  `json:"action" binding:"required,oneof=confirm unsubscribe delete"`
+	IDs    []uuid.UUID `json:"ids" binding:"required,min=1,max=100"`
+}
+
+type BulkSubscriptionResult struct {
+	Succeeded []uuid.UUID       `json:"succeeded"`
+	Failed    map[string]string `json:"failed,omitempty"`
 }
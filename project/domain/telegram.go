@@ -0,0 +1,47 @@
+package domain
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// TelegramResponse is the envelope of every Bot API reply.
+type TelegramResponse struct {
+	OK          bool            `json:"ok"`
+	Result      json.RawMessage `json:"result"`
+	ErrorCode   int             `json:"error_code"`
+	Description string          `json:"description"`
+}
+
+type TelegramUpdate struct {
+	UpdateID int64            `json:"update_id"`
+	Message  *TelegramMessage `json:"message"`
+}
+
+type TelegramMessage struct {
+	MessageID int64         `json:"message_id"`
+	From      *TelegramUser `json:"from"`
+	Chat      TelegramChat  `json:"chat"`
+	Text      string        `json:"text"`
+}
+
+type TelegramUser struct {
+	ID           int64  `json:"id"`
+	LanguageCode string `json:"language_code"`
+}
+
+type TelegramChat struct {
+	ID   int64  `json:"id"`
+	Type string `json:"type"`
+}
+
+// Command splits a "/name@bot arg1 arg2" message into the lower-case command
+// name and its arguments. ok is false for text that is not a command.
+func (m *TelegramMessage) Command() (name string, args []string, ok bool) {
+	fields := strings.Fields(m.Text)
+	if len(fields) == 0 || !strings.HasPrefix(fields[0], "/") {
+		return "", nil, false
+	}
+	name, _, _ = strings.Cut(strings.TrimPrefix(fields[0], "/"), "@")
+	return strings.ToLower(name), fields[1:], true
+}
@@ -1,42 +1,373 @@
 package domain
 
+import (
+	"strings"
+	"time"
+)
+
 type WeatherResponse struct {
-	Temperature float64 `json:"temperature"`
-	Humidity    float64 `json:"humidity"`
-	Description string  `json:"description"`
+	Temperature float64     `json:"temperature"`
+	Humidity    float64     `json:"humidity"`
+	Description string      `json:"description"`
+	Condition   Condition   `json:"condition"`
+	Severity    Severity    `json:"severity"`
+	AirQuality  *AirQuality `json:"air_quality,omitempty"`
+	Pollen      *Pollen     `json:"pollen,omitempty"`
+	// Forecast is only filled in when asked for with WeatherOptions.Forecast.
+	Forecast *WeatherForecast `json:"forecast,omitempty"`
+}
+
+// WeatherForecast is the provider's outlook for the current local day.
+type WeatherForecast struct {
+	Date          string    `json:"date"`
+	MinTempC      float64   `json:"min_temp_c"`
+	MaxTempC      float64   `json:"max_temp_c"`
+	ChanceOfRain  int       `json:"chance_of_rain"`
+	TotalPrecipMm float64   `json:"total_precip_mm"`
+	Description   string    `json:"description"`
+	Condition     Condition `json:"condition"`
+	Severity      Severity  `json:"severity"`
+}
+
+// AirQuality holds pollutant concentrations in µg/m³ and the US EPA index
+// (1 good to 6 hazardous).
+type AirQuality struct {
+	PM25          float64 `json:"pm2_5"`
+	PM10          float64 `json:"pm10"`
+	O3            float64 `json:"o3"`
+	NO2           float64 `json:"no2"`
+	USEPAIndex    int     `json:"us_epa_index"`
+	USEPACategory string  `json:"us_epa_category"`
+}
+
+// Pollen holds pollen concentrations in grains/m³ per plant.
+type Pollen struct {
+	Hazel   float64 `json:"hazel"`
+	Alder   float64 `json:"alder"`
+	Birch   float64 `json:"birch"`
+	Oak     float64 `json:"oak"`
+	Grass   float64 `json:"grass"`
+	Mugwort float64 `json:"mugwort"`
+	Ragweed float64 `json:"ragweed"`
+}
+
+// Plants lists the concentrations in a fixed order, labelled with the
+// English plant names.
+func (p *Pollen) Plants() []PollenCount {
+	return []PollenCount{
+		{"Hazel", p.Hazel}, {"Alder", p.Alder}, {"Birch", p.Birch}, {"Oak", p.Oak},
+		{"Grass", p.Grass}, {"Mugwort", p.Mugwort}, {"Ragweed", p.Ragweed},
+	}
+}
+
+// Highest returns the plant with the most pollen, the first one on a tie.
+func (p *Pollen) Highest() PollenCount {
+	plants := p.Plants()
+	highest := plants[0]
+	for _, plant := range plants[1:] {
+		if plant.GrainsPerM3 > highest.GrainsPerM3 {
+			highest = plant
+		}
+	}
+	return highest
+}
+
+type PollenCount struct {
+	Plant       string
+	GrainsPerM3 float64
+}
+
+var usEPACategories = []string{"Good", "Moderate", "Unhealthy for sensitive groups", "Unhealthy", "Very unhealthy", "Hazardous"}
+
+func USEPACategory(index int) string {
+	if index < 1 || index > len(usEPACategories) {
+		return ""
+	}
+	return usEPACategories[index-1]
+}
+
+// WeatherOptions selects optional data to fetch along with the conditions.
+type WeatherOptions struct {
+	AirQuality bool
+	Pollen     bool
+	Forecast   bool
+}
+
+// "include" values that add optional data sets.
+const (
+	IncludeAirQuality = "aqi"
+	IncludePollen     = "pollen"
+	IncludeForecast   = "forecast"
+)
+
+type WeatherInput struct {
+	LocationInput
+	Include string `form:"include"`
+}
+
+// Options parses Include, a comma-separated list of optional data sets.
+func (in WeatherInput) Options() (WeatherOptions, error) {
+	var opts WeatherOptions
+	for _, item := range strings.Split(in.Include, ",") {
+		switch strings.ToLower(strings.TrimSpace(item)) {
+		case "":
+		case IncludeAirQuality:
+			opts.AirQuality = true
+		case IncludePollen:
+			opts.Pollen = true
+		case IncludeForecast:
+			opts.Forecast = true
+		default:
+			return WeatherOptions{}, NewFieldError("include", "must be a comma-separated list of: aqi, pollen, forecast")
+		}
+	}
+	return opts, nil
+}
+
+// WeatherStreamInput names the city whose live weather is streamed.
+type WeatherStreamInput struct {
+	City string `form:"city"`
+}
+
+func (in WeatherStreamInput) Location() (Location, error) {
+	if strings.TrimSpace(in.City) == "" {
+		return Location{}, NewFieldError("city", "is required")
+	}
+	return LocationInput{City: in.City}.Location()
 }
 
 type ExternalWeatherAPIResponse struct {
-	Location struct {
-		Name    string `json:"name"`
-		Region  string `json:"region"`
-		Country string `json:"country"`
-	} `json:"location"`
-	Current struct {
-		TempC     float64 `json:"temp_c"`
-		TempF     float64 `json:"temp_f"`
-		IsDay     int     `json:"is_day"`
-		Condition struct {
+	Location ExternalLocation   `json:"location"`
+	Current  ExternalConditions `json:"current"`
+}
+
+type ExternalLocation struct {
+	Name    string  `json:"name"`
+	Region  string  `json:"region"`
+	Country string  `json:"country"`
+	Lat     float64 `json:"lat"`
+	Lon     float64 `json:"lon"`
+	TzID    string  `json:"tz_id"`
+	// Localtime is "YYYY-MM-DD h:mm" at the location, at LocaltimeEpoch.
+	Localtime      string `json:"localtime"`
+	LocaltimeEpoch int64  `json:"localtime_epoch"`
+}
+
+// Zone is the location's UTC offset at the time of the response, derived
+// from its local and Unix time since the zone database may be missing.
+func (l ExternalLocation) Zone() *time.Location {
+	local, err := time.Parse("2006-01-02 15:04", l.Localtime)
+	if err != nil || l.LocaltimeEpoch == 0 {
+		return time.UTC
+	}
+	offset := local.Sub(time.Unix(l.LocaltimeEpoch, 0)).Round(15 * time.Minute)
+	return time.FixedZone(l.TzID, int(offset.Seconds()))
+}
+
+// ExternalConditions is shared by current conditions and hourly history.
+type ExternalConditions struct {
+	LastUpdatedEpoch int64   `json:"last_updated_epoch"`
+	TimeEpoch        int64   `json:"time_epoch"`
+	TempC            float64 `json:"temp_c"`
+	TempF            float64 `json:"temp_f"`
+	IsDay            int     `json:"is_day"`
+	Condition        struct {
+		Text string `json:"text"`
+		Icon string `json:"icon"`
+		Code int    `json:"code"`
+	} `json:"condition"`
+	WindMph    float64 `json:"wind_mph"`
+	WindKph    float64 `json:"wind_kph"`
+	WindDegree int     `json:"wind_degree"`
+	WindDir    string  `json:"wind_dir"`
+	PressureMb float64 `json:"pressure_mb"`
+	PressureIn float64 `json:"pressure_in"`
+	PrecipMm   float64 `json:"precip_mm"`
+	PrecipIn   float64 `json:"precip_in"`
+	Humidity   int     `json:"humidity"`
+	Cloud      int     `json:"cloud"`
+	FeelslikeC float64 `json:"feelslike_c"`
+	FeelslikeF float64 `json:"feelslike_f"`
+	VisKm      float64 `json:"vis_km"`
+	VisMiles   float64 `json:"vis_miles"`
+	UV         float64 `json:"uv"`
+	GustMph    float64 `json:"gust_mph"`
+	GustKph    float64 `json:"gust_kph"`
+	// AirQuality is only sent when requested with aqi=yes.
+	AirQuality *ExternalAirQuality `json:"air_quality"`
+	// Pollen is only sent when requested with pollen=yes.
+	Pollen *ExternalPollen `json:"pollen"`
+}
+
+type ExternalAirQuality struct {
+	CO         float64 `json:"co"`
+	NO2        float64 `json:"no2"`
+	O3         float64 `json:"o3"`
+	SO2        float64 `json:"so2"`
+	PM25       float64 `json:"pm2_5"`
+	PM10       float64 `json:"pm10"`
+	USEPAIndex int     `json:"us-epa-index"`
+}
+
+// ExternalPollen is in grains/m³; the provider capitalizes the plant names.
+type ExternalPollen struct {
+	Hazel   float64 `json:"Hazel"`
+	Alder   float64 `json:"Alder"`
+	Birch   float64 `json:"Birch"`
+	Oak     float64 `json:"Oak"`
+	Grass   float64 `json:"Grass"`
+	Mugwort float64 `json:"Mugwort"`
+	Ragweed float64 `json:"Ragweed"`
+}
+
+// Normalize converts the provider's reading into Conditions.
+func (c ExternalConditions) Normalize() Conditions {
+	condition, severity := ConditionFromWeatherAPI(c.Condition.Code)
+	return Conditions{
+		TemperatureC:  c.TempC,
+		FeelsLikeC:    c.FeelslikeC,
+		Humidity:      float64(c.Humidity),
+		Description:   c.Condition.Text,
+		ConditionCode: c.Condition.Code,
+		Condition:     condition,
+		Severity:      severity,
+		IsDay:         c.IsDay == 1,
+		WindKph:       c.WindKph,
+		WindDegree:    c.WindDegree,
+		WindDir:       c.WindDir,
+		GustKph:       c.GustKph,
+		PressureMb:    c.PressureMb,
+		PrecipMm:      c.PrecipMm,
+		Cloud:         c.Cloud,
+		VisKm:         c.VisKm,
+		UV:            c.UV,
+		AirQuality:    c.AirQuality.normalize(),
+		Pollen:        c.Pollen.normalize(),
+	}
+}
+
+func (p *ExternalPollen) normalize() *Pollen {
+	if p == nil {
+		return nil
+	}
+	pollen := Pollen(*p)
+	return &pollen
+}
+
+func (a *ExternalAirQuality) normalize() *AirQuality {
+	if a == nil {
+		return nil
+	}
+	return &AirQuality{
+		PM25:          a.PM25,
+		PM10:          a.PM10,
+		O3:            a.O3,
+		NO2:           a.NO2,
+		USEPAIndex:    a.USEPAIndex,
+		USEPACategory: USEPACategory(a.USEPAIndex),
+	}
+}
+
+// ExternalHistoryResponse is the provider's history.json for a single day.
+type ExternalHistoryResponse struct {
+	Location ExternalLocation `json:"location"`
+	Forecast struct {
+		ForecastDay []struct {
+			Date string `json:"date"`
+			Day  struct {
+				MaxTempC      float64 `json:"maxtemp_c"`
+				MinTempC      float64 `json:"mintemp_c"`
+				AvgTempC      float64 `json:"avgtemp_c"`
+				MaxWindKph    float64 `json:"maxwind_kph"`
+				TotalPrecipMm float64 `json:"totalprecip_mm"`
+				AvgHumidity   float64 `json:"avghumidity"`
+				Condition     struct {
+					Text string `json:"text"`
+					Code int    `json:"code"`
+				} `json:"condition"`
+			} `json:"day"`
+			Hour []ExternalConditions `json:"hour"`
+		} `json:"forecastday"`
+	} `json:"forecast"`
+}
+
+// ExternalForecastResponse is the provider's forecast.json.
+type ExternalForecastResponse struct {
+	Location ExternalLocation `json:"location"`
+	Forecast struct {
+		ForecastDay []ExternalForecastDay `json:"forecastday"`
+	} `json:"forecast"`
+}
+
+type ExternalForecastDay struct {
+	Date string `json:"date"`
+	Day  struct {
+		MaxTempC          float64 `json:"maxtemp_c"`
+		MinTempC          float64 `json:"mintemp_c"`
+		TotalPrecipMm     float64 `json:"totalprecip_mm"`
+		DailyChanceOfRain int     `json:"daily_chance_of_rain"`
+		Condition         struct {
 			Text string `json:"text"`
-			Icon string `json:"icon"`
 			Code int    `json:"code"`
 		} `json:"condition"`
-		WindMph    float64 `json:"wind_mph"`
-		WindKph    float64 `json:"wind_kph"`
-		WindDegree int     `json:"wind_degree"`
-		WindDir    string  `json:"wind_dir"`
-		PressureMb float64 `json:"pressure_mb"`
-		PressureIn float64 `json:"pressure_in"`
-		PrecipMm   float64 `json:"precip_mm"`
-		PrecipIn   float64 `json:"precip_in"`
-		Humidity   int     `json:"humidity"`
-		Cloud      int     `json:"cloud"`
-		FeelslikeC float64 `json:"feelslike_c"`
-		FeelslikeF float64 `json:"feelslike_f"`
-		VisKm      float64 `json:"vis_km"`
-		VisMiles   float64 `json:"vis_miles"`
-		UV         float64 `json:"uv"`
-		GustMph    float64 `json:"gust_mph"`
-		GustKph    float64 `json:"gust_kph"`
-	} `json:"current"`
+	} `json:"day"`
+}
+
+func (d ExternalForecastDay) Normalize() *WeatherForecast {
+	condition, severity := ConditionFromWeatherAPI(d.Day.Condition.Code)
+	return &WeatherForecast{
+		Date:          d.Date,
+		MinTempC:      d.Day.MinTempC,
+		MaxTempC:      d.Day.MaxTempC,
+		ChanceOfRain:  d.Day.DailyChanceOfRain,
+		TotalPrecipMm: d.Day.TotalPrecipMm,
+		Description:   d.Day.Condition.Text,
+		Condition:     condition,
+		Severity:      severity,
+	}
+}
+
+// ExternalError is the body WeatherAPI sends with 4xx responses.
+type ExternalError struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// ExternalErrorNoLocation is WeatherAPI's "No matching location found" code.
+const ExternalErrorNoLocation = 1006
+
+// ExternalLocationSearchResult is one entry of the provider's search.json.
+type ExternalLocationSearchResult struct {
+	ID      int64   `json:"id"`
+	Name    string  `json:"name"`
+	Region  string  `json:"region"`
+	Country string  `json:"country"`
+	Lat     float64 `json:"lat"`
+	Lon     float64 `json:"lon"`
+}
+
+// MaxWeatherBatchSize caps the number of cities in one batch lookup.
+const MaxWeatherBatchSize = 50
+
+type WeatherBatchInput struct {
+	Cities []string `json:"cities" binding:"required,min=1,max=50,dive,required"`
+}
+
+// WeatherLookup is the outcome of resolving one city of a batch.
+type WeatherLookup struct {
+	City    string
+	Weather *WeatherResponse
+	Err     error
+}
+
+type WeatherBatchItem struct {
+	City    string           `json:"city"`
+	Weather *WeatherResponse `json:"weather,omitempty"`
+	Error   *Problem         `json:"error,omitempty"`
+}
+
+type WeatherBatchResponse struct {
+	Results []WeatherBatchItem `json:"results"`
 }
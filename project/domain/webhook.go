@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	WebhookEventChallenge     = "url_verification"
+	WebhookEventWeatherUpdate = "weather.update"
+
+	// WebhookSignatureHeader carries "t=<unix time>,v1=<hex HMAC-SHA256 of
+	// "<unix time>.<body>" keyed with the subscription's secret>".
+	WebhookSignatureHeader = "X-Webhook-Signature"
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookIDHeader        = "X-Webhook-ID"
+)
+
+// WebhookChallenge is sent once at subscribe time; the endpoint proves it is
+// willing to receive updates by echoing Challenge back.
+type WebhookChallenge struct {
+	ID        string `json:"id"`
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+}
+
+type WebhookWeatherUpdate struct {
+	ID             string           `json:"id"`
+	Type           string           `json:"type"`
+	CreatedAt      time.Time        `json:"created_at"`
+	SubscriptionID uuid.UUID        `json:"subscription_id"`
+	City           string           `json:"city"`
+	Location       ResolvedLocation `json:"location"`
+	Weather        *WeatherResponse `json:"weather"`
+	UnsubscribeURL string           `json:"unsubscribe_url,omitempty"`
+}
+
+// WebhookDelivery records one attempt to deliver an event. Attempts of the
+// same event share EventID.
+type WebhookDelivery struct {
+	ID             uint64    `gorm:"primaryKey;autoIncrement" json:"id"`
+	SubscriptionID uuid.UUID `gorm:"type:char(36);not null;index" json:"subscription_id"`
+	EventID        string    `gorm:"type:char(36);not null" json:"event_id"`
+	EventType      string    `gorm:"type:varchar(32);not null" json:"event_type"`
+	Attempt        int       `gorm:"not null" json:"attempt"`
+	URL            string    `gorm:"type:varchar(2048);not null" json:"url"`
+	StatusCode     int       `json:"status_code,omitempty"`
+	Error          string    `gorm:"type:varchar(512)" json:"error,omitempty"`
+	Succeeded      bool      `gorm:"not null" json:"succeeded"`
+	DurationMs     int64     `json:"duration_ms"`
+	CreatedAt      time.Time `json:"created_at"`
+}
@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"net/http"
+	"weather/project/domain"
+	"weather/project/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type APIKeyHandler struct {
+	apiKeyService service.APIKeyService
+}
+
+func NewAPIKeyHandler(aks service.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{apiKeyService: aks}
+}
+
+func (h *APIKeyHandler) Create(c *gin.Context) {
+	var input domain.APIKeyInput
+	if err := c.ShouldBind(&input); err != nil {
+		_ = c.Error(err).SetType(gin.ErrorTypeBind)
+		return
+	}
+
+	issued, err := h.apiKeyService.Create(c.Request.Context(), input)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, issued)
+}
+
+func (h *APIKeyHandler) List(c *gin.Context) {
+	keys, err := h.apiKeyService.List(c.Request.Context())
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"api_keys": keys})
+}
+
+func (h *APIKeyHandler) Rotate(c *gin.Context) {
+	id, ok := uuidParam(c)
+	if !ok {
+		return
+	}
+
+	issued, err := h.apiKeyService.Rotate(c.Request.Context(), id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, issued)
+}
+
+func (h *APIKeyHandler) Revoke(c *gin.Context) {
+	id, ok := uuidParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.apiKeyService.Revoke(c.Request.Context(), id); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "API key revoked"})
+}
+
+func uuidParam(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		_ = c.Error(domain.NewFieldError("id", "must be a valid UUID"))
+		return uuid.Nil, false
+	}
+	return id, true
+}
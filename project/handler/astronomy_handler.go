@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"net/http"
+	"weather/project/domain"
+	"weather/project/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AstronomyHandler struct {
+	astronomyService service.AstronomyService
+}
+
+func NewAstronomyHandler(as service.AstronomyService) *AstronomyHandler {
+	return &AstronomyHandler{astronomyService: as}
+}
+
+// GetAstronomy takes the same location parameters as GetWeather and an
+// optional local date, today by default.
+func (h *AstronomyHandler) GetAstronomy(c *gin.Context) {
+	var input domain.AstronomyInput
+	if err := c.ShouldBindQuery(&input); err != nil {
+		_ = c.Error(err).SetType(gin.ErrorTypeBind)
+		return
+	}
+	resolveAutoIP(c, &input.LocationInput)
+
+	location, err := input.Location()
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	astronomy, err := h.astronomyService.GetAstronomy(c.Request.Context(), location, input.Date)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, astronomy)
+}
@@ -0,0 +1,30 @@
+package handler
+
+import (
+	"net/http"
+	"weather/project/domain"
+	"weather/project/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HealthHandler struct {
+	healthService service.HealthService
+}
+
+func NewHealthHandler(hs service.HealthService) *HealthHandler {
+	return &HealthHandler{healthService: hs}
+}
+
+func (h *HealthHandler) Live(c *gin.Context) {
+	c.JSON(http.StatusOK, h.healthService.Liveness())
+}
+
+func (h *HealthHandler) Ready(c *gin.Context) {
+	report := h.healthService.Readiness(c.Request.Context())
+	if report.Status != domain.HealthStatusUp {
+		c.JSON(http.StatusServiceUnavailable, report)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
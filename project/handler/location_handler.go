@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"weather/project/domain"
+	"weather/project/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type LocationHandler struct {
+	locationService service.LocationService
+}
+
+func NewLocationHandler(ls service.LocationService) *LocationHandler {
+	return &LocationHandler{locationService: ls}
+}
+
+func (h *LocationHandler) Search(c *gin.Context) {
+	query := strings.TrimSpace(c.Query("q"))
+	if len([]rune(query)) < 2 || len(query) > 100 {
+		_ = c.Error(domain.NewFieldError("q", "must be between 2 and 100 characters"))
+		return
+	}
+
+	matches, err := h.locationService.Search(c.Request.Context(), query)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"results": matches})
+}
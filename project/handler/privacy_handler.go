@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"context"
+	"html/template"
+	"net/http"
+	"weather/project/domain"
+	"weather/project/i18n"
+	"weather/project/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PrivacyHandler struct {
+	privacyService service.PrivacyService
+}
+
+func NewPrivacyHandler(ps service.PrivacyService) *PrivacyHandler {
+	return &PrivacyHandler{privacyService: ps}
+}
+
+const privacyRequestAccepted = "If we hold data for this address, a verification link has been sent to it."
+
+func (h *PrivacyHandler) RequestExport(c *gin.Context) {
+	h.request(c, h.privacyService.RequestExport)
+}
+
+func (h *PrivacyHandler) RequestErasure(c *gin.Context) {
+	h.request(c, h.privacyService.RequestErasure)
+}
+
+func (h *PrivacyHandler) request(c *gin.Context, create func(ctx context.Context, email string) error) {
+	var input domain.PrivacyRequestInput
+	if err := c.ShouldBind(&input); err != nil {
+		_ = c.Error(err).SetType(gin.ErrorTypeBind)
+		return
+	}
+
+	if err := create(c.Request.Context(), input.Email); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": privacyRequestAccepted})
+}
+
+func (h *PrivacyHandler) Export(c *gin.Context) {
+	export, err := h.privacyService.Export(c.Request.Context(), c.Param("token"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="weather-api-data.json"`)
+	c.JSON(http.StatusOK, export)
+}
+
+// erasurePage asks the owner to confirm the erasure. The emailed link must not
+// erase anything by itself, since mail scanners and prefetchers open links.
+var erasurePage = template.Must(template.New("erasure").Parse(`<!DOCTYPE html>
+<html lang="{{.Lang}}">
+<head>
+<meta charset="utf-8">
+<meta name="robots" content="noindex">
+<title>{{.Title}}</title>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>{{.Text}}</p>
+<form method="post"><button type="submit">{{.Button}}</button></form>
+</body>
+</html>
+`))
+
+// ConfirmErasure renders the confirmation page the emailed link opens; the
+// form on it posts back to Erase.
+func (h *PrivacyHandler) ConfirmErasure(c *gin.Context) {
+	req, err := h.privacyService.PendingErasure(c.Request.Context(), c.Param("token"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	lang := i18n.FromContext(c.Request.Context())
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Header("Cache-Control", "no-store")
+	c.Status(http.StatusOK)
+	_ = erasurePage.Execute(c.Writer, map[string]string{
+		"Lang":   string(lang),
+		"Title":  lang.T("Delete your Weather API data"),
+		"Text":   lang.Tf("This permanently deletes all data we hold for %s, including past subscriptions. It cannot be undone.", req.Email),
+		"Button": lang.T("Delete my data"),
+	})
+}
+
+func (h *PrivacyHandler) Erase(c *gin.Context) {
+	result, err := h.privacyService.Erase(c.Request.Context(), c.Param("token"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"net/http"
+	"weather/project/domain"
+	"weather/project/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SubscriptionAdminHandler struct {
+	adminService service.SubscriptionAdminService
+}
+
+func NewSubscriptionAdminHandler(as service.SubscriptionAdminService) *SubscriptionAdminHandler {
+	return &SubscriptionAdminHandler{adminService: as}
+}
+
+func (h *SubscriptionAdminHandler) List(c *gin.Context) {
+	var filter domain.SubscriptionFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		_ = c.Error(err).SetType(gin.ErrorTypeBind)
+		return
+	}
+
+	page, err := h.adminService.List(c.Request.Context(), filter)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+func (h *SubscriptionAdminHandler) Get(c *gin.Context) {
+	id, ok := uuidParam(c)
+	if !ok {
+		return
+	}
+
+	sub, err := h.adminService.Get(c.Request.Context(), id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, sub)
+}
+
+func (h *SubscriptionAdminHandler) Confirm(c *gin.Context) {
+	id, ok := uuidParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.adminService.Confirm(c.Request.Context(), id); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Subscription confirmed"})
+}
+
+func (h *SubscriptionAdminHandler) Unsubscribe(c *gin.Context) {
+	id, ok := uuidParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.adminService.Unsubscribe(c.Request.Context(), id); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Unsubscribed successfully"})
+}
+
+func (h *SubscriptionAdminHandler) Deliveries(c *gin.Context) {
+	id, ok := uuidParam(c)
+	if !ok {
+		return
+	}
+
+	deliveries, err := h.adminService.Deliveries(c.Request.Context(), id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": deliveries})
+}
+
+func (h *SubscriptionAdminHandler) Delete(c *gin.Context) {
+	id, ok := uuidParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.adminService.Delete(c.Request.Context(), id); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Subscription deleted permanently"})
+}
+
+func (h *SubscriptionAdminHandler) Bulk(c *gin.Context) {
+	var input domain.BulkSubscriptionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		_ = c.Error(err).SetType(gin.ErrorTypeBind)
+		return
+	}
+
+	result, err := h.adminService.Bulk(c.Request.Context(), input)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
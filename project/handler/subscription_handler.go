@@ -1,8 +1,6 @@
 package handler
 
 import (
-	"errors"
-	"log"
 	"net/http"
 	"weather/project/domain"
 	"weather/project/service"
@@ -22,20 +20,14 @@ func (h *SubscriptionHandler) Subscribe(c *gin.Context) {
 	var input domain.SubscriptionInput
 
 	if err := c.ShouldBind(&input); err != nil {
-		log.Printf("Subscribe handler: failed to bind input: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		_ = c.Error(err).SetType(gin.ErrorTypeBind)
 		return
 	}
+	resolveAutoIP(c, &input.LocationInput)
 
-	_, err := h.subscriptionService.Subscribe(input)
+	_, err := h.subscriptionService.Subscribe(c.Request.Context(), input)
 	if err != nil {
-		log.Printf("Subscribe handler: error from subscriptionService for email %s: %v", input.Email, err)
-		if errors.Is(err, domain.ErrEmailAlreadySubscribed) {
-			c.JSON(http.StatusConflict, gin.H{"error": domain.ErrEmailAlreadySubscribed.Error()})
-			return
-		}
-
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process subscription request"})
+		_ = c.Error(err)
 		return
 	}
 
@@ -45,19 +37,13 @@ func (h *SubscriptionHandler) Subscribe(c *gin.Context) {
 func (h *SubscriptionHandler) ConfirmSubscription(c *gin.Context) {
 	token := c.Param("token")
 	if token == "" {
-		log.Println("ConfirmSubscription handler: token parameter is missing")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Confirmation token is required"})
+		_ = c.Error(domain.NewFieldError("token", "is required"))
 		return
 	}
 
-	err := h.subscriptionService.ConfirmSubscription(token)
+	err := h.subscriptionService.ConfirmSubscription(c.Request.Context(), token)
 	if err != nil {
-		log.Printf("ConfirmSubscription handler: error from subscriptionService for token %s: %v", token, err)
-		if errors.Is(err, domain.ErrTokenInvalidOrExpired) {
-			c.JSON(http.StatusNotFound, gin.H{"error": domain.ErrTokenInvalidOrExpired.Error()}) // 404 as per Swagger for not found
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to confirm subscription"})
+		_ = c.Error(err)
 		return
 	}
 
@@ -67,19 +53,13 @@ func (h *SubscriptionHandler) ConfirmSubscription(c *gin.Context) {
 func (h *SubscriptionHandler) Unsubscribe(c *gin.Context) {
 	token := c.Param("token")
 	if token == "" {
-		log.Println("Unsubscribe handler: token parameter is missing")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsubscribe token is required"})
+		_ = c.Error(domain.NewFieldError("token", "is required"))
 		return
 	}
 
-	err := h.subscriptionService.UnsubscribeByToken(token)
+	err := h.subscriptionService.UnsubscribeByToken(c.Request.Context(), token)
 	if err != nil {
-		log.Printf("Unsubscribe handler: error from subscriptionService for token %s: %v", token, err)
-		if errors.Is(err, domain.ErrTokenInvalidOrExpired) {
-			c.JSON(http.StatusNotFound, gin.H{"error": domain.ErrTokenInvalidOrExpired.Error()})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unsubscribe"})
+		_ = c.Error(err)
 		return
 	}
 
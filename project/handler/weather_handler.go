@@ -1,10 +1,11 @@
 package handler
 
 import (
-	"errors"
-	"log"
 	"net/http"
+	"strings"
+	"time"
 	"weather/project/domain"
+	"weather/project/middleware"
 	"weather/project/service"
 
 	"github.com/gin-gonic/gin"
@@ -18,29 +19,96 @@ func NewWeatherHandler(ws service.WeatherService) *WeatherHandler {
 	return &WeatherHandler{weatherService: ws}
 }
 
+// GetWeather accepts exactly one of city, lat/lon, zip, iata or ip; ip=auto
+// looks up the caller's own address. include=aqi adds air quality data.
 func (h *WeatherHandler) GetWeather(c *gin.Context) {
-	city := c.Query("city")
-	if city == "" {
-		log.Println("GetWeather handler: city parameter is missing")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "City parameter is required"})
+	var input domain.WeatherInput
+	if err := c.ShouldBindQuery(&input); err != nil {
+		_ = c.Error(err).SetType(gin.ErrorTypeBind)
 		return
 	}
+	resolveAutoIP(c, &input.LocationInput)
 
-	weather, err := h.weatherService.GetWeatherForCity(city)
+	location, err := input.Location()
 	if err != nil {
-		log.Printf("GetWeather handler: error from weatherService for city %s: %v", city, err)
-		if errors.Is(err, domain.ErrCityNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"error": domain.ErrCityNotFound.Error()})
-			return
-		}
-		if errors.Is(err, domain.ErrFailedToFetchWeather) {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not retrieve weather information at this time"})
-			return
-		}
+		_ = c.Error(err)
+		return
+	}
+	opts, err := input.Options()
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
 
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "An unexpected error occurred"})
+	weather, err := h.weatherService.GetWeather(c.Request.Context(), location, opts)
+	if err != nil {
+		_ = c.Error(err)
 		return
 	}
 
 	c.JSON(http.StatusOK, weather)
 }
+
+func (h *WeatherHandler) GetWeatherBatch(c *gin.Context) {
+	var input domain.WeatherBatchInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		_ = c.Error(err).SetType(gin.ErrorTypeBind)
+		return
+	}
+
+	lookups := h.weatherService.GetWeatherForCities(c.Request.Context(), input.Cities)
+
+	response := domain.WeatherBatchResponse{Results: make([]domain.WeatherBatchItem, len(lookups))}
+	for i, lookup := range lookups {
+		item := domain.WeatherBatchItem{City: lookup.City, Weather: lookup.Weather}
+		if lookup.Err != nil {
+			problem := middleware.NewProblem(c, &gin.Error{Err: lookup.Err})
+			item.Error = &problem
+		}
+		response.Results[i] = item
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetWeatherHistory takes the same location parameters as GetWeather plus
+// the local date to report on.
+func (h *WeatherHandler) GetWeatherHistory(c *gin.Context) {
+	var input domain.WeatherHistoryInput
+	if err := c.ShouldBindQuery(&input); err != nil {
+		_ = c.Error(err).SetType(gin.ErrorTypeBind)
+		return
+	}
+	resolveAutoIP(c, &input.LocationInput)
+
+	location, err := input.Location()
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	// Allow one day of slack for places already living in tomorrow.
+	date, _ := time.Parse(time.DateOnly, input.Date)
+	if date.After(time.Now().UTC().AddDate(0, 0, 1)) {
+		_ = c.Error(domain.NewFieldError("date", "must not be in the future"))
+		return
+	}
+
+	source := domain.HistorySource(input.Source)
+	if source == "" {
+		source = domain.HistorySourceAuto
+	}
+
+	history, err := h.weatherService.GetHistory(c.Request.Context(), location, input.Date, source)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
+
+func resolveAutoIP(c *gin.Context, input *domain.LocationInput) {
+	if strings.EqualFold(strings.TrimSpace(input.IP), domain.AutoIP) {
+		input.IP = c.ClientIP()
+	}
+}
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"io"
+	"time"
+	"weather/project/domain"
+	"weather/project/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// streamKeepAlive is how often an idle stream sends a comment line, so
+// proxies do not close it while the weather stays the same.
+const streamKeepAlive = 25 * time.Second
+
+type WeatherStreamHandler struct {
+	weatherStream service.WeatherStream
+}
+
+func NewWeatherStreamHandler(ws service.WeatherStream) *WeatherStreamHandler {
+	return &WeatherStreamHandler{weatherStream: ws}
+}
+
+// Stream sends the current weather of a city as a "weather" event, then
+// another whenever it changes, until the client disconnects.
+func (h *WeatherStreamHandler) Stream(c *gin.Context) {
+	var input domain.WeatherStreamInput
+	if err := c.ShouldBindQuery(&input); err != nil {
+		_ = c.Error(err).SetType(gin.ErrorTypeBind)
+		return
+	}
+	location, err := input.Location()
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	updates, err := h.weatherStream.Subscribe(c.Request.Context(), location)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case weather, ok := <-updates:
+			if !ok {
+				return false
+			}
+			c.SSEvent("weather", weather)
+		case <-keepAlive.C:
+			_, _ = io.WriteString(w, ": keep-alive\n\n")
+		}
+		return true
+	})
+}
@@ -0,0 +1,175 @@
+package i18n
+
+// catalog maps English messages to their translations. English needs no
+// entries of its own.
+var catalog = map[Lang]map[string]string{
+	Ukrainian: {
+		// Problem titles.
+		"Invalid request":              "Некоректний запит",
+		"City not found":               "Місто не знайдено",
+		"Weather provider unavailable": "Постачальник погоди недоступний",
+		"Email already subscribed":     "Email уже підписано",
+		"Subscription not found":       "Підписку не знайдено",
+		"Token invalid or expired":     "Токен недійсний або прострочений",
+		"Invalid API key":              "Недійсний API-ключ",
+		"API key not found":            "API-ключ не знайдено",
+		"API key revoked":              "API-ключ відкликано",
+		"Daily quota exceeded":         "Добову квоту вичерпано",
+		"Weather history unavailable":  "Історія погоди недоступна",
+		"Privacy request link invalid": "Посилання на запит щодо даних недійсне",
+		"Too many requests":            "Забагато запитів",
+		"Subscription limit reached":   "Досягнуто ліміту підписок",
+		"Email delivery failed":        "Не вдалося надіслати лист",
+		"Request too large":            "Завеликий запит",
+		"Unauthorized":                 "Не авторизовано",
+		"Not found":                    "Не знайдено",
+		"Internal server error":        "Внутрішня помилка сервера",
+		"Webhook verification failed":  "Не вдалося перевірити вебхук",
+
+		// Problem details.
+		"city not found by external weather API":                    "постачальник погоди не знайшов це місто",
+		"email already subscribed and confirmed":                    "цю адресу вже підписано й підтверджено",
+		"subscription not found":                                    "підписку не знайдено",
+		"token is invalid, expired, or not found":                   "токен недійсний, прострочений або не існує",
+		"failed to fetch weather data from external API":            "не вдалося отримати дані від постачальника погоди",
+		"API key is missing, invalid or revoked":                    "API-ключ відсутній, недійсний або відкликаний",
+		"daily quota for API key exceeded":                          "добову квоту API-ключа вичерпано",
+		"API key has been revoked":                                  "API-ключ відкликано",
+		"too many requests, please retry later":                     "забагато запитів, спробуйте пізніше",
+		"request body is too large":                                 "тіло запиту завелике",
+		"chat already follows the maximum number of cities":         "чат уже стежить за максимальною кількістю міст",
+		"failed to send email":                                      "не вдалося надіслати лист",
+		"admin authorization required":                              "потрібна авторизація адміністратора",
+		"resource not found":                                        "ресурс не знайдено",
+		"privacy request link is invalid, expired, or already used": "посилання недійсне, прострочене або вже використане",
+		"no weather history available for this location and date":   "для цього місця й дати немає історії погоди",
+		"webhook endpoint did not echo the verification challenge":  "вебхук не повернув перевірочний challenge",
+		"incoming webhook did not accept the test message":          "вебхук не прийняв тестове повідомлення",
+		"One or more fields are invalid.":                           "Одне або кілька полів некоректні.",
+		"Request body is empty.":                                    "Тіло запиту порожнє.",
+		"Request body is not valid JSON.":                           "Тіло запиту не є коректним JSON.",
+		"Field %q has the wrong type.":                              "Поле %q має неправильний тип.",
+		"Request could not be parsed.":                              "Не вдалося розібрати запит.",
+
+		// Field messages.
+		"is required":                                                        "обов'язкове поле",
+		"must be a valid email address":                                      "має бути коректною email-адресою",
+		"must be one of: %s":                                                 "має бути одним із: %s",
+		"must satisfy %s=%s":                                                 "має відповідати правилу %s=%s",
+		"failed on the '%s' rule":                                            "не відповідає правилу '%s'",
+		"must not be in the future":                                          "не може бути в майбутньому",
+		"is not a supported language":                                        "ця мова не підтримується",
+		"must be a valid UUID":                                               "має бути коректним UUID",
+		"must be between 2 and 100 characters":                               "має містити від 2 до 100 символів",
+		"is required, or one of city, lat/lon, zip, iata or ip":              "обов'язкове поле, або вкажіть одне з: city, lat/lon, zip, iata чи ip",
+		"only one of city, lat/lon, zip, iata or ip may be given":            "можна вказати лише одне з: city, lat/lon, zip, iata чи ip",
+		"must be a postal code of 2 to 10 letters, digits, spaces or dashes": "має бути поштовим індексом із 2–10 літер, цифр, пробілів або дефісів",
+		"must be a 3-letter airport code":                                    "має бути трилітерним кодом аеропорту",
+		"must be an IPv4 or IPv6 address":                                    "має бути адресою IPv4 або IPv6",
+		"must be a public address":                                           "має бути публічною адресою",
+		"is required together with lon":                                      "обов'язкове разом з lon",
+		"is required together with lat":                                      "обов'язкове разом з lat",
+		"must be between -90 and 90":                                         "має бути від -90 до 90",
+		"must be between -180 and 180":                                       "має бути від -180 до 180",
+		"must be webhook, slack or discord when webhook_url or webhook_secret is given": "має бути webhook, slack або discord, якщо вказано webhook_url або webhook_secret",
+		"is required for the webhook channel":                                           "обов'язкове для каналу webhook",
+		"must be an absolute http or https URL without credentials":                     "має бути абсолютною http- або https-адресою без облікових даних",
+		"must be at most 2048 characters":                                               "має містити не більше 2048 символів",
+		"must be between 16 and 256 characters":                                         "має містити від 16 до 256 символів",
+		"cannot be combined with channel, webhook_url or webhook_secret":                "не можна поєднувати з channel, webhook_url чи webhook_secret",
+		"must list at most 5 channels":                                                  "може містити не більше 5 каналів",
+		"must not repeat another channel":                                               "не може повторювати інший канал",
+		"must be empty for the email channel":                                           "має бути порожнім для каналу email",
+		"must be one of: email, webhook, slack, discord":                                "має бути одним із: email, webhook, slack, discord",
+		"must be a Slack incoming webhook URL, https://hooks.slack.com/services/...":    "має бути адресою вхідного вебхука Slack, https://hooks.slack.com/services/...",
+		"must be a Discord webhook URL, https://discord.com/api/webhooks/...":           "має бути адресою вебхука Discord, https://discord.com/api/webhooks/...",
+		"must be empty for the slack and discord channels":                              "має бути порожнім для каналів slack і discord",
+		"is not available on this server":                                               "недоступний на цьому сервері",
+		"must be a comma-separated list of: aqi, pollen, forecast":                      "має бути списком через кому з: aqi, pollen, forecast",
+
+		// Air quality categories.
+		"Good":                           "Добра",
+		"Moderate":                       "Помірна",
+		"Unhealthy for sensitive groups": "Шкідлива для чутливих груп",
+		"Unhealthy":                      "Шкідлива",
+		"Very unhealthy":                 "Дуже шкідлива",
+		"Hazardous":                      "Небезпечна",
+
+		// Pollen sources.
+		"Hazel":   "Ліщина",
+		"Alder":   "Вільха",
+		"Birch":   "Береза",
+		"Oak":     "Дуб",
+		"Grass":   "Злаки",
+		"Mugwort": "Полин",
+		"Ragweed": "Амброзія",
+
+		// Weather conditions.
+		"Clear":         "Ясно",
+		"Partly cloudy": "Мінлива хмарність",
+		"Cloudy":        "Хмарно",
+		"Fog":           "Туман",
+		"Drizzle":       "Мряка",
+		"Rain":          "Дощ",
+		"Freezing rain": "Крижаний дощ",
+		"Sleet":         "Мокрий сніг",
+		"Snow":          "Сніг",
+		"Ice pellets":   "Крижана крупа",
+		"Thunderstorm":  "Гроза",
+		"Unknown":       "Невідомо",
+
+		// Emails.
+		"Confirm your Weather API Subscription": "Підтвердіть підписку на Weather API",
+		"Hello %s,\n\nPlease confirm your subscription for weather updates in %s by clicking the link below:\n%s\n\nIf you did not request this, please ignore this email.\n\nThanks,\nThe Weather API Team": "Вітаємо, %s!\n\nБудь ласка, підтвердіть підписку на оновлення погоди для %s, перейшовши за посиланням:\n%s\n\nЯкщо ви не робили цього запиту, просто проігноруйте цей лист.\n\nДякуємо,\nКоманда Weather API",
+		"Weather Update for %s: %s": "Оновлення погоди, %s: %s",
+		"Severe weather in %s: %s":  "Небезпечна погода, %s: %s",
+		"Hello %s,\n\nHere's your weather update for %s:\n%s\nTo stop receiving these updates, click here: %s\n\nThanks,\nThe Weather API Team": "Вітаємо, %s!\n\nОновлення погоди для %s:\n%s\nЩоб більше не отримувати ці листи, перейдіть за посиланням: %s\n\nДякуємо,\nКоманда Weather API",
+		"Temperature: %.1f°C\nHumidity: %.0f%%\nDescription: %s\n":                                                                              "Температура: %.1f°C\nВологість: %.0f%%\nОпис: %s\n",
+		"Today: %.0f to %.0f°C, %s, %d%% chance of rain\n":                                                                                      "Сьогодні: від %.0f до %.0f°C, %s, імовірність дощу %d%%\n",
+		"\nAir quality: %s (US EPA index %d)\nPM2.5: %.1f µg/m³\nPM10: %.1f µg/m³\nO3: %.1f µg/m³\nNO2: %.1f µg/m³\n":                           "\nЯкість повітря: %s (індекс US EPA %d)\nPM2.5: %.1f мкг/м³\nPM10: %.1f мкг/м³\nO3: %.1f мкг/м³\nNO2: %.1f мкг/м³\n",
+		"\nPollen (grains/m³):\n":                   "\nПилок (зерен/м³):\n",
+		"Download your Weather API data":            "Завантажте свої дані з Weather API",
+		"Confirm deletion of your Weather API data": "Підтвердіть видалення своїх даних з Weather API",
+		"To download a copy of all data we hold for this address, open:\n%s/api/v1/privacy/export/%s":                                        "Щоб завантажити копію всіх даних, які ми зберігаємо для цієї адреси, відкрийте:\n%s/api/v1/privacy/export/%s",
+		"To permanently delete all data we hold for this address, open:\n%s/api/v1/privacy/erasure/%s\nThis cannot be undone.":               "Щоб остаточно видалити всі дані, які ми зберігаємо для цієї адреси, відкрийте:\n%s/api/v1/privacy/erasure/%s\nЦю дію неможливо скасувати.",
+		"Hello %s,\n\n%s\n\nThe link expires at %s. If you did not request this, please ignore this email.\n\nThanks,\nThe Weather API Team": "Вітаємо, %s!\n\n%s\n\nПосилання дійсне до %s. Якщо ви не робили цього запиту, просто проігноруйте цей лист.\n\nДякуємо,\nКоманда Weather API",
+
+		// Erasure confirmation page.
+		"Delete your Weather API data": "Видаліть свої дані з Weather API",
+		"This permanently deletes all data we hold for %s, including past subscriptions. It cannot be undone.": "Буде остаточно видалено всі дані, які ми зберігаємо для %s, зокрема минулі підписки. Цю дію неможливо скасувати.",
+		"Delete my data": "Видалити мої дані",
+
+		// Slack and Discord.
+		"Weather API will post weather updates to this channel.": "Weather API публікуватиме оновлення погоди в цьому каналі.",
+		"Temperature":          "Температура",
+		"Humidity":             "Вологість",
+		"Conditions":           "Умови",
+		"Today":                "Сьогодні",
+		"%.0f to %.0f°C, %s":   "від %.0f до %.0f°C, %s",
+		"Chance of rain":       "Імовірність дощу",
+		"Air quality":          "Якість повітря",
+		"%s (US EPA index %d)": "%s (індекс US EPA %d)",
+		"Pollen":               "Пилок",
+		"%s, %.0f grains/m³":   "%s, %.0f зерен/м³",
+		"Unsubscribe":          "Відписатися",
+
+		// Telegram bot.
+		"Commands:\n/weather <city> - current weather\n/subscribe <city> <hourly|daily> - get regular updates\n/unsubscribe [city] - stop updates for a city, or for all cities\n/list - your subscriptions": "Команди:\n/weather <місто> - поточна погода\n/subscribe <місто> <hourly|daily> - регулярні оновлення\n/unsubscribe [місто] - припинити оновлення для міста або для всіх міст\n/list - ваші підписки",
+		"Unknown command.":                        "Невідома команда.",
+		"Usage: /weather <city>":                  "Використання: /weather <місто>",
+		"Usage: /subscribe <city> <hourly|daily>": "Використання: /subscribe <місто> <hourly|daily>",
+		"Weather in %s:\n":                        "Погода, %s:\n",
+		"You will get %s weather updates for %s.": "Ви отримуватимете %s оновлення погоди для %s.",
+		"hourly":                        "щогодинні",
+		"daily":                         "щоденні",
+		"You have no subscriptions.":    "У вас немає підписок.",
+		"You are not subscribed to %s.": "Ви не підписані на %s.",
+		"Unsubscribed from all cities.": "Ви відписалися від усіх міст.",
+		"Unsubscribed from %s.":         "Ви відписалися від %s.",
+		"Your subscriptions:":           "Ваші підписки:",
+		"City not found: %s":            "Місто не знайдено: %s",
+		"Something went wrong. Please try again later.":                          "Щось пішло не так. Спробуйте пізніше.",
+		"Send /unsubscribe to stop receiving these updates.":                     "Надішліть /unsubscribe, щоб більше не отримувати ці оновлення.",
+		"You can follow at most %d cities. Remove one with /unsubscribe <city>.": "Можна стежити щонайбільше за %d містами. Видаліть одне командою /unsubscribe <місто>.",
+	},
+}
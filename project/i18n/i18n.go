@@ -0,0 +1,94 @@
+// Package i18n picks the language of a request and translates the API's own
+// messages. Messages are keyed by their English text, so untranslated ones
+// fall back to English.
+package i18n
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type Lang string
+
+const (
+	English   Lang = "en"
+	Ukrainian Lang = "uk"
+
+	Default = English
+)
+
+// Supported lists the languages with a catalog, in order of preference.
+var Supported = []Lang{English, Ukrainian}
+
+// Parse accepts a language tag such as "uk", "uk-UA" or "EN_gb".
+func Parse(tag string) (Lang, bool) {
+	primary, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+	primary, _, _ = strings.Cut(primary, "_")
+	for _, lang := range Supported {
+		if Lang(primary) == lang {
+			return lang, true
+		}
+	}
+	return "", false
+}
+
+// Negotiate picks the supported language the client weighs highest in an
+// Accept-Language header, or Default.
+func Negotiate(acceptLanguage string) Lang {
+	type candidate struct {
+		lang    Lang
+		quality float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		lang, ok := Parse(tag)
+		if !ok {
+			continue
+		}
+		quality := 1.0
+		if q, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			if parsed, err := strconv.ParseFloat(q, 64); err == nil {
+				quality = parsed
+			}
+		}
+		if quality > 0 {
+			candidates = append(candidates, candidate{lang, quality})
+		}
+	}
+	if len(candidates) == 0 {
+		return Default
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].quality > candidates[j].quality })
+	return candidates[0].lang
+}
+
+type contextKey struct{}
+
+func WithLang(ctx context.Context, lang Lang) context.Context {
+	return context.WithValue(ctx, contextKey{}, lang)
+}
+
+// FromContext returns the language stored by WithLang, or Default.
+func FromContext(ctx context.Context) Lang {
+	if lang, ok := ctx.Value(contextKey{}).(Lang); ok {
+		return lang
+	}
+	return Default
+}
+
+// T translates an English message.
+func (l Lang) T(message string) string {
+	if translated, ok := catalog[l][message]; ok {
+		return translated
+	}
+	return message
+}
+
+// Tf translates an English format string and formats it with args.
+func (l Lang) Tf(format string, args ...any) string {
+	return fmt.Sprintf(l.T(format), args...)
+}
@@ -0,0 +1,77 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+type ctxKey struct{}
+
+const RequestIDKey = "request_id"
+
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, requestID)
+}
+
+func RequestIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	requestID, _ := ctx.Value(ctxKey{}).(string)
+	return requestID
+}
+
+// New builds the application logger. format is either "json" or "text";
+// level is one of "debug", "info", "warn" or "error". Every record is passed
+// through a Redactor, and the given secrets never reach w verbatim.
+func New(w io.Writer, level, format string, secrets ...string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("logging.New: invalid log level %q: %w", level, err)
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl}
+	var handler slog.Handler
+	switch strings.ToLower(format) {
+	case "json":
+		handler = slog.NewJSONHandler(w, opts)
+	case "text":
+		handler = slog.NewTextHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("logging.New: unsupported log format %q", format)
+	}
+
+	// Correlation IDs are attached after redaction: trace IDs are long hex
+	// strings that would otherwise be mistaken for tokens.
+	handler = &contextHandler{Handler: handler}
+	return slog.New(&redactHandler{Handler: handler, redactor: NewRedactor(secrets...)}), nil
+}
+
+// contextHandler attaches the request ID and trace identifiers carried by
+// the context to every record, so that callers only have to use the *Context logging methods.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if requestID := RequestIDFromContext(ctx); requestID != "" {
+		r.AddAttrs(slog.String(RequestIDKey, requestID))
+	}
+	if spanCtx := trace.SpanContextFromContext(ctx); spanCtx.IsValid() {
+		r.AddAttrs(slog.String("trace_id", spanCtx.TraceID().String()), slog.String("span_id", spanCtx.SpanID().String()))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,158 @@
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// minSecretLength guards against blanking out every occurrence of a trivially
+// short value, e.g. an empty or placeholder password.
+const minSecretLength = 4
+
+var (
+	sensitiveQueryParam = regexp.MustCompile(`(?i)\b(key|api_key|apikey|token|secret|password)=[^&\s"']+`)
+	hexToken            = regexp.MustCompile(`\b[0-9a-fA-F]{32,}\b`)
+	emailAddress        = regexp.MustCompile(`\b([A-Za-z0-9._%+-]+)@([A-Za-z0-9.-]+\.[A-Za-z]{2,})\b`)
+	// Slack and Discord incoming-webhook URLs carry their credential in the
+	// path, so everything after the fixed prefix is masked.
+	incomingWebhookPath = regexp.MustCompile(`(?i)\b(hooks\.slack\.com/services/|(?:discord|discordapp)\.com/api/webhooks/)[^\s"'?#]+`)
+)
+
+var sensitiveKeys = map[string]bool{
+	"token":         true,
+	"password":      true,
+	"secret":        true,
+	"api_key":       true,
+	"apikey":        true,
+	"key":           true,
+	"authorization": true,
+}
+
+// Redactor masks configured secrets, API keys, tokens and email local-parts
+// in arbitrary strings and slog attributes.
+type Redactor struct {
+	secrets []string
+}
+
+func NewRedactor(secrets ...string) *Redactor {
+	var filtered []string
+	for _, secret := range secrets {
+		if len(secret) >= minSecretLength {
+			filtered = append(filtered, secret)
+		}
+	}
+	// Longest first, so a secret containing another one is fully replaced.
+	sort.Slice(filtered, func(i, j int) bool { return len(filtered[i]) > len(filtered[j]) })
+	return &Redactor{secrets: filtered}
+}
+
+func (r *Redactor) String(s string) string {
+	s = r.configuredSecrets(s)
+	s = incomingWebhookPath.ReplaceAllString(s, "${1}"+redacted)
+	s = sensitiveQueryParam.ReplaceAllString(s, "${1}="+redacted)
+	s = hexToken.ReplaceAllString(s, redacted)
+	s = emailAddress.ReplaceAllStringFunc(s, MaskEmail)
+	return s
+}
+
+func (r *Redactor) configuredSecrets(s string) string {
+	for _, secret := range r.secrets {
+		s = strings.ReplaceAll(s, secret, redacted)
+	}
+	return s
+}
+
+// Unredacted wraps a value that is logged on purpose with its tokens and
+// addresses, such as the simulated mail body, which is the only way to open
+// the links it carries. Configured secrets are still removed from it.
+type Unredacted string
+
+// MaskEmail keeps the first character of the local-part and the domain, so
+// that support can still correlate log lines without seeing the address.
+func MaskEmail(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at <= 0 {
+		return email
+	}
+	return email[:1] + "***" + email[at:]
+}
+
+func (r *Redactor) Attr(a slog.Attr) slog.Attr {
+	if value, ok := a.Value.Any().(Unredacted); ok {
+		return slog.String(a.Key, r.configuredSecrets(string(value)))
+	}
+	a.Value = a.Value.Resolve()
+	if sensitiveKeys[strings.ToLower(a.Key)] {
+		return slog.String(a.Key, redacted)
+	}
+
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, r.String(a.Value.String()))
+	case slog.KindGroup:
+		attrs := a.Value.Group()
+		redactedAttrs := make([]slog.Attr, len(attrs))
+		for i, attr := range attrs {
+			redactedAttrs[i] = r.Attr(attr)
+		}
+		return slog.Attr{Key: a.Key, Value: slog.GroupValue(redactedAttrs...)}
+	case slog.KindAny:
+		return slog.Attr{Key: a.Key, Value: r.anyValue(a.Value.Any())}
+	default:
+		return a
+	}
+}
+
+func (r *Redactor) anyValue(v any) slog.Value {
+	if err, ok := v.(error); ok {
+		return slog.StringValue(r.String(err.Error()))
+	}
+
+	// Arbitrary values are round-tripped through JSON so that struct fields
+	// holding emails or tokens are masked while the structure is preserved.
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return slog.AnyValue(v)
+	}
+	masked := r.String(string(raw))
+	if masked == string(raw) {
+		return slog.AnyValue(v)
+	}
+	var decoded any
+	if err := json.Unmarshal([]byte(masked), &decoded); err != nil {
+		return slog.StringValue(masked)
+	}
+	return slog.AnyValue(decoded)
+}
+
+type redactHandler struct {
+	slog.Handler
+	redactor *Redactor
+}
+
+func (h *redactHandler) Handle(ctx context.Context, r slog.Record) error {
+	masked := slog.NewRecord(r.Time, r.Level, h.redactor.String(r.Message), r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		masked.AddAttrs(h.redactor.Attr(a))
+		return true
+	})
+	return h.Handler.Handle(ctx, masked)
+}
+
+func (h *redactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	masked := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		masked[i] = h.redactor.Attr(a)
+	}
+	return &redactHandler{Handler: h.Handler.WithAttrs(masked), redactor: h.redactor}
+}
+
+func (h *redactHandler) WithGroup(name string) slog.Handler {
+	return &redactHandler{Handler: h.Handler.WithGroup(name), redactor: h.redactor}
+}
@@ -0,0 +1,131 @@
+package logging_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+	"weather/project/config"
+	"weather/project/logging"
+)
+
+var testConfig = config.Config{
+	DBPassword:       "db-Pa55word-x9",
+	WeatherAPIKey:    "wk9f2c81d0aa4b7e",
+	AdminToken:       "admin-T0ken-Zq7",
+	TelegramBotToken: "123456789:AAHdqTcvCH1vGWJxfSeofSAs0K5PALDsaw",
+}
+
+func TestLoggerNeverWritesSecrets(t *testing.T) {
+	secrets := testConfig.Secrets()
+
+	for _, format := range []string{"json", "text"} {
+		for _, secret := range secrets {
+			t.Run(format+"/"+secret[:4], func(t *testing.T) {
+				var buf bytes.Buffer
+				logger, err := logging.New(&buf, "debug", format, secrets...)
+				if err != nil {
+					t.Fatalf("logging.New: %v", err)
+				}
+
+				logger.Info("message carries " + secret)
+				logger.Info("attrs", slog.String("value", secret), slog.String("password", secret))
+				logger.Info("nested", slog.Group("outer", slog.Group("inner", slog.String("value", "x"+secret+"x"))))
+				logger.WithGroup("db").With(slog.String("dsn", "weather:"+secret+"@tcp(localhost:3306)/weather")).Info("with attrs")
+				logger.Info("query", slog.String("url", "https://api.weatherapi.com/v1/current.json?key="+secret+"&q=Kyiv"))
+				logger.Info("token query", slog.String("url", "/api/v1/confirm?token="+secret))
+				logger.Error("failed", slog.Any("error", fmt.Errorf("request failed: %w", errors.New("Get \"https://x/y?key="+secret+"\": timeout"))))
+				logger.Info("struct", slog.Any("config", struct{ Password string }{secret}))
+
+				if out := buf.String(); strings.Contains(out, secret) {
+					t.Errorf("log output contains secret %q:\n%s", secret, out)
+				}
+			})
+		}
+	}
+}
+
+func TestLoggerMasksEmailLocalParts(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := logging.New(&buf, "info", "json", testConfig.Secrets()...)
+	if err != nil {
+		t.Fatalf("logging.New: %v", err)
+	}
+
+	logger.Info("subscribed john.doe@example.com",
+		slog.String("email", "jane.roe@example.org"),
+		slog.Group("subscription", slog.String("owner", "max.mustermann@example.de")),
+		slog.Any("error", errors.New("send to kim.lee@example.net failed")),
+	)
+
+	out := buf.String()
+	for _, local := range []string{"john.doe@", "jane.roe@", "max.mustermann@", "kim.lee@"} {
+		if strings.Contains(out, local) {
+			t.Errorf("log output contains email local-part %q:\n%s", local, out)
+		}
+	}
+	for _, masked := range []string{"j***@example.com", "j***@example.org", "m***@example.de", "k***@example.net"} {
+		if !strings.Contains(out, masked) {
+			t.Errorf("log output lacks masked address %q:\n%s", masked, out)
+		}
+	}
+}
+
+func TestLoggerMasksIncomingWebhookURLs(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := logging.New(&buf, "debug", "json", testConfig.Secrets()...)
+	if err != nil {
+		t.Fatalf("logging.New: %v", err)
+	}
+
+	logger.Debug("posting", slog.String("target", "https://hooks.slack.com/services/T024BE7LD/B08N1F3S1/xoxbSecretPart1"))
+	logger.Error("delivery failed", slog.Any("error", errors.New(`Post "https://discord.com/api/webhooks/1190231234567890/Zk9_discord-Secret-Part2": timeout`)))
+	logger.Warn("rejected https://discordapp.com/api/webhooks/42/legacySecretPart3?wait=true")
+
+	out := buf.String()
+	for _, secret := range []string{"xoxbSecretPart1", "T024BE7LD", "Zk9_discord-Secret-Part2", "legacySecretPart3"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("log output contains webhook credential %q:\n%s", secret, out)
+		}
+	}
+	for _, kept := range []string{"hooks.slack.com/services/[REDACTED]", "discord.com/api/webhooks/[REDACTED]", "discordapp.com/api/webhooks/[REDACTED]?wait=true"} {
+		if !strings.Contains(out, kept) {
+			t.Errorf("log output lacks %q:\n%s", kept, out)
+		}
+	}
+}
+
+func TestLoggerKeepsUnredactedValues(t *testing.T) {
+	const link = "http://localhost:8080/api/v1/confirm/9b1f0c6a4d2e8f7a3c5b1e0d9f8a7b6c5d4e3f2a1b0c9d8e"
+	for _, format := range []string{"json", "text"} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger, err := logging.New(&buf, "info", format, testConfig.Secrets()...)
+			if err != nil {
+				t.Fatalf("logging.New: %v", err)
+			}
+
+			body := "Hello john.doe@example.com, confirm at " + link + " (" + testConfig.AdminToken + ")"
+			logger.With(slog.Any("preset", logging.Unredacted(link))).Info("SIMULATING SENDING EMAIL",
+				slog.String("to", "john.doe@example.com"),
+				slog.Any("body", logging.Unredacted(body)),
+			)
+
+			out := buf.String()
+			if strings.Count(out, link) != 2 {
+				t.Errorf("log output lost the confirm link:\n%s", out)
+			}
+			if !strings.Contains(out, "Hello john.doe@example.com") {
+				t.Errorf("unredacted body was masked:\n%s", out)
+			}
+			if strings.Contains(out, testConfig.AdminToken) {
+				t.Errorf("unredacted body kept a configured secret:\n%s", out)
+			}
+			if strings.Contains(out, `"to":"john.doe@`) || strings.Contains(out, "to=john.doe@") {
+				t.Errorf("recipient was not masked:\n%s", out)
+			}
+		})
+	}
+}
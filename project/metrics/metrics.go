@@ -0,0 +1,166 @@
+package metrics
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+	"weather/project/domain"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "weather"
+
+const (
+	OutcomeSuccess      = "success"
+	OutcomeCityNotFound = "city_not_found"
+	OutcomeError        = "error"
+)
+
+const (
+	SubscriptionCreated      = "created"
+	SubscriptionConfirmed    = "confirmed"
+	SubscriptionUnsubscribed = "unsubscribed"
+)
+
+// Registry holds every collector of the application. A dedicated registry is
+// used instead of the global default so nothing is exported by accident.
+var Registry = prometheus.NewRegistry()
+
+var factory = promauto.With(Registry)
+
+var (
+	HTTPRequestsTotal = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Number of HTTP requests handled, by route, method and status code.",
+	}, []string{"route", "method", "status"})
+
+	HTTPRequestDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of HTTP requests, by route, method and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	UpstreamRequestDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "upstream",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of calls to the external weather provider, by operation and outcome.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation", "outcome"})
+
+	UpstreamErrorsTotal = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "upstream",
+		Name:      "errors_total",
+		Help:      "Number of failed calls to the external weather provider, by operation and outcome.",
+	}, []string{"operation", "outcome"})
+
+	SubscriptionEventsTotal = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "subscriptions",
+		Name:      "events_total",
+		Help:      "Subscription lifecycle events: created, confirmed, unsubscribed.",
+	}, []string{"event"})
+
+	RateLimitedTotal = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "rate_limited_total",
+		Help:      "Number of requests rejected by the rate limiter, by scope (ip or email).",
+	}, []string{"scope"})
+
+	EmailsTotal = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "emails",
+		Name:      "total",
+		Help:      "Number of emails handed to the mail transport, by kind and result.",
+	}, []string{"kind", "result"})
+
+	WebhookDeliveriesTotal = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "webhooks",
+		Name:      "deliveries_total",
+		Help:      "Number of webhook delivery attempts, by event type and result.",
+	}, []string{"event", "result"})
+
+	WeatherUpdatesTotal = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "subscriptions",
+		Name:      "weather_updates_total",
+		Help:      "Number of scheduled weather updates, by frequency and outcome.",
+	}, []string{"frequency", "outcome"})
+
+	WeatherStreamListeners = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "weather_stream",
+		Name:      "listeners",
+		Help:      "Number of clients connected to live weather streams.",
+	})
+
+	WeatherStreamPollers = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "weather_stream",
+		Name:      "pollers",
+		Help:      "Number of locations polled for live weather streams.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// RegisterDBStats exposes the connection pool statistics from sql.DB.Stats().
+func RegisterDBStats(db *sql.DB, dbName string) {
+	Registry.MustRegister(collectors.NewDBStatsCollector(db, dbName))
+}
+
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+func ObserveUpstream(operation string, started time.Time, err error) {
+	outcome := UpstreamOutcome(err)
+	UpstreamRequestDuration.WithLabelValues(operation, outcome).Observe(time.Since(started).Seconds())
+	if err != nil {
+		UpstreamErrorsTotal.WithLabelValues(operation, outcome).Inc()
+	}
+}
+
+func UpstreamOutcome(err error) string {
+	switch {
+	case err == nil:
+		return OutcomeSuccess
+	case errors.Is(err, domain.ErrCityNotFound):
+		return OutcomeCityNotFound
+	default:
+		return OutcomeError
+	}
+}
+
+func ObserveEmail(kind string, err error) {
+	result := "sent"
+	if err != nil {
+		result = "failed"
+	}
+	EmailsTotal.WithLabelValues(kind, result).Inc()
+}
+
+func ObserveWebhookDelivery(event string, err error) {
+	result := "delivered"
+	if err != nil {
+		result = "failed"
+	}
+	WebhookDeliveriesTotal.WithLabelValues(event, result).Inc()
+}
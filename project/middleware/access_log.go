@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func AccessLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		started := time.Now()
+		// A panic is logged as the 500 Recovery answers it with, then passed
+		// on to Recovery.
+		defer func() {
+			recovered := recover()
+			status := c.Writer.Status()
+			if recovered != nil {
+				status = http.StatusInternalServerError
+			}
+			logRequest(c, started, status)
+			if recovered != nil {
+				panic(recovered)
+			}
+		}()
+		c.Next()
+	}
+}
+
+func logRequest(c *gin.Context, started time.Time, status int) {
+	level := slog.LevelInfo
+	if status >= 500 {
+		level = slog.LevelError
+	} else if status >= 400 {
+		level = slog.LevelWarn
+	}
+
+	slog.Log(c.Request.Context(), level, "HTTP request",
+		slog.String("method", c.Request.Method),
+		slog.String("path", c.Request.URL.Path),
+		slog.String("route", c.FullPath()),
+		slog.Int("status", status),
+		slog.Duration("latency", time.Since(started)),
+		slog.String("client_ip", c.ClientIP()),
+		slog.Int("bytes", c.Writer.Size()),
+	)
+}
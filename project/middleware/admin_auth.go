@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"log/slog"
+	"strings"
+	"weather/project/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminAuth protects administrative routes with a static bearer token. When
+// no token is configured the routes are locked rather than left open.
+func AdminAuth(adminToken string) gin.HandlerFunc {
+	if adminToken == "" {
+		slog.Warn("ADMIN_TOKEN is not set; admin endpoints are disabled.")
+	}
+	return func(c *gin.Context) {
+		provided, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if adminToken == "" || !ok || subtle.ConstantTimeCompare([]byte(provided), []byte(adminToken)) != 1 {
+			_ = c.Error(domain.ErrAdminUnauthorized)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
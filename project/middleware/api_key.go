@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"strconv"
+	"time"
+	"weather/project/domain"
+	"weather/project/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	APIKeyHeader = "X-API-Key"
+
+	// APIKeyContextKey holds the authenticated *domain.APIKey in gin.Context.
+	APIKeyContextKey = "api_key"
+)
+
+// RequestCost tells how many requests of the daily quota a call consumes.
+type RequestCost func(c *gin.Context) int
+
+func APIKeyAuth(apiKeyService service.APIKeyService) gin.HandlerFunc {
+	return APIKeyAuthWithCost(apiKeyService, func(*gin.Context) int { return 1 })
+}
+
+func APIKeyAuthWithCost(apiKeyService service.APIKeyService, cost RequestCost) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key, used, err := apiKeyService.Authorize(c.Request.Context(), c.GetHeader(APIKeyHeader), cost(c))
+		if err != nil {
+			if errors.Is(err, domain.ErrAPIKeyQuotaExceeded) {
+				setQuotaHeaders(c, key, used)
+				c.Header("Retry-After", strconv.Itoa(secondsUntilNextUTCDay()))
+			}
+			_ = c.Error(err)
+			c.Abort()
+			return
+		}
+
+		setQuotaHeaders(c, key, used)
+		c.Set(APIKeyContextKey, key)
+		c.Next()
+	}
+}
+
+func setQuotaHeaders(c *gin.Context, key *domain.APIKey, used int) {
+	remaining := key.DailyQuota - used
+	if remaining < 0 {
+		remaining = 0
+	}
+	c.Header("X-Quota-Limit", strconv.Itoa(key.DailyQuota))
+	c.Header("X-Quota-Remaining", strconv.Itoa(remaining))
+}
+
+func secondsUntilNextUTCDay() int {
+	now := time.Now().UTC()
+	return int(math.Ceil(now.Truncate(24 * time.Hour).Add(24 * time.Hour).Sub(now).Seconds()))
+}
+
+// CostPerCity charges a batch weather lookup one request per city, so a
+// batch draws the quota down exactly like the equivalent single lookups.
+// The body is restored for the handler.
+func CostPerCity(c *gin.Context) int {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return 1
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	var input domain.WeatherBatchInput
+	if err := json.Unmarshal(body, &input); err != nil || len(input.Cities) == 0 {
+		return 1
+	}
+	// Oversized batches are rejected later; do not bill them beyond the cap.
+	return min(len(input.Cities), domain.MaxWeatherBatchSize)
+}
@@ -0,0 +1,120 @@
+package middleware_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"weather/project/domain"
+	"weather/project/middleware"
+	"weather/project/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// quotaService grants "wk_valid" a quota of 3 and remembers the cost of
+// every call.
+type quotaService struct {
+	service.APIKeyService
+	used  int
+	costs []int
+}
+
+func (s *quotaService) Authorize(_ context.Context, rawKey string, cost int) (*domain.APIKey, int, error) {
+	s.costs = append(s.costs, cost)
+	if rawKey != "wk_valid" {
+		return nil, 0, domain.ErrAPIKeyInvalid
+	}
+	key := &domain.APIKey{Name: "test", DailyQuota: 3}
+	if s.used+cost > key.DailyQuota {
+		return key, s.used, domain.ErrAPIKeyQuotaExceeded
+	}
+	s.used += cost
+	return key, s.used, nil
+}
+
+func TestAPIKeyAuthQuotaHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	keys := &quotaService{}
+	router := gin.New()
+	router.Use(middleware.ErrorHandler())
+	router.POST("/weather/batch", middleware.APIKeyAuthWithCost(keys, middleware.CostPerCity), func(c *gin.Context) {
+		var input domain.WeatherBatchInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.String(http.StatusBadRequest, "body lost")
+			return
+		}
+		c.String(http.StatusOK, strconv.Itoa(len(input.Cities)))
+	})
+
+	steps := []struct {
+		name          string
+		key, body     string
+		wantStatus    int
+		wantCost      int
+		wantRemaining string
+	}{
+		{name: "no key", key: "", body: `{"cities":["Kyiv"]}`, wantStatus: http.StatusUnauthorized, wantCost: 1},
+		{name: "two cities", key: "wk_valid", body: `{"cities":["Kyiv","Lviv"]}`, wantStatus: http.StatusOK, wantCost: 2, wantRemaining: "1"},
+		{name: "malformed body costs one", key: "wk_valid", body: `{"cities":`, wantStatus: http.StatusBadRequest, wantCost: 1, wantRemaining: "0"},
+		{name: "quota spent", key: "wk_valid", body: `{"cities":["Odesa"]}`, wantStatus: http.StatusTooManyRequests, wantCost: 1, wantRemaining: "0"},
+	}
+	for _, step := range steps {
+		req := httptest.NewRequest(http.MethodPost, "/weather/batch", strings.NewReader(step.body))
+		req.Header.Set("Content-Type", "application/json")
+		if step.key != "" {
+			req.Header.Set(middleware.APIKeyHeader, step.key)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != step.wantStatus {
+			t.Fatalf("%s: status = %d, want %d: %s", step.name, rec.Code, step.wantStatus, rec.Body)
+		}
+		if got := keys.costs[len(keys.costs)-1]; got != step.wantCost {
+			t.Errorf("%s: cost = %d, want %d", step.name, got, step.wantCost)
+		}
+		if got := rec.Header().Get("X-Quota-Remaining"); got != step.wantRemaining {
+			t.Errorf("%s: X-Quota-Remaining = %q, want %q", step.name, got, step.wantRemaining)
+		}
+		if step.wantRemaining != "" && rec.Header().Get("X-Quota-Limit") != "3" {
+			t.Errorf("%s: X-Quota-Limit = %q, want 3", step.name, rec.Header().Get("X-Quota-Limit"))
+		}
+		retryAfter := rec.Header().Get("Retry-After")
+		if step.wantStatus != http.StatusTooManyRequests {
+			if retryAfter != "" {
+				t.Errorf("%s: unexpected Retry-After %q", step.name, retryAfter)
+			}
+			continue
+		}
+		if seconds, err := strconv.Atoi(retryAfter); err != nil || seconds < 1 || seconds > 24*60*60 {
+			t.Errorf("%s: Retry-After = %q, want the seconds until the next UTC day", step.name, retryAfter)
+		}
+		if !strings.Contains(rec.Body.String(), `"code":"quota_exceeded"`) {
+			t.Errorf("%s: body = %s", step.name, rec.Body)
+		}
+	}
+	if keys.used != 3 {
+		t.Errorf("used = %d, want 3", keys.used)
+	}
+}
+
+func TestCostPerCityCapsOversizedBatches(t *testing.T) {
+	cities := make([]string, domain.MaxWeatherBatchSize+10)
+	for i := range cities {
+		cities[i] = `"City"`
+	}
+	body := `{"cities":[` + strings.Join(cities, ",") + `]}`
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/weather/batch", strings.NewReader(body))
+
+	if got := middleware.CostPerCity(c); got != domain.MaxWeatherBatchSize {
+		t.Errorf("cost = %d, want %d", got, domain.MaxWeatherBatchSize)
+	}
+	if restored, err := io.ReadAll(c.Request.Body); err != nil || string(restored) != body {
+		t.Errorf("body not restored for the handler: %v", err)
+	}
+}
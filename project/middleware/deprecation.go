@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Deprecated marks every response of a route group as deprecated (RFC 9745)
+// with the date it stops being served (RFC 8594), and links the equivalent
+// path under successorPrefix.
+func Deprecated(prefix, successorPrefix string, deprecatedAt, sunset time.Time) gin.HandlerFunc {
+	deprecation := fmt.Sprintf("@%d", deprecatedAt.Unix())
+	sunsetHeader := sunset.UTC().Format(http.TimeFormat)
+	return func(c *gin.Context) {
+		c.Header("Deprecation", deprecation)
+		c.Header("Sunset", sunsetHeader)
+		successor := successorPrefix + strings.TrimPrefix(c.Request.URL.Path, prefix)
+		c.Header("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", successor))
+		c.Next()
+	}
+}
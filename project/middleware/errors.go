@@ -0,0 +1,204 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"runtime/debug"
+	"strings"
+	"weather/project/domain"
+	"weather/project/i18n"
+	"weather/project/logging"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+const (
+	ProblemContentType = "application/problem+json"
+	problemTypePrefix  = "urn:weather-api:problem:"
+)
+
+type problemMapping struct {
+	err    error
+	status int
+	code   string
+	title  string
+}
+
+// problemMappings is the single place where domain errors get an HTTP status
+// and a stable code. Order matters only for errors wrapping one another.
+var problemMappings = []problemMapping{
+	validationFailed,
+	{domain.ErrCityNotFound, http.StatusNotFound, "city_not_found", "City not found"},
+	{domain.ErrFailedToFetchWeather, http.StatusInternalServerError, "upstream_unavailable", "Weather provider unavailable"},
+	{domain.ErrEmailAlreadySubscribed, http.StatusConflict, "email_already_subscribed", "Email already subscribed"},
+	{domain.ErrSubscriptionNotFound, http.StatusNotFound, "subscription_not_found", "Subscription not found"},
+	{domain.ErrTokenInvalidOrExpired, http.StatusNotFound, "token_invalid", "Token invalid or expired"},
+	{domain.ErrAPIKeyInvalid, http.StatusUnauthorized, "api_key_invalid", "Invalid API key"},
+	{domain.ErrAPIKeyNotFound, http.StatusNotFound, "api_key_not_found", "API key not found"},
+	{domain.ErrAPIKeyRevoked, http.StatusConflict, "api_key_revoked", "API key revoked"},
+	{domain.ErrAPIKeyQuotaExceeded, http.StatusTooManyRequests, "quota_exceeded", "Daily quota exceeded"},
+	{domain.ErrHistoryUnavailable, http.StatusNotFound, "history_unavailable", "Weather history unavailable"},
+	{domain.ErrWebhookVerification, http.StatusUnprocessableEntity, "webhook_verification_failed", "Webhook verification failed"},
+	{domain.ErrChatWebhookRejected, http.StatusUnprocessableEntity, "webhook_verification_failed", "Webhook verification failed"},
+	{domain.ErrPrivacyRequestInvalid, http.StatusNotFound, "privacy_request_invalid", "Privacy request link invalid"},
+	{domain.ErrChatSubscriptionLimit, http.StatusConflict, "subscription_limit_reached", "Subscription limit reached"},
+	{domain.ErrEmailSendingFailed, http.StatusBadGateway, "email_delivery_failed", "Email delivery failed"},
+	{domain.ErrRateLimited, http.StatusTooManyRequests, "rate_limited", "Too many requests"},
+	{domain.ErrRequestTooLarge, http.StatusRequestEntityTooLarge, "request_too_large", "Request too large"},
+	{domain.ErrAdminUnauthorized, http.StatusUnauthorized, "unauthorized", "Unauthorized"},
+	{domain.ErrRouteNotFound, http.StatusNotFound, "not_found", "Not found"},
+}
+
+var (
+	// validationFailed also covers binding and schema failures, which carry
+	// no domain error.
+	validationFailed = problemMapping{domain.ErrInvalidInput, http.StatusBadRequest, "validation_failed", "Invalid request"}
+	internalError    = problemMapping{nil, http.StatusInternalServerError, "internal_error", "Internal server error"}
+)
+
+func init() {
+	// Report validation failures under the names clients actually send.
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			for _, tag := range []string{"json", "form"} {
+				if name := strings.Split(field.Tag.Get(tag), ",")[0]; name != "" && name != "-" {
+					return name
+				}
+			}
+			return field.Name
+		})
+	}
+}
+
+// Recovery turns panics into an internal_error problem. It is registered
+// first so it also catches panics in the other middleware; ErrorHandler has
+// been unwound by then, so the problem is rendered here.
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, recovered any) {
+		slog.ErrorContext(c.Request.Context(), "Panic recovered", slog.Any("panic", recovered), slog.String("stack", string(debug.Stack())))
+		ginErr := c.Error(fmt.Errorf("panic: %v", recovered))
+		if c.Writer.Written() {
+			c.Abort()
+			return
+		}
+		problem := NewProblem(c, ginErr)
+		c.Header("Content-Type", ProblemContentType)
+		c.AbortWithStatusJSON(problem.Status, problem)
+	})
+}
+
+// NotFound is used for unknown routes so they get a problem document too.
+func NotFound(c *gin.Context) {
+	_ = c.Error(domain.ErrRouteNotFound)
+}
+
+// ErrorHandler renders the last error attached with c.Error as an RFC 7807
+// problem document, unless the handler has already written a response.
+func ErrorHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		problem := NewProblem(c, c.Errors.Last())
+		if problem.Status >= http.StatusInternalServerError {
+			slog.ErrorContext(c.Request.Context(), "Request failed", slog.String("code", problem.Code), slog.Any("error", c.Errors.Last().Err))
+		} else {
+			slog.InfoContext(c.Request.Context(), "Request rejected", slog.String("code", problem.Code), slog.Any("error", c.Errors.Last().Err))
+		}
+
+		c.Header("Content-Type", ProblemContentType)
+		c.JSON(problem.Status, problem)
+	}
+}
+
+// NewProblem renders err in the language of the request; field messages
+// without a catalog entry stay in English.
+func NewProblem(c *gin.Context, ginErr *gin.Error) domain.Problem {
+	err := ginErr.Err
+	lang := i18n.FromContext(c.Request.Context())
+	mapping := internalError
+	detail := ""
+	var fields []domain.FieldError
+
+	var validationErrs validator.ValidationErrors
+	var validationErr *domain.ValidationError
+	switch {
+	case errors.As(err, &validationErrs):
+		mapping = validationFailed
+		fields = fieldErrors(lang, validationErrs)
+		detail = lang.T("One or more fields are invalid.")
+	case errors.As(err, &validationErr):
+		mapping = validationFailed
+		fields = make([]domain.FieldError, len(validationErr.Fields))
+		for i, f := range validationErr.Fields {
+			fields[i] = domain.FieldError{Field: f.Field, Message: lang.T(f.Message)}
+		}
+		detail = lang.T("One or more fields are invalid.")
+	case ginErr.IsType(gin.ErrorTypeBind):
+		mapping = validationFailed
+		detail = bindErrorDetail(lang, err)
+	default:
+		for _, m := range problemMappings {
+			if errors.Is(err, m.err) {
+				mapping = m
+				detail = lang.T(m.err.Error())
+				break
+			}
+		}
+	}
+
+	return domain.Problem{
+		Type:      problemTypePrefix + mapping.code,
+		Title:     lang.T(mapping.title),
+		Status:    mapping.status,
+		Detail:    detail,
+		Instance:  c.Request.URL.Path,
+		Code:      mapping.code,
+		RequestID: logging.RequestIDFromContext(c.Request.Context()),
+		Errors:    fields,
+	}
+}
+
+func fieldErrors(lang i18n.Lang, errs validator.ValidationErrors) []domain.FieldError {
+	fields := make([]domain.FieldError, len(errs))
+	for i, fe := range errs {
+		message := lang.Tf("failed on the '%s' rule", fe.Tag())
+		switch fe.Tag() {
+		case "required":
+			message = lang.T("is required")
+		case "email":
+			message = lang.T("must be a valid email address")
+		case "oneof":
+			message = lang.Tf("must be one of: %s", fe.Param())
+		case "min", "max":
+			message = lang.Tf("must satisfy %s=%s", fe.Tag(), fe.Param())
+		}
+		fields[i] = domain.FieldError{Field: fe.Field(), Message: message}
+	}
+	return fields
+}
+
+func bindErrorDetail(lang i18n.Lang, err error) string {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.Is(err, io.EOF):
+		return lang.T("Request body is empty.")
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF):
+		return lang.T("Request body is not valid JSON.")
+	case errors.As(err, &typeErr):
+		return lang.Tf("Field %q has the wrong type.", typeErr.Field)
+	default:
+		return lang.T("Request could not be parsed.")
+	}
+}
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"weather/project/domain"
+	"weather/project/i18n"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Language picks the response language from ?lang=, falling back to
+// Accept-Language, and stores it in the request context for problem
+// documents, emails and the weather provider.
+func Language() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		lang := i18n.Negotiate(c.GetHeader("Accept-Language"))
+		tag, explicit := c.GetQuery("lang")
+		supported := true
+		if explicit {
+			var parsed i18n.Lang
+			if parsed, supported = i18n.Parse(tag); supported {
+				lang = parsed
+			}
+		}
+
+		c.Request = c.Request.WithContext(i18n.WithLang(c.Request.Context(), lang))
+		c.Header("Content-Language", string(lang))
+		c.Writer.Header().Add("Vary", "Accept-Language")
+
+		if !supported {
+			_ = c.Error(domain.NewFieldError("lang", "is not a supported language"))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
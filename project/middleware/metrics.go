@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+	"weather/project/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// unmatchedRoute labels requests that did not hit any registered route, so
+// that scanning for random paths cannot blow up label cardinality.
+const unmatchedRoute = "unmatched"
+
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		started := time.Now()
+		// Like AccessLog, a panic is counted as a 500 and passed on.
+		defer func() {
+			recovered := recover()
+			status := c.Writer.Status()
+			if recovered != nil {
+				status = http.StatusInternalServerError
+			}
+			observeRequest(c, started, status)
+			if recovered != nil {
+				panic(recovered)
+			}
+		}()
+		c.Next()
+	}
+}
+
+func observeRequest(c *gin.Context, started time.Time, status int) {
+	route := c.FullPath()
+	if route == "" {
+		route = unmatchedRoute
+	}
+	code := strconv.Itoa(status)
+
+	metrics.HTTPRequestsTotal.WithLabelValues(route, c.Request.Method, code).Inc()
+	metrics.HTTPRequestDuration.WithLabelValues(route, c.Request.Method, code).Observe(time.Since(started).Seconds())
+}
@@ -0,0 +1,250 @@
+package middleware
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"weather/project/domain"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/gin-gonic/gin"
+)
+
+// NewSpecRouter resolves requests to operations of the OpenAPI document.
+func NewSpecRouter(doc *openapi3.T) (routers.Router, error) {
+	router, err := gorillamux.NewRouter(doc)
+	if err != nil {
+		return nil, fmt.Errorf("middleware.NewSpecRouter: %w", err)
+	}
+	return router, nil
+}
+
+func init() {
+	const form = "application/x-www-form-urlencoded"
+	openapi3filter.RegisterBodyDecoder(form, formBodyDecoder(openapi3filter.RegisteredBodyDecoder(form)))
+	// HTML pages are checked as plain strings.
+	openapi3filter.RegisterBodyDecoder("text/html", openapi3filter.RegisteredBodyDecoder("text/plain"))
+}
+
+// formBodyDecoder drops the fields a form leaves out. kin-openapi decodes
+// them as null, which fails validation for every optional field.
+func formBodyDecoder(decode openapi3filter.BodyDecoder) openapi3filter.BodyDecoder {
+	return func(body io.Reader, header http.Header, schema *openapi3.SchemaRef, encFn openapi3filter.EncodingFn) (any, error) {
+		value, err := decode(body, header, formSchema(schema), encFn)
+		if obj, ok := value.(map[string]any); ok {
+			for name, v := range obj {
+				if v == nil {
+					delete(obj, name)
+				}
+			}
+		}
+		return value, err
+	}
+}
+
+// formSchema leaves out properties a form cannot carry, such as lists of
+// objects, which only JSON bodies accept. kin-openapi refuses to decode a
+// form against a schema that has them.
+func formSchema(schema *openapi3.SchemaRef) *openapi3.SchemaRef {
+	properties := make(openapi3.Schemas, len(schema.Value.Properties))
+	for name, prop := range schema.Value.Properties {
+		nested := prop.Value.Type.Is("object") ||
+			(prop.Value.Type.Is("array") && prop.Value.Items != nil && prop.Value.Items.Value.Type.Is("object"))
+		if !nested {
+			properties[name] = prop
+		}
+	}
+	if len(properties) == len(schema.Value.Properties) {
+		return schema
+	}
+	flat := *schema.Value
+	flat.Properties = properties
+	return &openapi3.SchemaRef{Value: &flat}
+}
+
+// Authentication is enforced by the route middleware, the spec only
+// documents it. Handlers apply their own defaults; letting the validator fill
+// them in would rewrite request bodies, which it cannot do for forms.
+var specValidationOptions = &openapi3filter.Options{
+	MultiError:          true,
+	AuthenticationFunc:  openapi3filter.NoopAuthenticationFunc,
+	SkipSettingDefaults: true,
+}
+
+// ValidateRequests rejects requests that do not match the OpenAPI document
+// with a validation_failed problem. Routes missing from the document are
+// passed through untouched.
+func ValidateRequests(router routers.Router) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route, pathParams, err := router.FindRoute(c.Request)
+		if err != nil {
+			c.Next()
+			return
+		}
+
+		input := &openapi3filter.RequestValidationInput{
+			Request:    c.Request,
+			PathParams: pathParams,
+			Route:      route,
+			Options:    specValidationOptions,
+		}
+		if err := openapi3filter.ValidateRequest(c.Request.Context(), input); err != nil {
+			_ = c.Error(&domain.ValidationError{Fields: specFieldErrors(err)})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// ValidateResponses buffers every response of a documented route and
+// replaces it with an internal_error problem if it breaks the contract. It
+// must wrap ErrorHandler so problem documents are checked as well, and is
+// meant for tests and staging since it holds each body in memory. Event
+// streams never end, so their routes are passed through unbuffered.
+func ValidateResponses(router routers.Router) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route, pathParams, err := router.FindRoute(c.Request)
+		if err != nil || streamsEvents(route) {
+			c.Next()
+			return
+		}
+
+		original := c.Writer
+		buffered := &bufferedWriter{ResponseWriter: original, status: http.StatusOK}
+		c.Writer = buffered
+		// Restored on panic too, so Recovery writes to the client.
+		defer func() { c.Writer = original }()
+		c.Next()
+		c.Writer = original
+
+		input := &openapi3filter.ResponseValidationInput{
+			RequestValidationInput: &openapi3filter.RequestValidationInput{
+				Request:    c.Request,
+				PathParams: pathParams,
+				Route:      route,
+			},
+			Status:  buffered.status,
+			Header:  original.Header(),
+			Body:    io.NopCloser(bytes.NewReader(buffered.body.Bytes())),
+			Options: specValidationOptions,
+		}
+		if err := openapi3filter.ValidateResponse(c.Request.Context(), input); err != nil {
+			slog.ErrorContext(c.Request.Context(), "Response violates the OpenAPI contract", slog.String("route", route.Path), slog.Int("status", buffered.status), slog.Any("error", err))
+			problem := NewProblem(c, &gin.Error{Err: fmt.Errorf("middleware.ValidateResponses: %w", err)})
+			original.Header().Set("Content-Type", ProblemContentType)
+			c.JSON(problem.Status, problem)
+			return
+		}
+
+		original.WriteHeader(buffered.status)
+		if buffered.written {
+			original.WriteHeaderNow()
+			_, _ = original.Write(buffered.body.Bytes())
+		}
+	}
+}
+
+func streamsEvents(route *routers.Route) bool {
+	ok := route.Operation.Responses.Status(http.StatusOK)
+	return ok != nil && ok.Value != nil && ok.Value.Content.Get("text/event-stream") != nil
+}
+
+// bufferedWriter holds the response back until it has been validated.
+type bufferedWriter struct {
+	gin.ResponseWriter
+	body    bytes.Buffer
+	status  int
+	written bool
+}
+
+func (w *bufferedWriter) WriteHeader(code int) {
+	if !w.written {
+		w.status = code
+	}
+}
+
+func (w *bufferedWriter) WriteHeaderNow() {
+	w.written = true
+}
+
+func (w *bufferedWriter) Write(data []byte) (int, error) {
+	w.written = true
+	return w.body.Write(data)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	w.written = true
+	return w.body.WriteString(s)
+}
+
+func (w *bufferedWriter) Status() int {
+	return w.status
+}
+
+func (w *bufferedWriter) Size() int {
+	if !w.written {
+		return -1
+	}
+	return w.body.Len()
+}
+
+func (w *bufferedWriter) Written() bool {
+	return w.written
+}
+
+func (w *bufferedWriter) Flush() {}
+
+// specFieldErrors flattens the nested errors of kin-openapi into one entry
+// per offending parameter or body field. Type switches are used on purpose:
+// errors.As would look through a RequestError and lose the parameter name.
+func specFieldErrors(err error) []domain.FieldError {
+	switch e := err.(type) {
+	case openapi3.MultiError:
+		var fields []domain.FieldError
+		for _, inner := range e {
+			fields = append(fields, specFieldErrors(inner)...)
+		}
+		return fields
+	case *openapi3filter.RequestError:
+		if e.Parameter != nil {
+			return []domain.FieldError{{Field: e.Parameter.Name, Message: specErrorMessage(e)}}
+		}
+		switch e.Err.(type) {
+		case openapi3.MultiError, *openapi3.SchemaError:
+			return specFieldErrors(e.Err)
+		}
+		return []domain.FieldError{{Field: "body", Message: specErrorMessage(e)}}
+	case *openapi3.SchemaError:
+		field := strings.Join(e.JSONPointer(), ".")
+		if field == "" {
+			field = "body"
+		}
+		return []domain.FieldError{{Field: field, Message: e.Reason}}
+	default:
+		return []domain.FieldError{{Field: "request", Message: err.Error()}}
+	}
+}
+
+func specErrorMessage(err *openapi3filter.RequestError) string {
+	cause := err.Err
+	if multi, ok := cause.(openapi3.MultiError); ok && len(multi) > 0 {
+		cause = multi[0]
+	}
+	if schemaErr, ok := cause.(*openapi3.SchemaError); ok {
+		return schemaErr.Reason
+	}
+	if err.Reason != "" {
+		return err.Reason
+	}
+	if cause != nil {
+		return cause.Error()
+	}
+	return "is invalid"
+}
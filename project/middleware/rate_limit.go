@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"weather/project/domain"
+	"weather/project/metrics"
+	"weather/project/ratelimit"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// maxEmailBodyBytes bounds the bodies read to find the email address; the
+// subscription and privacy forms are far smaller.
+const maxEmailBodyBytes = 8 << 10
+
+// RateLimitByIP throttles requests per client IP. The IP is resolved by gin,
+// which only honours X-Forwarded-For when the peer is a trusted proxy.
+func RateLimitByIP(store ratelimit.Store, limit ratelimit.Limit) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		applyRateLimit(c, store, "ip", c.ClientIP(), limit)
+	}
+}
+
+// RateLimitByEmail throttles requests targeting the same email address, so a
+// single inbox cannot be flooded from many different IPs.
+func RateLimitByEmail(store ratelimit.Store, limit ratelimit.Limit) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		email, err := emailFromBody(c)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			_ = c.Error(domain.ErrRequestTooLarge)
+			c.Abort()
+			return
+		}
+		if err != nil || email == "" {
+			// Let the handler reject malformed input with a proper message.
+			c.Next()
+			return
+		}
+		applyRateLimit(c, store, "email", email, limit)
+	}
+}
+
+func applyRateLimit(c *gin.Context, store ratelimit.Store, scope, subject string, limit ratelimit.Limit) {
+	allowed, retryAfter, err := store.Take(c.Request.Context(), scope+":"+subject, limit)
+	if err != nil {
+		// Fail open: an unavailable shared store must not take the API down.
+		slog.ErrorContext(c.Request.Context(), "Rate limit store failed", slog.String("scope", scope), slog.Any("error", err))
+		c.Next()
+		return
+	}
+	if !allowed {
+		metrics.RateLimitedTotal.WithLabelValues(scope).Inc()
+		slog.WarnContext(c.Request.Context(), "Rate limit exceeded", slog.String("scope", scope), slog.String("subject", subject), slog.Duration("retry_after", retryAfter))
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		_ = c.Error(domain.ErrRateLimited)
+		c.Abort()
+		return
+	}
+	c.Next()
+}
+
+// emailFromBody extracts the "email" field without consuming the request
+// body, which is restored for the handler to bind again.
+func emailFromBody(c *gin.Context) (string, error) {
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxEmailBodyBytes))
+	if err != nil {
+		return "", err
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	req := c.Request.Clone(c.Request.Context())
+	req.Body = io.NopCloser(bytes.NewReader(body))
+
+	var input struct {
+		Email string `form:"email" json:"email"`
+	}
+	if err := binding.Default(req.Method, c.ContentType()).Bind(req, &input); err != nil {
+		return "", err
+	}
+	return strings.ToLower(strings.TrimSpace(input.Email)), nil
+}
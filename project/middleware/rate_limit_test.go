@@ -0,0 +1,178 @@
+package middleware_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"weather/project/domain"
+	"weather/project/middleware"
+	"weather/project/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fakeClock is advanced by the test; the store reads it on every Take.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// recordingStore passes Take on to a memory store and remembers the keys,
+// or fails every call when err is set.
+type recordingStore struct {
+	ratelimit.Store
+	err  error
+	keys []string
+}
+
+func (s *recordingStore) Take(ctx context.Context, key string, limit ratelimit.Limit) (bool, time.Duration, error) {
+	s.keys = append(s.keys, key)
+	if s.err != nil {
+		return false, 0, s.err
+	}
+	return s.Store.Take(ctx, key, limit)
+}
+
+func newRateLimitedRouter(store ratelimit.Store) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.ErrorHandler())
+	router.POST("/subscribe",
+		middleware.RateLimitByIP(store, ratelimit.Limit{Every: time.Minute, Burst: 2}),
+		middleware.RateLimitByEmail(store, ratelimit.Limit{Every: 20 * time.Minute, Burst: 1}),
+		func(c *gin.Context) {
+			var input struct {
+				Email string `json:"email"`
+			}
+			// The handler must still see the body the middleware read.
+			if err := c.ShouldBindJSON(&input); err != nil {
+				c.String(http.StatusBadRequest, "body lost")
+				return
+			}
+			c.String(http.StatusOK, input.Email)
+		})
+	return router
+}
+
+func subscribe(router http.Handler, ip, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/subscribe", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.RemoteAddr = ip + ":40000"
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRateLimit(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)}
+	store := &recordingStore{Store: ratelimit.NewMemoryStoreWithClock(clock.Now)}
+	router := newRateLimitedRouter(store)
+
+	steps := []struct {
+		name           string
+		advance        time.Duration
+		ip, email      string
+		wantStatus     int
+		wantRetryAfter string
+	}{
+		{name: "first request", ip: "203.0.113.1", email: "Someone@Example.com", wantStatus: http.StatusOK},
+		{name: "same email, other IP", ip: "203.0.113.2", email: " someone@example.com", wantStatus: http.StatusTooManyRequests, wantRetryAfter: "1200"},
+		{name: "other email", ip: "203.0.113.1", email: "other@example.com", wantStatus: http.StatusOK},
+		{name: "IP burst spent", ip: "203.0.113.1", email: "third@example.com", wantStatus: http.StatusTooManyRequests, wantRetryAfter: "60"},
+		{name: "partly refilled", advance: 45 * time.Second, ip: "203.0.113.1", email: "third@example.com", wantStatus: http.StatusTooManyRequests, wantRetryAfter: "15"},
+		{name: "one IP token back", advance: 15 * time.Second, ip: "203.0.113.1", email: "third@example.com", wantStatus: http.StatusOK},
+		{name: "email bucket refilled", advance: 20 * time.Minute, ip: "203.0.113.3", email: "someone@example.com", wantStatus: http.StatusOK},
+	}
+	for _, step := range steps {
+		clock.Advance(step.advance)
+		rec := subscribe(router, step.ip, `{"email":"`+step.email+`"}`)
+		if rec.Code != step.wantStatus {
+			t.Fatalf("%s: status = %d, want %d: %s", step.name, rec.Code, step.wantStatus, rec.Body)
+		}
+		if got := rec.Header().Get("Retry-After"); got != step.wantRetryAfter {
+			t.Errorf("%s: Retry-After = %q, want %q", step.name, got, step.wantRetryAfter)
+		}
+		if rec.Code != http.StatusTooManyRequests {
+			if got := rec.Body.String(); got != step.email {
+				t.Errorf("%s: handler saw email %q", step.name, got)
+			}
+			continue
+		}
+		if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, middleware.ProblemContentType) {
+			t.Errorf("%s: Content-Type = %q", step.name, ct)
+		}
+		var problem domain.Problem
+		if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+			t.Fatalf("%s: decoding problem: %v", step.name, err)
+		}
+		if problem.Status != http.StatusTooManyRequests || problem.Code != "rate_limited" {
+			t.Errorf("%s: problem = %+v", step.name, problem)
+		}
+	}
+
+	// Email buckets are keyed on the normalized address.
+	emailKeys := map[string]bool{}
+	for _, key := range store.keys {
+		if strings.HasPrefix(key, "email:") {
+			emailKeys[key] = true
+		}
+	}
+	for _, key := range []string{"email:someone@example.com", "email:other@example.com", "email:third@example.com"} {
+		if !emailKeys[key] {
+			t.Errorf("no Take for %s in %v", key, store.keys)
+		}
+	}
+	if len(emailKeys) != 3 {
+		t.Errorf("email keys = %v", emailKeys)
+	}
+}
+
+func TestRateLimitByEmailRejectsOversizedBody(t *testing.T) {
+	store := &recordingStore{Store: ratelimit.NewMemoryStore()}
+	router := newRateLimitedRouter(store)
+
+	body := `{"email":"someone@example.com","city":"` + strings.Repeat("x", 8<<10) + `"}`
+	rec := subscribe(router, "203.0.113.1", body)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status = %d, want 413: %s", rec.Code, rec.Body)
+	}
+	var problem domain.Problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil || problem.Code != "request_too_large" {
+		t.Errorf("problem = %+v (%v)", problem, err)
+	}
+	for _, key := range store.keys {
+		if strings.HasPrefix(key, "email:") {
+			t.Errorf("oversized body reached the email bucket: %s", key)
+		}
+	}
+}
+
+func TestRateLimitFailsOpen(t *testing.T) {
+	store := &recordingStore{err: errors.New("store unavailable")}
+	router := newRateLimitedRouter(store)
+
+	if rec := subscribe(router, "203.0.113.1", `{"email":"someone@example.com"}`); rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want 200 while the store is down: %s", rec.Code, rec.Body)
+	}
+	if len(store.keys) != 2 {
+		t.Errorf("Take calls = %v, want the IP and the email bucket", store.keys)
+	}
+}
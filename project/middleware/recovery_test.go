@@ -0,0 +1,42 @@
+package middleware_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"weather/project/domain"
+	"weather/project/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRecoveryCatchesPanicsInMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.Recovery(), middleware.RequestID(), middleware.AccessLog(), middleware.Metrics())
+	// Panics outside ErrorHandler, which never gets to render them.
+	router.Use(func(c *gin.Context) {
+		if c.Query("panic") == "early" {
+			panic("middleware exploded")
+		}
+		c.Next()
+	}, middleware.ErrorHandler())
+	router.GET("/boom", func(c *gin.Context) { panic("handler exploded") })
+
+	for _, path := range []string{"/boom?panic=early", "/boom"} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+		if rec.Code != http.StatusInternalServerError {
+			t.Fatalf("%s: status = %d: %s", path, rec.Code, rec.Body)
+		}
+		var problem domain.Problem
+		if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+			t.Fatalf("%s: decoding problem: %v: %s", path, err, rec.Body)
+		}
+		if problem.Code != "internal_error" || problem.RequestID != rec.Header().Get(middleware.RequestIDHeader) {
+			t.Errorf("%s: problem = %+v", path, problem)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// Limit describes a token bucket: one token is added every Every, and at most
+// Burst tokens can be accumulated.
+type Limit struct {
+	Every time.Duration
+	Burst int
+}
+
+// Store keeps token buckets. The in-memory implementation is enough for a
+// single instance; a shared implementation (e.g. Redis-backed) can be plugged
+// in when the API runs behind a load balancer.
+type Store interface {
+	// Take consumes one token from the bucket identified by key. When the
+	// bucket is empty it reports how long the caller has to wait.
+	Take(ctx context.Context, key string, limit Limit) (allowed bool, retryAfter time.Duration, err error)
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	fullAt  time.Time
+}
+
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+const sweepInterval = time.Minute
+
+func NewMemoryStore() *MemoryStore {
+	return NewMemoryStoreWithClock(time.Now)
+}
+
+// NewMemoryStoreWithClock reads the time from now, so tests can refill the
+// buckets without waiting.
+func NewMemoryStoreWithClock(now func() time.Time) *MemoryStore {
+	return &MemoryStore{
+		buckets: make(map[string]*bucket),
+		now:     now,
+	}
+}
+
+func (s *MemoryStore) Take(_ context.Context, key string, limit Limit) (bool, time.Duration, error) {
+	if limit.Every <= 0 {
+		return false, 0, fmt.Errorf("ratelimit.Take: limit interval must be positive, got %s", limit.Every)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), updated: now}
+		s.buckets[key] = b
+	}
+
+	refilled := now.Sub(b.updated).Seconds() / limit.Every.Seconds()
+	b.tokens = math.Min(float64(limit.Burst), b.tokens+refilled)
+	b.updated = now
+
+	allowed := b.tokens >= 1
+	var retryAfter time.Duration
+	if allowed {
+		b.tokens--
+	} else {
+		retryAfter = time.Duration((1 - b.tokens) * float64(limit.Every))
+	}
+	b.fullAt = now.Add(time.Duration((float64(limit.Burst) - b.tokens) * float64(limit.Every)))
+
+	return allowed, retryAfter, nil
+}
+
+// sweep drops buckets that have refilled completely: they are
+// indistinguishable from a fresh bucket, so keeping them only costs memory.
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for key, b := range s.buckets {
+		if !now.Before(b.fullAt) {
+			delete(s.buckets, key)
+		}
+	}
+}
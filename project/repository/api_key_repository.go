@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+	"weather/project/domain"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *domain.APIKey) error
+	FindByID(ctx context.Context, id uuid.UUID) (*domain.APIKey, error)
+	FindByHash(ctx context.Context, keyHash string) (*domain.APIKey, error)
+	List(ctx context.Context) ([]domain.APIKey, error)
+	Update(ctx context.Context, key *domain.APIKey) error
+	TouchLastUsed(ctx context.Context, id uuid.UUID, at time.Time) error
+	// IncrementUsage atomically adds n requests to the key's counter for day
+	// unless the total would exceed quota. It returns the total and whether
+	// the requests were counted, so rejected calls never use up the quota.
+	IncrementUsage(ctx context.Context, id uuid.UUID, day string, n, quota int) (int, bool, error)
+	UsageForDay(ctx context.Context, day string) (map[uuid.UUID]int, error)
+}
+
+type apiKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewAPIKeyRepository(db *gorm.DB) APIKeyRepository {
+	return &apiKeyRepository{db: db}
+}
+
+func (r *apiKeyRepository) Create(ctx context.Context, key *domain.APIKey) error {
+	return r.db.WithContext(ctx).Create(key).Error
+}
+
+func (r *apiKeyRepository) FindByID(ctx context.Context, id uuid.UUID) (*domain.APIKey, error) {
+	var key domain.APIKey
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&key).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrAPIKeyNotFound
+		}
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *apiKeyRepository) FindByHash(ctx context.Context, keyHash string) (*domain.APIKey, error) {
+	var key domain.APIKey
+	err := r.db.WithContext(ctx).Where("key_hash = ?", keyHash).First(&key).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrAPIKeyInvalid
+		}
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *apiKeyRepository) List(ctx context.Context) ([]domain.APIKey, error) {
+	var keys []domain.APIKey
+	err := r.db.WithContext(ctx).Order("created_at DESC").Find(&keys).Error
+	return keys, err
+}
+
+func (r *apiKeyRepository) Update(ctx context.Context, key *domain.APIKey) error {
+	if key.ID == uuid.Nil {
+		return errors.New("cannot update API key without ID")
+	}
+	return r.db.WithContext(ctx).Save(key).Error
+}
+
+func (r *apiKeyRepository) TouchLastUsed(ctx context.Context, id uuid.UUID, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&domain.APIKey{}).Where("id = ?", id).UpdateColumn("last_used_at", at).Error
+}
+
+func (r *apiKeyRepository) IncrementUsage(ctx context.Context, id uuid.UUID, day string, n, quota int) (int, bool, error) {
+	var count int
+	var counted bool
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		usage := domain.APIKeyUsage{APIKeyID: id, Day: day}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&usage).Error; err != nil {
+			return err
+		}
+		// The guard in the WHERE clause keeps concurrent calls from
+		// overshooting the quota between the check and the update.
+		result := tx.Model(&domain.APIKeyUsage{}).
+			Where("api_key_id = ? AND day = ? AND count + ? <= ?", id, day, n, quota).
+			UpdateColumn("count", gorm.Expr("count + ?", n))
+		if result.Error != nil {
+			return result.Error
+		}
+		counted = result.RowsAffected == 1
+		return tx.Model(&domain.APIKeyUsage{}).
+			Where("api_key_id = ? AND day = ?", id, day).
+			Select("count").Scan(&count).Error
+	})
+	return count, counted, err
+}
+
+func (r *apiKeyRepository) UsageForDay(ctx context.Context, day string) (map[uuid.UUID]int, error) {
+	var rows []domain.APIKeyUsage
+	if err := r.db.WithContext(ctx).Where("day = ?", day).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	usage := make(map[uuid.UUID]int, len(rows))
+	for _, row := range rows {
+		usage[row.APIKeyID] = row.Count
+	}
+	return usage, nil
+}
@@ -1,15 +1,17 @@
 package repository
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"time"
 	"weather/project/config"
 	"weather/project/domain"
+	"weather/project/logging"
+	"weather/project/metrics"
 
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 )
 
 var DB *gorm.DB
@@ -18,11 +20,14 @@ func InitDB(cfg config.Config) (*gorm.DB, error) {
 	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
 		cfg.DBUser, cfg.DBPassword, cfg.DBHost, cfg.DBPort, cfg.DBName)
 	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
+		Logger: logging.NewGormLogger(slog.Default(), cfg.DBSlowQueryThreshold),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("repository.InitDB: failed to connect to database: %w", err)
 	}
+	if err := RegisterTracingCallbacks(db); err != nil {
+		return nil, fmt.Errorf("repository.InitDB: failed to register tracing callbacks: %w", err)
+	}
 	sqlDB, err := db.DB()
 	if err != nil {
 		return nil, fmt.Errorf("repository.InitDB: failed to get generic database object: %w", err)
@@ -30,20 +35,110 @@ func InitDB(cfg config.Config) (*gorm.DB, error) {
 	sqlDB.SetMaxIdleConns(10)
 	sqlDB.SetMaxOpenConns(100)
 	sqlDB.SetConnMaxLifetime(time.Hour)
+	metrics.RegisterDBStats(sqlDB, cfg.DBName)
 
 	DB = db
-	log.Println("Database connection established")
+	slog.Info("Database connection established")
 	return db, nil
 }
 
 func MigrateDB(db *gorm.DB) error {
-	log.Println("Running database migrations...")
+	slog.Info("Running database migrations...")
 	err := db.AutoMigrate(
 		&domain.Subscription{},
+		&domain.SubscriptionChannel{},
+		&domain.APIKey{},
+		&domain.APIKeyUsage{},
+		&domain.PrivacyRequest{},
+		&domain.WeatherObservation{},
+		&domain.PlaceAlias{},
+		&domain.WebhookDelivery{},
 	)
 	if err != nil {
 		return fmt.Errorf("repository.MigrateDB: failed to run migrations: %w", err)
 	}
-	log.Println("Database migrations completed")
+	if err := migrateSubscriptionChannels(db); err != nil {
+		return fmt.Errorf("repository.MigrateDB: failed to migrate subscription channels: %w", err)
+	}
+	if err := migrateObservationPlaces(db); err != nil {
+		return fmt.Errorf("repository.MigrateDB: failed to key weather observations by place: %w", err)
+	}
+	if err := forgetSubscriptionIPs(db); err != nil {
+		return fmt.Errorf("repository.MigrateDB: failed to replace subscription IP addresses: %w", err)
+	}
+	slog.Info("Database migrations completed")
+	return nil
+}
+
+// migrateSubscriptionChannels moves the single-channel columns subscriptions
+// used to have into subscription_channels and gives every subscription still
+// without a channel the email one. It is a no-op once done.
+func migrateSubscriptionChannels(db *gorm.DB) error {
+	migrator := db.Migrator()
+	if migrator.HasColumn(&domain.Subscription{}, "webhook_url") {
+		err := db.Exec(`INSERT INTO subscription_channels (subscription_id, kind, target, secret, created_at)
+			SELECT id, 'webhook', webhook_url, webhook_secret, created_at FROM subscriptions
+			WHERE channel = 'webhook' AND id NOT IN (SELECT subscription_id FROM subscription_channels)`).Error
+		if err != nil {
+			return err
+		}
+		for _, column := range []string{"channel", "webhook_url", "webhook_secret"} {
+			if err := migrator.DropColumn(&domain.Subscription{}, column); err != nil {
+				return err
+			}
+		}
+	}
+	return db.Exec(`INSERT INTO subscription_channels (subscription_id, kind, created_at)
+		SELECT id, 'email', created_at FROM subscriptions
+		WHERE id NOT IN (SELECT subscription_id FROM subscription_channels)`).Error
+}
+
+// migrateObservationPlaces turns the query weather observations used to be
+// keyed by into place aliases and keeps one row per place and reading. It is
+// a no-op once done.
+func migrateObservationPlaces(db *gorm.DB) error {
+	migrator := db.Migrator()
+	if !migrator.HasColumn(&domain.WeatherObservation{}, "location_query") {
+		return nil
+	}
+	err := db.Exec(`INSERT IGNORE INTO place_aliases (query, place_key, updated_at)
+		SELECT location_query, MAX(place_key), MAX(created_at) FROM weather_observations
+		WHERE location_query <> '' GROUP BY location_query`).Error
+	if err != nil {
+		return err
+	}
+	err = db.Exec(`DELETE o FROM weather_observations o JOIN weather_observations kept
+		ON kept.place_key = o.place_key AND kept.observed_at = o.observed_at AND kept.id < o.id`).Error
+	if err != nil {
+		return err
+	}
+	for _, index := range []string{"idx_observation_reading", "idx_observation_query_date"} {
+		if migrator.HasIndex(&domain.WeatherObservation{}, index) {
+			if err := migrator.DropIndex(&domain.WeatherObservation{}, index); err != nil {
+				return err
+			}
+		}
+	}
+	if err := migrator.DropColumn(&domain.WeatherObservation{}, "location_query"); err != nil {
+		return err
+	}
+	return migrator.CreateIndex(&domain.WeatherObservation{}, "idx_observation_reading")
+}
+
+// forgetSubscriptionIPs replaces the IP addresses subscriptions used to be
+// stored under with the coordinates they resolved to.
+func forgetSubscriptionIPs(db *gorm.DB) error {
+	return db.Exec(`UPDATE subscriptions SET city = CONCAT(location_lat, ',', location_lon), location_kind = 'coordinates'
+		WHERE location_kind = 'ip'`).Error
+}
+
+func PingDB(ctx context.Context, db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return fmt.Errorf("repository.PingDB: failed to get generic database object: %w", err)
+	}
+	if err := sqlDB.PingContext(ctx); err != nil {
+		return fmt.Errorf("repository.PingDB: database is unreachable: %w", err)
+	}
 	return nil
 }
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"weather/project/domain"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ObservationRepository interface {
+	// Save archives a reading, without its description, and points the
+	// reading's query at its place; a reading already archived is ignored.
+	Save(ctx context.Context, obs *domain.WeatherObservation) error
+	// FindByQueryAndDate returns the readings of the place query last
+	// resolved to, ordered by time.
+	FindByQueryAndDate(ctx context.Context, query, localDate string) ([]domain.WeatherObservation, error)
+	// FindLatestByQuery returns the most recent reading archived for the
+	// place of query, or nil if there is none.
+	FindLatestByQuery(ctx context.Context, query string) (*domain.WeatherObservation, error)
+}
+
+type observationRepository struct {
+	db *gorm.DB
+}
+
+func NewObservationRepository(db *gorm.DB) ObservationRepository {
+	return &observationRepository{db: db}
+}
+
+func (r *observationRepository) Save(ctx context.Context, obs *domain.WeatherObservation) error {
+	archived := *obs
+	archived.Conditions.Description = ""
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if obs.LocationQuery != "" {
+			alias := domain.PlaceAlias{Query: obs.LocationQuery, PlaceKey: obs.PlaceKey}
+			err := tx.Clauses(clause.OnConflict{DoUpdates: clause.AssignmentColumns([]string{"place_key", "updated_at"})}).Create(&alias).Error
+			if err != nil {
+				return err
+			}
+		}
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&archived).Error
+	})
+}
+
+func (r *observationRepository) FindByQueryAndDate(ctx context.Context, query, localDate string) ([]domain.WeatherObservation, error) {
+	var observations []domain.WeatherObservation
+	err := r.db.WithContext(ctx).
+		Where("place_key = (?) AND local_date = ?", r.placeOf(query), localDate).
+		Order("observed_at").
+		Find(&observations).Error
+	return observations, err
+}
+
+func (r *observationRepository) FindLatestByQuery(ctx context.Context, query string) (*domain.WeatherObservation, error) {
+	var observations []domain.WeatherObservation
+	err := r.db.WithContext(ctx).
+		Where("place_key = (?)", r.placeOf(query)).
+		Order("observed_at DESC").
+		Limit(1).
+		Find(&observations).Error
+	if err != nil || len(observations) == 0 {
+		return nil, err
+	}
+	return &observations[0], nil
+}
+
+// placeOf is a subquery for the place key query is an alias of.
+func (r *observationRepository) placeOf(query string) *gorm.DB {
+	return r.db.Model(&domain.PlaceAlias{}).Select("place_key").Where("query = ?", query)
+}
@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+	"weather/project/domain"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PrivacyRepository gathers and purges everything stored about an email
+// address. Tables holding personal data must be covered by EraseByEmail.
+type PrivacyRepository interface {
+	CreateRequest(ctx context.Context, req *domain.PrivacyRequest) error
+	FindRequestByToken(ctx context.Context, token string) (*domain.PrivacyRequest, error)
+	// MarkRequestCompleted fails with ErrPrivacyRequestInvalid when the
+	// request was already completed, so one link cannot be used twice.
+	MarkRequestCompleted(ctx context.Context, id uuid.UUID, at time.Time) error
+	FindRequestsByEmail(ctx context.Context, email string) ([]domain.PrivacyRequest, error)
+	// FindSubscriptionsByEmail includes soft-deleted subscriptions.
+	FindSubscriptionsByEmail(ctx context.Context, email string) ([]domain.Subscription, error)
+	FindWebhookDeliveriesByEmail(ctx context.Context, email string) ([]domain.WebhookDelivery, error)
+	EraseByEmail(ctx context.Context, email string) (map[string]int64, error)
+	PurgeSoftDeletedSubscriptions(ctx context.Context, deletedBefore time.Time) (int64, error)
+	PurgeExpiredRequests(ctx context.Context, expiredBefore time.Time) (int64, error)
+}
+
+type privacyRepository struct {
+	db *gorm.DB
+}
+
+func NewPrivacyRepository(db *gorm.DB) PrivacyRepository {
+	return &privacyRepository{db: db}
+}
+
+func (r *privacyRepository) CreateRequest(ctx context.Context, req *domain.PrivacyRequest) error {
+	return r.db.WithContext(ctx).Create(req).Error
+}
+
+func (r *privacyRepository) FindRequestByToken(ctx context.Context, token string) (*domain.PrivacyRequest, error) {
+	var req domain.PrivacyRequest
+	err := r.db.WithContext(ctx).Where("token = ?", token).First(&req).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrPrivacyRequestInvalid
+		}
+		return nil, err
+	}
+	return &req, nil
+}
+
+func (r *privacyRepository) MarkRequestCompleted(ctx context.Context, id uuid.UUID, at time.Time) error {
+	result := r.db.WithContext(ctx).Model(&domain.PrivacyRequest{}).
+		Where("id = ? AND completed_at IS NULL", id).
+		Update("completed_at", at)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrPrivacyRequestInvalid
+	}
+	return nil
+}
+
+func (r *privacyRepository) FindRequestsByEmail(ctx context.Context, email string) ([]domain.PrivacyRequest, error) {
+	var reqs []domain.PrivacyRequest
+	err := r.db.WithContext(ctx).Where("email = ?", email).Order("created_at").Find(&reqs).Error
+	return reqs, err
+}
+
+func (r *privacyRepository) FindSubscriptionsByEmail(ctx context.Context, email string) ([]domain.Subscription, error) {
+	var subs []domain.Subscription
+	err := r.db.WithContext(ctx).Unscoped().Preload("Channels").Where("email = ?", email).Order("created_at").Find(&subs).Error
+	return subs, err
+}
+
+func (r *privacyRepository) FindWebhookDeliveriesByEmail(ctx context.Context, email string) ([]domain.WebhookDelivery, error) {
+	var deliveries []domain.WebhookDelivery
+	err := r.db.WithContext(ctx).
+		Where("subscription_id IN (?)", r.db.Unscoped().Model(&domain.Subscription{}).Select("id").Where("email = ?", email)).
+		Order("created_at").
+		Find(&deliveries).Error
+	return deliveries, err
+}
+
+func (r *privacyRepository) EraseByEmail(ctx context.Context, email string) (map[string]int64, error) {
+	deleted := make(map[string]int64)
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("subscription_id IN (?)", tx.Unscoped().Model(&domain.Subscription{}).Select("id").Where("email = ?", email)).
+			Delete(&domain.WebhookDelivery{})
+		if result.Error != nil {
+			return result.Error
+		}
+		deleted["webhook_deliveries"] = result.RowsAffected
+
+		result = tx.Where("subscription_id IN (?)", tx.Unscoped().Model(&domain.Subscription{}).Select("id").Where("email = ?", email)).
+			Delete(&domain.SubscriptionChannel{})
+		if result.Error != nil {
+			return result.Error
+		}
+		deleted["subscription_channels"] = result.RowsAffected
+
+		result = tx.Unscoped().Where("email = ?", email).Delete(&domain.Subscription{})
+		if result.Error != nil {
+			return result.Error
+		}
+		deleted["subscriptions"] = result.RowsAffected
+
+		result = tx.Where("email = ?", email).Delete(&domain.PrivacyRequest{})
+		if result.Error != nil {
+			return result.Error
+		}
+		deleted["privacy_requests"] = result.RowsAffected
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deleted, nil
+}
+
+func (r *privacyRepository) PurgeSoftDeletedSubscriptions(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	var purged int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		expired := tx.Unscoped().Model(&domain.Subscription{}).Select("id").
+			Where("deleted_at IS NOT NULL AND deleted_at < ?", deletedBefore)
+		if err := tx.Where("subscription_id IN (?)", expired).Delete(&domain.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("subscription_id IN (?)", expired).Delete(&domain.SubscriptionChannel{}).Error; err != nil {
+			return err
+		}
+		result := tx.Unscoped().
+			Where("deleted_at IS NOT NULL AND deleted_at < ?", deletedBefore).
+			Delete(&domain.Subscription{})
+		purged = result.RowsAffected
+		return result.Error
+	})
+	return purged, err
+}
+
+func (r *privacyRepository) PurgeExpiredRequests(ctx context.Context, expiredBefore time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Where("expires_at < ?", expiredBefore).Delete(&domain.PrivacyRequest{})
+	return result.RowsAffected, result.Error
+}
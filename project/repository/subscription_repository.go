@@ -1,20 +1,50 @@
 package repository
 
 import (
+	"context"
 	"errors"
+	"strings"
+	"time"
 	"weather/project/domain"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type SubscriptionRepository interface {
-	Create(sub *domain.Subscription) error
-	FindByEmail(email string) (*domain.Subscription, error)
-	FindByConfirmToken(token string) (*domain.Subscription, error)
-	FindByUnsubscribeToken(token string) (*domain.Subscription, error)
-	Update(sub *domain.Subscription) error
-	Delete(id uuid.UUID) error
+	Create(ctx context.Context, sub *domain.Subscription) error
+	FindByEmail(ctx context.Context, email string) (*domain.Subscription, error)
+	FindByConfirmToken(ctx context.Context, token string) (*domain.Subscription, error)
+	FindByUnsubscribeToken(ctx context.Context, token string) (*domain.Subscription, error)
+	// FindByChat lists a Telegram chat's subscriptions, oldest first.
+	FindByChat(ctx context.Context, chatID int64) ([]domain.Subscription, error)
+	// Update saves the subscription's own columns; its channels are left
+	// as they are.
+	Update(ctx context.Context, sub *domain.Subscription) error
+	// ReplaceChannels saves the subscription together with sub.Channels,
+	// dropping the channels it had before.
+	ReplaceChannels(ctx context.Context, sub *domain.Subscription) error
+	Delete(ctx context.Context, id uuid.UUID) error
+	// DeleteByChat unsubscribes a chat from city, or from every city when
+	// city is empty, and reports how many subscriptions it removed.
+	DeleteByChat(ctx context.Context, chatID int64, city string) (int64, error)
+	FindByID(ctx context.Context, id uuid.UUID) (*domain.Subscription, error)
+	List(ctx context.Context, filter domain.SubscriptionFilter) ([]domain.Subscription, int64, error)
+	// HardDelete removes the row, its channels and webhook deliveries
+	// permanently, including soft-deleted ones.
+	HardDelete(ctx context.Context, id uuid.UUID) error
+	// FindAwaitingUpdate lists confirmed subscriptions of frequency that got
+	// no update since before, or were created before it if they never did.
+	FindAwaitingUpdate(ctx context.Context, frequency domain.SubscriptionFrequency, before time.Time) ([]domain.Subscription, error)
+	// ClaimUpdate reserves sub for one scheduler until the given time. It
+	// reports false when another one holds the claim, or already sent the
+	// update since sub was read.
+	ClaimUpdate(ctx context.Context, sub *domain.Subscription, now, until time.Time) (bool, error)
+	// ReleaseUpdate gives up a claim without recording an update.
+	ReleaseUpdate(ctx context.Context, id uuid.UUID) error
+	// MarkUpdateSent records the update and releases the claim.
+	MarkUpdateSent(ctx context.Context, id uuid.UUID, at time.Time) error
 }
 
 type subscriptionRepository struct {
@@ -25,13 +55,13 @@ func NewSubscriptionRepository(db *gorm.DB) SubscriptionRepository {
 	return &subscriptionRepository{db: db}
 }
 
-func (r *subscriptionRepository) Create(sub *domain.Subscription) error {
-	return r.db.Create(sub).Error
+func (r *subscriptionRepository) Create(ctx context.Context, sub *domain.Subscription) error {
+	return r.db.WithContext(ctx).Create(sub).Error
 }
 
-func (r *subscriptionRepository) FindByEmail(email string) (*domain.Subscription, error) {
+func (r *subscriptionRepository) FindByEmail(ctx context.Context, email string) (*domain.Subscription, error) {
 	var sub domain.Subscription
-	err := r.db.Where("email = ?", email).First(&sub).Error
+	err := r.db.WithContext(ctx).Preload("Channels").Where("email = ?", email).First(&sub).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, domain.ErrSubscriptionNotFound
@@ -41,9 +71,9 @@ func (r *subscriptionRepository) FindByEmail(email string) (*domain.Subscription
 	return &sub, nil
 }
 
-func (r *subscriptionRepository) FindByConfirmToken(token string) (*domain.Subscription, error) {
+func (r *subscriptionRepository) FindByConfirmToken(ctx context.Context, token string) (*domain.Subscription, error) {
 	var sub domain.Subscription
-	err := r.db.Where("confirm_token = ?", token).First(&sub).Error
+	err := r.db.WithContext(ctx).Preload("Channels").Where("confirm_token = ?", token).First(&sub).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, domain.ErrTokenInvalidOrExpired
@@ -53,9 +83,9 @@ func (r *subscriptionRepository) FindByConfirmToken(token string) (*domain.Subsc
 	return &sub, nil
 }
 
-func (r *subscriptionRepository) FindByUnsubscribeToken(token string) (*domain.Subscription, error) {
+func (r *subscriptionRepository) FindByUnsubscribeToken(ctx context.Context, token string) (*domain.Subscription, error) {
 	var sub domain.Subscription
-	err := r.db.Where("unsubscribe_token = ?", token).First(&sub).Error
+	err := r.db.WithContext(ctx).Preload("Channels").Where("unsubscribe_token = ?", token).First(&sub).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, domain.ErrTokenInvalidOrExpired
@@ -65,14 +95,145 @@ func (r *subscriptionRepository) FindByUnsubscribeToken(token string) (*domain.S
 	return &sub, nil
 }
 
-func (r *subscriptionRepository) Update(sub *domain.Subscription) error {
+func (r *subscriptionRepository) FindByChat(ctx context.Context, chatID int64) ([]domain.Subscription, error) {
+	var subs []domain.Subscription
+	err := r.db.WithContext(ctx).Preload("Channels").Where("telegram_chat_id = ?", chatID).Order("created_at").Find(&subs).Error
+	return subs, err
+}
+
+func (r *subscriptionRepository) Update(ctx context.Context, sub *domain.Subscription) error {
+
+	if sub.ID == uuid.Nil {
+		return errors.New("cannot update subscription without ID")
+	}
+	return r.db.WithContext(ctx).Omit(clause.Associations).Save(sub).Error
+}
 
+func (r *subscriptionRepository) ReplaceChannels(ctx context.Context, sub *domain.Subscription) error {
 	if sub.ID == uuid.Nil {
 		return errors.New("cannot update subscription without ID")
 	}
-	return r.db.Save(sub).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Save(sub).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("subscription_id = ?", sub.ID).Delete(&domain.SubscriptionChannel{}).Error; err != nil {
+			return err
+		}
+		for i := range sub.Channels {
+			sub.Channels[i].ID = 0
+			sub.Channels[i].SubscriptionID = sub.ID
+		}
+		return tx.Create(&sub.Channels).Error
+	})
+}
+
+func (r *subscriptionRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Delete(&domain.Subscription{}, "id = ?", id).Error
+}
+
+func (r *subscriptionRepository) DeleteByChat(ctx context.Context, chatID int64, city string) (int64, error) {
+	query := r.db.WithContext(ctx).Where("telegram_chat_id = ?", chatID)
+	if city != "" {
+		query = query.Where("city = ?", city)
+	}
+	result := query.Delete(&domain.Subscription{})
+	return result.RowsAffected, result.Error
 }
 
-func (r *subscriptionRepository) Delete(id uuid.UUID) error {
-	return r.db.Delete(&domain.Subscription{}, "id = ?", id).Error
+func (r *subscriptionRepository) FindByID(ctx context.Context, id uuid.UUID) (*domain.Subscription, error) {
+	var sub domain.Subscription
+	err := r.db.WithContext(ctx).Preload("Channels").Where("id = ?", id).First(&sub).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrSubscriptionNotFound
+		}
+		return nil, err
+	}
+	return &sub, nil
+}
+
+func (r *subscriptionRepository) List(ctx context.Context, filter domain.SubscriptionFilter) ([]domain.Subscription, int64, error) {
+	var total int64
+	if err := r.db.WithContext(ctx).Model(&domain.Subscription{}).Scopes(subscriptionFilterScope(filter)).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var subs []domain.Subscription
+	err := r.db.WithContext(ctx).Preload("Channels").Scopes(subscriptionFilterScope(filter)).
+		Order("created_at DESC").
+		Offset((filter.Page - 1) * filter.PageSize).
+		Limit(filter.PageSize).
+		Find(&subs).Error
+	return subs, total, err
+}
+
+func subscriptionFilterScope(filter domain.SubscriptionFilter) func(*gorm.DB) *gorm.DB {
+	return func(query *gorm.DB) *gorm.DB {
+		if filter.Email != "" {
+			query = query.Where("email LIKE ?", "%"+escapeLike(filter.Email)+"%")
+		}
+		if filter.City != "" {
+			query = query.Where("city LIKE ?", "%"+escapeLike(filter.City)+"%")
+		}
+		if filter.Frequency != "" {
+			query = query.Where("frequency = ?", filter.Frequency)
+		}
+		if filter.Confirmed != nil {
+			query = query.Where("confirmed = ?", *filter.Confirmed)
+		}
+		return query
+	}
+}
+
+func (r *subscriptionRepository) HardDelete(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("subscription_id = ?", id).Delete(&domain.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("subscription_id = ?", id).Delete(&domain.SubscriptionChannel{}).Error; err != nil {
+			return err
+		}
+		result := tx.Unscoped().Delete(&domain.Subscription{}, "id = ?", id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return domain.ErrSubscriptionNotFound
+		}
+		return nil
+	})
+}
+
+func (r *subscriptionRepository) FindAwaitingUpdate(ctx context.Context, frequency domain.SubscriptionFrequency, before time.Time) ([]domain.Subscription, error) {
+	var subs []domain.Subscription
+	err := r.db.WithContext(ctx).Preload("Channels").
+		Where("confirmed = ? AND frequency = ?", true, frequency).
+		Where("COALESCE(last_update_at, created_at) < ?", before).
+		Order("created_at").
+		Find(&subs).Error
+	return subs, err
+}
+
+func (r *subscriptionRepository) ClaimUpdate(ctx context.Context, sub *domain.Subscription, now, until time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&domain.Subscription{}).
+		Where("id = ? AND last_update_at <=> ?", sub.ID, sub.LastUpdateAt).
+		Where("update_claimed_until IS NULL OR update_claimed_until <= ?", now).
+		UpdateColumn("update_claimed_until", until)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *subscriptionRepository) ReleaseUpdate(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Model(&domain.Subscription{}).Where("id = ?", id).UpdateColumn("update_claimed_until", nil).Error
+}
+
+func (r *subscriptionRepository) MarkUpdateSent(ctx context.Context, id uuid.UUID, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&domain.Subscription{}).Where("id = ?", id).
+		UpdateColumns(map[string]any{"last_update_at": at, "update_claimed_until": nil}).Error
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
 }
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const tracerName = "weather/project/repository"
+
+// RegisterTracingCallbacks wraps every GORM operation in a client span. Only
+// the parameterised SQL is recorded, never the bound values.
+func RegisterTracingCallbacks(db *gorm.DB) error {
+	cb := db.Callback()
+	operations := []struct {
+		name          string
+		before, after func(name string, fn func(*gorm.DB)) error
+	}{
+		{"create", cb.Create().Before("gorm:create").Register, cb.Create().After("gorm:create").Register},
+		{"query", cb.Query().Before("gorm:query").Register, cb.Query().After("gorm:query").Register},
+		{"update", cb.Update().Before("gorm:update").Register, cb.Update().After("gorm:update").Register},
+		{"delete", cb.Delete().Before("gorm:delete").Register, cb.Delete().After("gorm:delete").Register},
+		{"row", cb.Row().Before("gorm:row").Register, cb.Row().After("gorm:row").Register},
+		{"raw", cb.Raw().Before("gorm:raw").Register, cb.Raw().After("gorm:raw").Register},
+	}
+
+	for _, op := range operations {
+		if err := op.before("tracing:before_"+op.name, startSpan(op.name)); err != nil {
+			return fmt.Errorf("repository.RegisterTracingCallbacks: %w", err)
+		}
+		if err := op.after("tracing:after_"+op.name, endSpan); err != nil {
+			return fmt.Errorf("repository.RegisterTracingCallbacks: %w", err)
+		}
+	}
+	return nil
+}
+
+func startSpan(operation string) func(*gorm.DB) {
+	return func(tx *gorm.DB) {
+		ctx, _ := otel.Tracer(tracerName).Start(tx.Statement.Context, "gorm."+operation,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				semconv.DBSystemMySQL,
+				semconv.DBOperationName(operation),
+			),
+		)
+		tx.Statement.Context = ctx
+	}
+}
+
+func endSpan(tx *gorm.DB) {
+	span := trace.SpanFromContext(tx.Statement.Context)
+	if !span.IsRecording() {
+		return
+	}
+	defer span.End()
+
+	span.SetAttributes(
+		semconv.DBCollectionName(tx.Statement.Table),
+		semconv.DBQueryText(tx.Statement.SQL.String()),
+	)
+	span.SetAttributes(attribute.Int64("db.rows_affected", tx.Statement.RowsAffected))
+	if tx.Error != nil && !errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+		span.RecordError(tx.Error)
+		span.SetStatus(codes.Error, tx.Error.Error())
+	}
+}
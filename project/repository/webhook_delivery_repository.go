@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"context"
+	"weather/project/domain"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type WebhookDeliveryRepository interface {
+	Create(ctx context.Context, delivery *domain.WebhookDelivery) error
+	// ListBySubscription returns the most recent attempts first.
+	ListBySubscription(ctx context.Context, subscriptionID uuid.UUID, limit int) ([]domain.WebhookDelivery, error)
+}
+
+type webhookDeliveryRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookDeliveryRepository(db *gorm.DB) WebhookDeliveryRepository {
+	return &webhookDeliveryRepository{db: db}
+}
+
+func (r *webhookDeliveryRepository) Create(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	return r.db.WithContext(ctx).Create(delivery).Error
+}
+
+func (r *webhookDeliveryRepository) ListBySubscription(ctx context.Context, subscriptionID uuid.UUID, limit int) ([]domain.WebhookDelivery, error) {
+	var deliveries []domain.WebhookDelivery
+	err := r.db.WithContext(ctx).
+		Where("subscription_id = ?", subscriptionID).
+		Order("created_at DESC, id DESC").
+		Limit(limit).
+		Find(&deliveries).Error
+	return deliveries, err
+}
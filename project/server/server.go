@@ -1,40 +1,184 @@
 package server
 
 import (
-	"log"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"weather/project/apispec"
+	"weather/project/config"
 	"weather/project/handler"
+	"weather/project/metrics"
+	"weather/project/middleware"
+	"weather/project/ratelimit"
+	"weather/project/service"
 
 	"github.com/gin-gonic/gin"
 )
 
-func SetupRouter(
-	weatherHandler *handler.WeatherHandler,
-	subscriptionHandler *handler.SubscriptionHandler,
-) *gin.Engine {
+type RouterDeps struct {
+	WeatherHandler      *handler.WeatherHandler
+	WeatherStream       *handler.WeatherStreamHandler
+	LocationHandler     *handler.LocationHandler
+	AstronomyHandler    *handler.AstronomyHandler
+	SubscriptionHandler *handler.SubscriptionHandler
+	HealthHandler       *handler.HealthHandler
+	APIKeyHandler       *handler.APIKeyHandler
+	APIKeyService       service.APIKeyService
+	SubscriptionAdmin   *handler.SubscriptionAdminHandler
+	PrivacyHandler      *handler.PrivacyHandler
+	RateLimitStore      ratelimit.Store
+}
 
-	router := gin.Default()
+func SetupRouter(cfg config.Config, deps RouterDeps) (*gin.Engine, error) {
 
-	router.Use(gin.Logger())
+	router := gin.New()
 
-	router.Use(gin.Recovery())
+	spec, err := apispec.Load()
+	if err != nil {
+		return nil, fmt.Errorf("server.SetupRouter: %w", err)
+	}
+	specRouter, err := middleware.NewSpecRouter(spec)
+	if err != nil {
+		return nil, fmt.Errorf("server.SetupRouter: %w", err)
+	}
 
-	router.GET("/health", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{"status": "UP", "message": "Weather API is healthy"})
-	})
+	// Without trusted proxies X-Forwarded-For is ignored and ClientIP() is the
+	// TCP peer, so clients cannot dodge per-IP rate limits by spoofing it.
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("server.SetupRouter: invalid trusted proxies: %w", err)
+	}
 
-	router.StaticFile("/swagger.yaml", "./swagger.yaml")
+	// Recovery comes first, like gin's default, so a panic anywhere below is
+	// still answered with a problem document.
+	router.Use(middleware.Recovery())
 
-	apiGroup := router.Group("/api")
-	{
+	router.Use(middleware.RequestID())
+
+	router.Use(middleware.Tracing())
+
+	router.Use(middleware.AccessLog())
+
+	router.Use(middleware.Metrics())
+
+	if cfg.OpenAPIValidateResponses || gin.Mode() == gin.TestMode {
+		router.Use(middleware.ValidateResponses(specRouter))
+	}
+
+	router.Use(middleware.ErrorHandler())
+
+	router.Use(middleware.Language())
+
+	router.NoRoute(middleware.NotFound)
+
+	router.GET("/health", deps.HealthHandler.Live)
+	router.GET("/livez", deps.HealthHandler.Live)
+	router.GET("/readyz", deps.HealthHandler.Ready)
+	router.GET("/metrics", gin.WrapH(metrics.Handler()))
+
+	renderedSpec, err := apispec.Render(spec)
+	if err != nil {
+		return nil, fmt.Errorf("server.SetupRouter: %w", err)
+	}
+	serveSpec := func(c *gin.Context) { c.Data(http.StatusOK, "application/yaml", renderedSpec) }
+	router.GET("/openapi.yaml", serveSpec)
+	router.GET("/swagger.yaml", serveSpec)
+	router.GET("/docs", func(c *gin.Context) { c.Data(http.StatusOK, "text/html; charset=utf-8", apispec.SwaggerUI) })
+
+	// Requests are validated after authentication and rate limiting, so
+	// rejected callers learn nothing about the expected input.
+	var mw apiMiddleware
+	if cfg.OpenAPIValidateRequests {
+		mw.validate = append(mw.validate, middleware.ValidateRequests(specRouter))
+	}
+	if cfg.RateLimitEnabled {
+		mw.ipLimit = append(mw.ipLimit, middleware.RateLimitByIP(deps.RateLimitStore, ratelimit.Limit{Every: cfg.RateLimitIPEvery, Burst: cfg.RateLimitIPBurst}))
+		mw.emailLimit = append(mw.emailLimit, middleware.RateLimitByEmail(deps.RateLimitStore, ratelimit.Limit{Every: cfg.RateLimitEmailEvery, Burst: cfg.RateLimitEmailBurst}))
+	}
+	if cfg.APIKeyAuthEnabled {
+		mw.apiKeyAuth = append(mw.apiKeyAuth, middleware.APIKeyAuth(deps.APIKeyService))
+		mw.apiKeyAuthPerCity = append(mw.apiKeyAuthPerCity, middleware.APIKeyAuthWithCost(deps.APIKeyService, middleware.CostPerCity))
+	}
+
+	for _, version := range apiVersions {
+		version.register(router.Group(version.prefix), deps, mw)
+	}
 
-		apiGroup.GET("/weather", weatherHandler.GetWeather)
+	// The unversioned paths predate /api/v1 and keep serving its handlers
+	// until the sunset date.
+	legacyGroup := router.Group(apispec.LegacyPrefix, middleware.Deprecated(apispec.LegacyPrefix, apispec.CurrentPrefix, cfg.LegacyAPIDeprecatedAt, cfg.LegacyAPISunset))
+	registerV1(legacyGroup, deps, mw)
+
+	adminGroup := router.Group("/admin", append([]gin.HandlerFunc{middleware.AdminAuth(cfg.AdminToken)}, mw.validate...)...)
+	{
+		adminGroup.POST("/api-keys", deps.APIKeyHandler.Create)
+		adminGroup.GET("/api-keys", deps.APIKeyHandler.List)
+		adminGroup.POST("/api-keys/:id/rotate", deps.APIKeyHandler.Rotate)
+		adminGroup.DELETE("/api-keys/:id", deps.APIKeyHandler.Revoke)
 
-		apiGroup.POST("/subscribe", subscriptionHandler.Subscribe)
-		apiGroup.GET("/confirm/:token", subscriptionHandler.ConfirmSubscription)
-		apiGroup.GET("/unsubscribe/:token", subscriptionHandler.Unsubscribe)
+		adminGroup.GET("/subscriptions", deps.SubscriptionAdmin.List)
+		adminGroup.POST("/subscriptions/bulk", deps.SubscriptionAdmin.Bulk)
+		adminGroup.GET("/subscriptions/:id", deps.SubscriptionAdmin.Get)
+		adminGroup.POST("/subscriptions/:id/confirm", deps.SubscriptionAdmin.Confirm)
+		adminGroup.POST("/subscriptions/:id/unsubscribe", deps.SubscriptionAdmin.Unsubscribe)
+		adminGroup.GET("/subscriptions/:id/deliveries", deps.SubscriptionAdmin.Deliveries)
+		adminGroup.DELETE("/subscriptions/:id", deps.SubscriptionAdmin.Delete)
 	}
 
-	log.Println("Router setup complete.")
-	return router
+	slog.Info("Router setup complete.")
+	return router, nil
+}
+
+// apiMiddleware holds the optional per-route middleware shared by all API
+// versions; disabled features leave their slice empty.
+type apiMiddleware struct {
+	validate   []gin.HandlerFunc
+	ipLimit    []gin.HandlerFunc
+	emailLimit []gin.HandlerFunc
+	apiKeyAuth []gin.HandlerFunc
+	// apiKeyAuthPerCity bills batch lookups per requested city.
+	apiKeyAuthPerCity []gin.HandlerFunc
+}
+
+type apiVersion struct {
+	prefix   string
+	register func(group *gin.RouterGroup, deps RouterDeps, mw apiMiddleware)
+}
+
+// apiVersions lists the mounted public API versions. A new version gets its
+// own register function, reusing handlers whose behavior did not change.
+var apiVersions = []apiVersion{
+	{prefix: apispec.CurrentPrefix, register: registerV1},
+}
+
+func registerV1(group *gin.RouterGroup, deps RouterDeps, mw apiMiddleware) {
+	weatherGroup := group.Group("", withMiddleware(mw.apiKeyAuth, mw.validate...)...)
+	weatherGroup.GET("/weather", deps.WeatherHandler.GetWeather)
+	weatherGroup.GET("/weather/history", deps.WeatherHandler.GetWeatherHistory)
+	weatherGroup.GET("/weather/stream", deps.WeatherStream.Stream)
+	weatherGroup.GET("/astronomy", deps.AstronomyHandler.GetAstronomy)
+
+	batchGroup := group.Group("", withMiddleware(mw.apiKeyAuthPerCity, mw.validate...)...)
+	batchGroup.POST("/weather/batch", deps.WeatherHandler.GetWeatherBatch)
+
+	locationGroup := group.Group("/locations", withMiddleware(mw.ipLimit, mw.validate...)...)
+	locationGroup.GET("/search", deps.LocationHandler.Search)
+
+	subscriptionGroup := group.Group("", withMiddleware(mw.ipLimit, mw.validate...)...)
+	subscriptionGroup.POST("/subscribe", withMiddleware(mw.emailLimit, deps.SubscriptionHandler.Subscribe)...)
+	subscriptionGroup.GET("/confirm/:token", deps.SubscriptionHandler.ConfirmSubscription)
+	subscriptionGroup.GET("/unsubscribe/:token", deps.SubscriptionHandler.Unsubscribe)
+
+	privacyGroup := group.Group("/privacy", withMiddleware(mw.ipLimit, mw.validate...)...)
+	privacyGroup.POST("/export", withMiddleware(mw.emailLimit, deps.PrivacyHandler.RequestExport)...)
+	privacyGroup.GET("/export/:token", deps.PrivacyHandler.Export)
+	privacyGroup.POST("/erasure", withMiddleware(mw.emailLimit, deps.PrivacyHandler.RequestErasure)...)
+	privacyGroup.GET("/erasure/:token", deps.PrivacyHandler.ConfirmErasure)
+	privacyGroup.POST("/erasure/:token", deps.PrivacyHandler.Erase)
+	privacyGroup.DELETE("/erasure/:token", deps.PrivacyHandler.Erase)
+}
+
+// withMiddleware copies before appending so handler chains registered for
+// several versions never share a backing array.
+func withMiddleware(mw []gin.HandlerFunc, handlers ...gin.HandlerFunc) []gin.HandlerFunc {
+	return append(append([]gin.HandlerFunc{}, mw...), handlers...)
 }
@@ -0,0 +1,542 @@
+package server_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"weather/project/config"
+	"weather/project/domain"
+	"weather/project/handler"
+	"weather/project/middleware"
+	"weather/project/ratelimit"
+	"weather/project/server"
+	"weather/project/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// The fakes answer the happy path for any input except these, which each
+// route uses for its error case.
+const (
+	unknownCity = "Nowhere"
+	panicCity   = "Panic"
+	takenEmail  = "taken@example.com"
+	validToken  = "valid-token"
+	validAPIKey = "wk_valid"
+	adminToken  = "admin-token"
+)
+
+var (
+	knownID   = uuid.MustParse("3f2b8c1e-7a4d-4e2f-9b61-0c5d8e7f1a2b")
+	unknownID = uuid.MustParse("00000000-0000-0000-0000-000000000404")
+	kyiv      = domain.ResolvedLocation{ProviderID: 1, Name: "Kyiv", Region: "Kyiv City", Country: "Ukraine", Lat: 50.45, Lon: 30.52}
+	createdAt = time.Date(2026, 10, 1, 8, 0, 0, 0, time.UTC)
+)
+
+type fakeWeather struct{}
+
+func (fakeWeather) GetWeather(_ context.Context, location domain.Location, opts domain.WeatherOptions) (*domain.WeatherResponse, error) {
+	switch location.Value {
+	case unknownCity:
+		return nil, domain.ErrCityNotFound
+	case panicCity:
+		panic("weather exploded")
+	}
+	weather := &domain.WeatherResponse{Temperature: 12.5, Humidity: 60, Description: "Sunny", Condition: domain.ConditionClear, Severity: domain.SeverityNone}
+	if opts.AirQuality {
+		weather.AirQuality = &domain.AirQuality{PM25: 8.2, PM10: 12.1, O3: 40, NO2: 9.5, USEPAIndex: 1, USEPACategory: domain.USEPACategory(1)}
+	}
+	if opts.Pollen {
+		weather.Pollen = &domain.Pollen{Birch: 12, Grass: 42, Ragweed: 3}
+	}
+	return weather, nil
+}
+
+func (w fakeWeather) GetWeatherForCities(ctx context.Context, cities []string) []domain.WeatherLookup {
+	lookups := make([]domain.WeatherLookup, len(cities))
+	for i, city := range cities {
+		weather, err := w.GetWeather(ctx, domain.NewCityLocation(city), domain.WeatherOptions{})
+		lookups[i] = domain.WeatherLookup{City: city, Weather: weather, Err: err}
+	}
+	return lookups
+}
+
+func (w fakeWeather) GetCachedWeather(ctx context.Context, location domain.Location) (*domain.WeatherResponse, error) {
+	return w.GetWeather(ctx, location, domain.WeatherOptions{})
+}
+
+func (fakeWeather) GetHistory(_ context.Context, location domain.Location, date string, _ domain.HistorySource) (*domain.WeatherHistory, error) {
+	if location.Value == unknownCity {
+		return nil, domain.ErrHistoryUnavailable
+	}
+	return &domain.WeatherHistory{
+		Date:     date,
+		Source:   domain.HistorySourceProvider,
+		Location: kyiv,
+		Day:      domain.DaySummary{MinTempC: 8, MaxTempC: 15, AvgTempC: 11, AvgHumidity: 70, MaxWindKph: 12, Description: "Sunny", Condition: domain.ConditionClear, Severity: domain.SeverityNone},
+		Hours: []domain.HourlyConditions{{Time: createdAt, Conditions: domain.Conditions{
+			TemperatureC: 11, FeelsLikeC: 10, Humidity: 70, Description: "Sunny", ConditionCode: 1000,
+			Condition: domain.ConditionClear, Severity: domain.SeverityNone, IsDay: true, WindDir: "N",
+		}}},
+	}, nil
+}
+
+type fakeStream struct{}
+
+func (fakeStream) Subscribe(ctx context.Context, location domain.Location) (<-chan *domain.WeatherResponse, error) {
+	weather, err := fakeWeather{}.GetWeather(ctx, location, domain.WeatherOptions{})
+	if err != nil {
+		return nil, err
+	}
+	// One reading and a closed channel end the stream right away.
+	updates := make(chan *domain.WeatherResponse, 1)
+	updates <- weather
+	close(updates)
+	return updates, nil
+}
+
+type fakeLocations struct{}
+
+func (fakeLocations) Search(_ context.Context, query string) ([]domain.ResolvedLocation, error) {
+	if query == unknownCity {
+		return []domain.ResolvedLocation{}, nil
+	}
+	return []domain.ResolvedLocation{kyiv}, nil
+}
+
+func (fakeLocations) Resolve(_ context.Context, location domain.Location) (*domain.ResolvedLocation, error) {
+	if location.Value == unknownCity {
+		return nil, domain.ErrCityNotFound
+	}
+	return &kyiv, nil
+}
+
+type fakeAstronomy struct{}
+
+func (fakeAstronomy) GetAstronomy(_ context.Context, location domain.Location, date string) (*domain.Astronomy, error) {
+	if location.Value == unknownCity {
+		return nil, domain.ErrCityNotFound
+	}
+	sunrise, sunset := createdAt.Add(-time.Hour), createdAt.Add(10*time.Hour)
+	return &domain.Astronomy{Date: date, Source: domain.AstronomySourceProvider, Location: kyiv, Sunrise: &sunrise, Sunset: &sunset, MoonPhase: "Waxing Gibbous", MoonIllumination: 78}, nil
+}
+
+type fakeSubscriptions struct {
+	service.SubscriptionService
+	last domain.SubscriptionInput
+}
+
+func (f *fakeSubscriptions) Subscribe(_ context.Context, input domain.SubscriptionInput) (*domain.Subscription, error) {
+	f.last = input
+	if input.Email == takenEmail {
+		return nil, domain.ErrEmailAlreadySubscribed
+	}
+	channels, err := input.Channels()
+	if err != nil {
+		return nil, err
+	}
+	return &domain.Subscription{Email: &input.Email, City: input.City, Frequency: domain.SubscriptionFrequency(input.Frequency), Channels: channels}, nil
+}
+
+func (f *fakeSubscriptions) ConfirmSubscription(_ context.Context, token string) error {
+	return tokenErr(token, domain.ErrTokenInvalidOrExpired)
+}
+
+func (f *fakeSubscriptions) UnsubscribeByToken(_ context.Context, token string) error {
+	return tokenErr(token, domain.ErrTokenInvalidOrExpired)
+}
+
+func tokenErr(token string, err error) error {
+	if token != validToken {
+		return err
+	}
+	return nil
+}
+
+type fakeAPIKeys struct{}
+
+func (fakeAPIKeys) Create(_ context.Context, input domain.APIKeyInput) (*domain.IssuedAPIKey, error) {
+	return &domain.IssuedAPIKey{APIKey: apiKey(knownID, input.Name), Key: validAPIKey}, nil
+}
+
+func (fakeAPIKeys) List(context.Context) ([]domain.APIKey, error) {
+	return []domain.APIKey{apiKey(knownID, "mobile")}, nil
+}
+
+func (fakeAPIKeys) Rotate(_ context.Context, id uuid.UUID) (*domain.IssuedAPIKey, error) {
+	if id != knownID {
+		return nil, domain.ErrAPIKeyNotFound
+	}
+	return &domain.IssuedAPIKey{APIKey: apiKey(id, "mobile"), Key: validAPIKey}, nil
+}
+
+func (fakeAPIKeys) Revoke(_ context.Context, id uuid.UUID) error {
+	if id != knownID {
+		return domain.ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+func (fakeAPIKeys) Authorize(_ context.Context, rawKey string, _ int) (*domain.APIKey, int, error) {
+	if rawKey != validAPIKey {
+		return nil, 0, domain.ErrAPIKeyInvalid
+	}
+	key := apiKey(knownID, "mobile")
+	return &key, 1, nil
+}
+
+func apiKey(id uuid.UUID, name string) domain.APIKey {
+	return domain.APIKey{ID: id, Name: name, Prefix: "wk_valid", DailyQuota: 1000, CreatedAt: createdAt, UpdatedAt: createdAt}
+}
+
+type fakeAdmin struct{}
+
+func (fakeAdmin) List(_ context.Context, filter domain.SubscriptionFilter) (*domain.SubscriptionPage, error) {
+	filter.Normalize()
+	return &domain.SubscriptionPage{Items: []domain.Subscription{subscription(knownID)}, Total: 1, Page: filter.Page, PageSize: filter.PageSize}, nil
+}
+
+func (fakeAdmin) Get(_ context.Context, id uuid.UUID) (*domain.Subscription, error) {
+	if id != knownID {
+		return nil, domain.ErrSubscriptionNotFound
+	}
+	sub := subscription(id)
+	return &sub, nil
+}
+
+func (a fakeAdmin) Confirm(ctx context.Context, id uuid.UUID) error     { return a.find(id) }
+func (a fakeAdmin) Unsubscribe(ctx context.Context, id uuid.UUID) error { return a.find(id) }
+func (a fakeAdmin) Delete(ctx context.Context, id uuid.UUID) error      { return a.find(id) }
+
+func (fakeAdmin) find(id uuid.UUID) error {
+	if id != knownID {
+		return domain.ErrSubscriptionNotFound
+	}
+	return nil
+}
+
+func (fakeAdmin) Bulk(_ context.Context, input domain.BulkSubscriptionInput) (*domain.BulkSubscriptionResult, error) {
+	return &domain.BulkSubscriptionResult{Succeeded: input.IDs}, nil
+}
+
+func (a fakeAdmin) Deliveries(_ context.Context, id uuid.UUID) ([]domain.WebhookDelivery, error) {
+	if err := a.find(id); err != nil {
+		return nil, err
+	}
+	return []domain.WebhookDelivery{{ID: 1, SubscriptionID: id, EventID: uuid.NewString(), EventType: "weather.update", Attempt: 1, URL: "https://example.com/hook", StatusCode: 200, Succeeded: true, DurationMs: 42, CreatedAt: createdAt}}, nil
+}
+
+func subscription(id uuid.UUID) domain.Subscription {
+	email := "jane@example.com"
+	return domain.Subscription{
+		ID: id, Email: &email, City: "Kyiv", LocationKind: domain.LocationCity, Resolved: kyiv,
+		Frequency: "daily", Language: "en", Confirmed: true, CreatedAt: createdAt, UpdatedAt: createdAt,
+		Channels: []domain.SubscriptionChannel{{Kind: domain.ChannelEmail}},
+	}
+}
+
+type fakePrivacy struct{}
+
+func (fakePrivacy) RequestExport(context.Context, string) error  { return nil }
+func (fakePrivacy) RequestErasure(context.Context, string) error { return nil }
+
+func (fakePrivacy) Export(_ context.Context, token string) (*domain.PersonalDataExport, error) {
+	if err := tokenErr(token, domain.ErrPrivacyRequestInvalid); err != nil {
+		return nil, err
+	}
+	return &domain.PersonalDataExport{Email: "jane@example.com", GeneratedAt: createdAt, Subscriptions: []domain.SubscriptionExport{}, PrivacyRequests: []domain.PrivacyRequest{}}, nil
+}
+
+func (fakePrivacy) PendingErasure(_ context.Context, token string) (*domain.PrivacyRequest, error) {
+	if err := tokenErr(token, domain.ErrPrivacyRequestInvalid); err != nil {
+		return nil, err
+	}
+	return &domain.PrivacyRequest{ID: knownID, Email: "jane@example.com", Kind: domain.PrivacyRequestErasure, ExpiresAt: createdAt.Add(time.Hour), CreatedAt: createdAt}, nil
+}
+
+func (fakePrivacy) Erase(_ context.Context, token string) (*domain.ErasureResult, error) {
+	if err := tokenErr(token, domain.ErrPrivacyRequestInvalid); err != nil {
+		return nil, err
+	}
+	return &domain.ErasureResult{Email: "jane@example.com", Deleted: map[string]int64{"subscriptions": 1}}, nil
+}
+
+type fakeChecker struct{ err string }
+
+func (f fakeChecker) Name() string { return "database" }
+
+func (f fakeChecker) Check(context.Context) domain.ComponentHealth {
+	if f.err != "" {
+		return domain.ComponentHealth{Status: domain.HealthStatusDown, Error: f.err, CheckedAt: createdAt}
+	}
+	return domain.ComponentHealth{Status: domain.HealthStatusUp, CheckedAt: createdAt}
+}
+
+func newRouter(t *testing.T, subscriptions service.SubscriptionService, health service.HealthService) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	cfg := config.Config{
+		AdminToken:              adminToken,
+		APIKeyAuthEnabled:       true,
+		OpenAPIValidateRequests: true,
+		RateLimitEnabled:        true,
+		RateLimitIPEvery:        time.Millisecond,
+		RateLimitIPBurst:        1000,
+		RateLimitEmailEvery:     time.Millisecond,
+		RateLimitEmailBurst:     1000,
+		LegacyAPIDeprecatedAt:   time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC),
+		LegacyAPISunset:         time.Date(2027, 4, 30, 0, 0, 0, 0, time.UTC),
+	}
+	apiKeys := fakeAPIKeys{}
+	router, err := server.SetupRouter(cfg, server.RouterDeps{
+		WeatherHandler:      handler.NewWeatherHandler(fakeWeather{}),
+		WeatherStream:       handler.NewWeatherStreamHandler(fakeStream{}),
+		LocationHandler:     handler.NewLocationHandler(fakeLocations{}),
+		AstronomyHandler:    handler.NewAstronomyHandler(fakeAstronomy{}),
+		SubscriptionHandler: handler.NewSubscriptionHandler(subscriptions),
+		HealthHandler:       handler.NewHealthHandler(health),
+		APIKeyHandler:       handler.NewAPIKeyHandler(apiKeys),
+		APIKeyService:       apiKeys,
+		SubscriptionAdmin:   handler.NewSubscriptionAdminHandler(fakeAdmin{}),
+		PrivacyHandler:      handler.NewPrivacyHandler(fakePrivacy{}),
+		RateLimitStore:      ratelimit.NewMemoryStore(),
+	})
+	if err != nil {
+		t.Fatalf("SetupRouter: %v", err)
+	}
+	return router
+}
+
+type routeCase struct {
+	name        string
+	method      string
+	path        string
+	body        string
+	contentType string
+	// auth is "api-key", "admin" or empty.
+	auth string
+	want int
+}
+
+// TestDocumentedRoutes drives the happy path and one error of every route in
+// the OpenAPI document. In test mode every response is validated against the
+// document, and one that does not match it turns into a 500 internal_error.
+func TestDocumentedRoutes(t *testing.T) {
+	const jsonType = "application/json"
+	known, unknown := knownID.String(), unknownID.String()
+
+	cases := []routeCase{
+		{"weather", "GET", "/api/v1/weather?city=Kyiv", "", "", "api-key", 200},
+		{"weather with air quality and pollen", "GET", "/api/v1/weather?city=Kyiv&include=aqi,pollen", "", "", "api-key", 200},
+		{"weather unknown include", "GET", "/api/v1/weather?city=Kyiv&include=uv", "", "", "api-key", 400},
+		{"weather unknown city", "GET", "/api/v1/weather?city=" + unknownCity, "", "", "api-key", 404},
+		{"weather without key", "GET", "/api/v1/weather?city=Kyiv", "", "", "", 401},
+		{"history", "GET", "/api/v1/weather/history?city=Kyiv&date=2026-10-13", "", "", "api-key", 200},
+		{"history bad date", "GET", "/api/v1/weather/history?city=Kyiv&date=13.10.2026", "", "", "api-key", 400},
+		{"stream", "GET", "/api/v1/weather/stream?city=Kyiv", "", "", "api-key", 200},
+		{"stream unknown city", "GET", "/api/v1/weather/stream?city=" + unknownCity, "", "", "api-key", 404},
+		{"astronomy", "GET", "/api/v1/astronomy?city=Kyiv&date=2026-10-13", "", "", "api-key", 200},
+		{"astronomy unknown city", "GET", "/api/v1/astronomy?city=" + unknownCity, "", "", "api-key", 404},
+		{"batch", "POST", "/api/v1/weather/batch", `{"cities":["Kyiv","` + unknownCity + `"]}`, jsonType, "api-key", 200},
+		{"batch empty", "POST", "/api/v1/weather/batch", `{"cities":[]}`, jsonType, "api-key", 400},
+		{"location search", "GET", "/api/v1/locations/search?q=Kyi", "", "", "", 200},
+		{"location search without query", "GET", "/api/v1/locations/search", "", "", "", 400},
+
+		{"subscribe", "POST", "/api/v1/subscribe", `{"email":"jane@example.com","city":"Kyiv","frequency":"daily"}`, jsonType, "", 200},
+		{"subscribe taken", "POST", "/api/v1/subscribe", `{"email":"` + takenEmail + `","city":"Kyiv","frequency":"daily"}`, jsonType, "", 409},
+		{"subscribe too large", "POST", "/api/v1/subscribe", `{"email":"jane@example.com","city":"` + strings.Repeat("K", 10<<10) + `","frequency":"daily"}`, jsonType, "", 413},
+		{"confirm", "GET", "/api/v1/confirm/" + validToken, "", "", "", 200},
+		{"confirm bad token", "GET", "/api/v1/confirm/bad-token", "", "", "", 404},
+		{"unsubscribe", "GET", "/api/v1/unsubscribe/" + validToken, "", "", "", 200},
+		{"unsubscribe bad token", "GET", "/api/v1/unsubscribe/bad-token", "", "", "", 404},
+
+		{"export request", "POST", "/api/v1/privacy/export", `{"email":"jane@example.com"}`, jsonType, "", 202},
+		{"export request bad email", "POST", "/api/v1/privacy/export", `{"email":"jane"}`, jsonType, "", 400},
+		{"export", "GET", "/api/v1/privacy/export/" + validToken, "", "", "", 200},
+		{"export bad token", "GET", "/api/v1/privacy/export/bad-token", "", "", "", 404},
+		{"erasure request", "POST", "/api/v1/privacy/erasure", `{"email":"jane@example.com"}`, jsonType, "", 202},
+		{"erasure request bad email", "POST", "/api/v1/privacy/erasure", `{}`, jsonType, "", 400},
+		{"erasure page", "GET", "/api/v1/privacy/erasure/" + validToken, "", "", "", 200},
+		{"erasure page bad token", "GET", "/api/v1/privacy/erasure/bad-token", "", "", "", 404},
+		{"erase by form", "POST", "/api/v1/privacy/erasure/" + validToken, "", "", "", 200},
+		{"erase by form bad token", "POST", "/api/v1/privacy/erasure/bad-token", "", "", "", 404},
+		{"erase", "DELETE", "/api/v1/privacy/erasure/" + validToken, "", "", "", 200},
+		{"erase bad token", "DELETE", "/api/v1/privacy/erasure/bad-token", "", "", "", 404},
+
+		{"legacy weather", "GET", "/api/weather?city=Kyiv", "", "", "api-key", 200},
+
+		{"list api keys", "GET", "/admin/api-keys", "", "", "admin", 200},
+		{"list api keys without token", "GET", "/admin/api-keys", "", "", "", 401},
+		{"create api key", "POST", "/admin/api-keys", `{"name":"mobile","daily_quota":1000}`, jsonType, "admin", 201},
+		{"create api key without name", "POST", "/admin/api-keys", `{}`, jsonType, "admin", 400},
+		{"rotate api key", "POST", "/admin/api-keys/" + known + "/rotate", "", "", "admin", 200},
+		{"rotate unknown api key", "POST", "/admin/api-keys/" + unknown + "/rotate", "", "", "admin", 404},
+		{"revoke api key", "DELETE", "/admin/api-keys/" + known, "", "", "admin", 200},
+		{"revoke unknown api key", "DELETE", "/admin/api-keys/" + unknown, "", "", "admin", 404},
+
+		{"list subscriptions", "GET", "/admin/subscriptions?city=Kyiv&confirmed=true&page=1&page_size=10", "", "", "admin", 200},
+		{"list subscriptions bad page", "GET", "/admin/subscriptions?page=0", "", "", "admin", 400},
+		{"bulk", "POST", "/admin/subscriptions/bulk", `{"action":"confirm","ids":["` + known + `"]}`, jsonType, "admin", 200},
+		{"bulk bad action", "POST", "/admin/subscriptions/bulk", `{"action":"zap","ids":["` + known + `"]}`, jsonType, "admin", 400},
+		{"get subscription", "GET", "/admin/subscriptions/" + known, "", "", "admin", 200},
+		{"get unknown subscription", "GET", "/admin/subscriptions/" + unknown, "", "", "admin", 404},
+		{"delete subscription", "DELETE", "/admin/subscriptions/" + known, "", "", "admin", 200},
+		{"delete unknown subscription", "DELETE", "/admin/subscriptions/" + unknown, "", "", "admin", 404},
+		{"confirm subscription", "POST", "/admin/subscriptions/" + known + "/confirm", "", "", "admin", 200},
+		{"confirm unknown subscription", "POST", "/admin/subscriptions/" + unknown + "/confirm", "", "", "admin", 404},
+		{"unsubscribe subscription", "POST", "/admin/subscriptions/" + known + "/unsubscribe", "", "", "admin", 200},
+		{"unsubscribe unknown subscription", "POST", "/admin/subscriptions/" + unknown + "/unsubscribe", "", "", "admin", 404},
+		{"deliveries", "GET", "/admin/subscriptions/" + known + "/deliveries", "", "", "admin", 200},
+		{"deliveries of unknown subscription", "GET", "/admin/subscriptions/" + unknown + "/deliveries", "", "", "admin", 404},
+
+		{"health", "GET", "/health", "", "", "", 200},
+		{"livez", "GET", "/livez", "", "", "", 200},
+		{"readyz", "GET", "/readyz", "", "", "", 200},
+		{"metrics", "GET", "/metrics", "", "", "", 200},
+		{"openapi", "GET", "/openapi.yaml", "", "", "", 200},
+		{"openapi under its old path", "GET", "/swagger.yaml", "", "", "", 200},
+		{"docs", "GET", "/docs", "", "", "", 200},
+		{"undocumented route", "GET", "/nope", "", "", "", 404},
+	}
+
+	router := newRouter(t, &fakeSubscriptions{}, service.NewHealthService(time.Second, fakeChecker{}))
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := serve(router, tc)
+			if w.Code != tc.want {
+				t.Fatalf("%s %s = %d, want %d: %s", tc.method, tc.path, w.Code, tc.want, w.Body.String())
+			}
+		})
+	}
+
+	// A dependency that is down is the documented error of /readyz.
+	down := newRouter(t, &fakeSubscriptions{}, service.NewHealthService(time.Second, fakeChecker{err: "connection refused"}))
+	if w := serve(down, routeCase{method: "GET", path: "/readyz"}); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("readyz with the database down = %d: %s", w.Code, w.Body.String())
+	}
+}
+
+// TestSubscribeFormWithoutOptionalFields is the HTML form of the landing
+// page, which only sends the fields the user filled in.
+func TestSubscribeFormWithoutOptionalFields(t *testing.T) {
+	subscriptions := &fakeSubscriptions{}
+	router := newRouter(t, subscriptions, service.NewHealthService(time.Second))
+
+	form := url.Values{"email": {"jane@example.com"}, "city": {"Kyiv"}, "frequency": {"hourly"}}
+	w := serve(router, routeCase{method: "POST", path: "/api/v1/subscribe", body: form.Encode(), contentType: "application/x-www-form-urlencoded"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+
+	got := subscriptions.last
+	if got.Email != "jane@example.com" || got.City != "Kyiv" || got.Frequency != "hourly" || got.IncludeAirQuality {
+		t.Errorf("input = %+v", got)
+	}
+	channels, err := got.Channels()
+	if err != nil {
+		t.Fatalf("Channels: %v", err)
+	}
+	if len(channels) != 1 || channels[0].Kind != domain.ChannelEmail {
+		t.Errorf("channels = %+v, want email only", channels)
+	}
+}
+
+func TestLegacyAPIDeprecationHeaders(t *testing.T) {
+	router := newRouter(t, &fakeSubscriptions{}, service.NewHealthService(time.Second))
+
+	legacy := serve(router, routeCase{method: "GET", path: "/api/weather?city=Kyiv", auth: "api-key"})
+	if legacy.Code != http.StatusOK {
+		t.Fatalf("legacy status = %d: %s", legacy.Code, legacy.Body.String())
+	}
+	want := map[string]string{
+		"Deprecation": "@" + strconv.FormatInt(time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC).Unix(), 10),
+		"Sunset":      "Fri, 30 Apr 2027 00:00:00 GMT",
+		"Link":        `</api/v1/weather>; rel="successor-version"`,
+	}
+	for header, value := range want {
+		if got := legacy.Header().Get(header); got != value {
+			t.Errorf("legacy %s = %q, want %q", header, got, value)
+		}
+	}
+
+	current := serve(router, routeCase{method: "GET", path: "/api/v1/weather?city=Kyiv", auth: "api-key"})
+	if current.Code != http.StatusOK {
+		t.Fatalf("v1 status = %d: %s", current.Code, current.Body.String())
+	}
+	for header := range want {
+		if got := current.Header().Get(header); got != "" {
+			t.Errorf("v1 %s = %q, want none", header, got)
+		}
+	}
+}
+
+// TestPanicIsAProblem checks that Recovery wraps the whole chain: the panic
+// is answered with a problem document and still gets an access log entry.
+func TestPanicIsAProblem(t *testing.T) {
+	var logs bytes.Buffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&logs, nil)))
+	t.Cleanup(func() { slog.SetDefault(defaultLogger) })
+
+	router := newRouter(t, &fakeSubscriptions{}, service.NewHealthService(time.Second))
+	w := serve(router, routeCase{method: "GET", path: "/api/v1/weather?city=" + panicCity, auth: "api-key"})
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, middleware.ProblemContentType) {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	var problem domain.Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("decoding problem: %v", err)
+	}
+	if problem.Code != "internal_error" || problem.RequestID == "" || problem.RequestID != w.Header().Get(middleware.RequestIDHeader) {
+		t.Errorf("problem = %+v, X-Request-ID = %q", problem, w.Header().Get(middleware.RequestIDHeader))
+	}
+
+	var accessLogged bool
+	for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+		var entry struct {
+			Msg    string `json:"msg"`
+			Status int    `json:"status"`
+		}
+		if json.Unmarshal([]byte(line), &entry) == nil && entry.Msg == "HTTP request" && entry.Status == http.StatusInternalServerError {
+			accessLogged = true
+		}
+	}
+	if !accessLogged {
+		t.Errorf("no access log entry with status 500:\n%s", logs.String())
+	}
+}
+
+func serve(router *gin.Engine, tc routeCase) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+	if tc.contentType != "" {
+		req.Header.Set("Content-Type", tc.contentType)
+	}
+	switch tc.auth {
+	case "api-key":
+		req.Header.Set("X-API-Key", validAPIKey)
+	case "admin":
+		req.Header.Set("Authorization", "Bearer "+adminToken)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(streamRecorder{w}, req)
+	return w
+}
+
+// streamRecorder lets gin's Context.Stream, which watches for the client
+// going away, run against a recorder.
+type streamRecorder struct{ *httptest.ResponseRecorder }
+
+func (streamRecorder) CloseNotify() <-chan bool { return make(chan bool) }
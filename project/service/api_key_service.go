@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+	"weather/project/domain"
+	"weather/project/repository"
+
+	"github.com/google/uuid"
+)
+
+const (
+	apiKeyPrefix      = "wk_"
+	apiKeyBytes       = 32
+	apiKeyPrefixChars = 8
+)
+
+type APIKeyService interface {
+	Create(ctx context.Context, input domain.APIKeyInput) (*domain.IssuedAPIKey, error)
+	List(ctx context.Context) ([]domain.APIKey, error)
+	Rotate(ctx context.Context, id uuid.UUID) (*domain.IssuedAPIKey, error)
+	Revoke(ctx context.Context, id uuid.UUID) error
+	// Authorize validates a raw key and counts cost requests against its
+	// daily quota. It returns the key and the number of requests used today;
+	// a call over the quota is rejected and not counted.
+	Authorize(ctx context.Context, rawKey string, cost int) (*domain.APIKey, int, error)
+}
+
+type apiKeyService struct {
+	repo         repository.APIKeyRepository
+	tokenService TokenService
+	defaultQuota int
+	now          func() time.Time
+}
+
+func NewAPIKeyService(repo repository.APIKeyRepository, tokenService TokenService, defaultQuota int) APIKeyService {
+	return &apiKeyService{
+		repo:         repo,
+		tokenService: tokenService,
+		defaultQuota: defaultQuota,
+		now:          time.Now,
+	}
+}
+
+func hashAPIKey(rawKey string) string {
+	sum := sha256.Sum256([]byte(rawKey))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *apiKeyService) today() string {
+	return s.now().UTC().Format(time.DateOnly)
+}
+
+func (s *apiKeyService) generate() (rawKey, prefix, keyHash string, err error) {
+	token, err := s.tokenService.GenerateToken(apiKeyBytes)
+	if err != nil {
+		return "", "", "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+	rawKey = apiKeyPrefix + token
+	return rawKey, rawKey[:len(apiKeyPrefix)+apiKeyPrefixChars], hashAPIKey(rawKey), nil
+}
+
+func (s *apiKeyService) Create(ctx context.Context, input domain.APIKeyInput) (*domain.IssuedAPIKey, error) {
+	rawKey, prefix, keyHash, err := s.generate()
+	if err != nil {
+		return nil, err
+	}
+
+	quota := input.DailyQuota
+	if quota == 0 {
+		quota = s.defaultQuota
+	}
+	key := &domain.APIKey{
+		Name:       input.Name,
+		Prefix:     prefix,
+		KeyHash:    keyHash,
+		DailyQuota: quota,
+	}
+	if err := s.repo.Create(ctx, key); err != nil {
+		slog.ErrorContext(ctx, "Error creating API key", slog.String("name", input.Name), slog.Any("error", err))
+		return nil, fmt.Errorf("failed to create API key: %w", err)
+	}
+
+	slog.InfoContext(ctx, "API key created", slog.String("api_key_id", key.ID.String()), slog.String("prefix", prefix))
+	return &domain.IssuedAPIKey{APIKey: *key, Key: rawKey}, nil
+}
+
+func (s *apiKeyService) List(ctx context.Context) ([]domain.APIKey, error) {
+	keys, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+	usage, err := s.repo.UsageForDay(ctx, s.today())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load API key usage: %w", err)
+	}
+	for i := range keys {
+		keys[i].UsageToday = usage[keys[i].ID]
+	}
+	return keys, nil
+}
+
+func (s *apiKeyService) Rotate(ctx context.Context, id uuid.UUID) (*domain.IssuedAPIKey, error) {
+	key, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if key.Revoked() {
+		return nil, domain.ErrAPIKeyRevoked
+	}
+
+	rawKey, prefix, keyHash, err := s.generate()
+	if err != nil {
+		return nil, err
+	}
+	key.Prefix = prefix
+	key.KeyHash = keyHash
+	if err := s.repo.Update(ctx, key); err != nil {
+		slog.ErrorContext(ctx, "Error rotating API key", slog.String("api_key_id", id.String()), slog.Any("error", err))
+		return nil, fmt.Errorf("failed to rotate API key: %w", err)
+	}
+
+	slog.InfoContext(ctx, "API key rotated", slog.String("api_key_id", id.String()), slog.String("prefix", prefix))
+	return &domain.IssuedAPIKey{APIKey: *key, Key: rawKey}, nil
+}
+
+func (s *apiKeyService) Revoke(ctx context.Context, id uuid.UUID) error {
+	key, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if key.Revoked() {
+		return nil
+	}
+
+	now := s.now()
+	key.RevokedAt = &now
+	if err := s.repo.Update(ctx, key); err != nil {
+		slog.ErrorContext(ctx, "Error revoking API key", slog.String("api_key_id", id.String()), slog.Any("error", err))
+		return fmt.Errorf("failed to revoke API key: %w", err)
+	}
+
+	slog.InfoContext(ctx, "API key revoked", slog.String("api_key_id", id.String()))
+	return nil
+}
+
+func (s *apiKeyService) Authorize(ctx context.Context, rawKey string, cost int) (*domain.APIKey, int, error) {
+	if rawKey == "" {
+		return nil, 0, domain.ErrAPIKeyInvalid
+	}
+
+	key, err := s.repo.FindByHash(ctx, hashAPIKey(rawKey))
+	if err != nil {
+		if !errors.Is(err, domain.ErrAPIKeyInvalid) {
+			return nil, 0, fmt.Errorf("failed to look up API key: %w", err)
+		}
+		return nil, 0, err
+	}
+	if key.Revoked() {
+		return nil, 0, domain.ErrAPIKeyInvalid
+	}
+
+	used, counted, err := s.repo.IncrementUsage(ctx, key.ID, s.today(), max(cost, 1), key.DailyQuota)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to record API key usage: %w", err)
+	}
+	if !counted {
+		return key, used, domain.ErrAPIKeyQuotaExceeded
+	}
+
+	now := s.now()
+	key.LastUsedAt = &now
+	if err := s.repo.TouchLastUsed(ctx, key.ID, now); err != nil {
+		// Usage was already counted; a stale last-used timestamp is harmless.
+		slog.WarnContext(ctx, "Error updating API key last use", slog.String("api_key_id", key.ID.String()), slog.Any("error", err))
+	}
+
+	return key, used, nil
+}
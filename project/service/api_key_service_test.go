@@ -0,0 +1,202 @@
+package service_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"weather/project/domain"
+	"weather/project/repository"
+	"weather/project/service"
+
+	"github.com/google/uuid"
+)
+
+// fakeAPIKeyRepo keeps keys and today's usage in memory, with the same
+// quota guard as the database.
+type fakeAPIKeyRepo struct {
+	repository.APIKeyRepository
+	mu    sync.Mutex
+	keys  map[uuid.UUID]domain.APIKey
+	usage map[string]int
+}
+
+func newFakeAPIKeyRepo() *fakeAPIKeyRepo {
+	return &fakeAPIKeyRepo{keys: make(map[uuid.UUID]domain.APIKey), usage: make(map[string]int)}
+}
+
+func (r *fakeAPIKeyRepo) Create(_ context.Context, key *domain.APIKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key.ID = uuid.New()
+	r.keys[key.ID] = *key
+	return nil
+}
+
+func (r *fakeAPIKeyRepo) FindByID(_ context.Context, id uuid.UUID) (*domain.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key, ok := r.keys[id]
+	if !ok {
+		return nil, domain.ErrAPIKeyNotFound
+	}
+	return &key, nil
+}
+
+func (r *fakeAPIKeyRepo) FindByHash(_ context.Context, keyHash string) (*domain.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, key := range r.keys {
+		if key.KeyHash == keyHash {
+			return &key, nil
+		}
+	}
+	return nil, domain.ErrAPIKeyInvalid
+}
+
+func (r *fakeAPIKeyRepo) List(context.Context) ([]domain.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	keys := make([]domain.APIKey, 0, len(r.keys))
+	for _, key := range r.keys {
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func (r *fakeAPIKeyRepo) Update(_ context.Context, key *domain.APIKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.keys[key.ID] = *key
+	return nil
+}
+
+func (r *fakeAPIKeyRepo) TouchLastUsed(_ context.Context, id uuid.UUID, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := r.keys[id]
+	key.LastUsedAt = &at
+	r.keys[id] = key
+	return nil
+}
+
+func (r *fakeAPIKeyRepo) IncrementUsage(_ context.Context, id uuid.UUID, day string, n, quota int) (int, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	counter := id.String() + "|" + day
+	if r.usage[counter]+n > quota {
+		return r.usage[counter], false, nil
+	}
+	r.usage[counter] += n
+	return r.usage[counter], true, nil
+}
+
+func (r *fakeAPIKeyRepo) UsageForDay(_ context.Context, day string) (map[uuid.UUID]int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	usage := make(map[uuid.UUID]int)
+	for counter, n := range r.usage {
+		id, counterDay, _ := strings.Cut(counter, "|")
+		if counterDay == day {
+			usage[uuid.MustParse(id)] = n
+		}
+	}
+	return usage, nil
+}
+
+func TestAPIKeyStoredOnlyAsHash(t *testing.T) {
+	repo := newFakeAPIKeyRepo()
+	keys := service.NewAPIKeyService(repo, service.NewTokenService(), 1000)
+
+	issued, err := keys.Create(context.Background(), domain.APIKeyInput{Name: "dashboard"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if !strings.HasPrefix(issued.Key, "wk_") || !strings.HasPrefix(issued.Key, issued.Prefix) || issued.DailyQuota != 1000 {
+		t.Errorf("issued key %q with prefix %q and quota %d", issued.Key, issued.Prefix, issued.DailyQuota)
+	}
+	stored := repo.keys[issued.ID]
+	sum := sha256.Sum256([]byte(issued.Key))
+	if stored.KeyHash != hex.EncodeToString(sum[:]) {
+		t.Errorf("stored hash %q is not the SHA-256 of the key", stored.KeyHash)
+	}
+}
+
+func TestAPIKeyAuthorize(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeAPIKeyRepo()
+	keys := service.NewAPIKeyService(repo, service.NewTokenService(), 1000)
+	issued, err := keys.Create(ctx, domain.APIKeyInput{Name: "batch", DailyQuota: 5})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	steps := []struct {
+		name     string
+		key      string
+		cost     int
+		wantUsed int
+		wantErr  error
+	}{
+		{name: "missing key", key: "", cost: 1, wantErr: domain.ErrAPIKeyInvalid},
+		{name: "unknown key", key: issued.Key + "x", cost: 1, wantErr: domain.ErrAPIKeyInvalid},
+		{name: "single lookup", key: issued.Key, cost: 1, wantUsed: 1},
+		{name: "zero cost counts once", key: issued.Key, cost: 0, wantUsed: 2},
+		{name: "batch of three", key: issued.Key, cost: 3, wantUsed: 5},
+		{name: "over the quota", key: issued.Key, cost: 1, wantUsed: 5, wantErr: domain.ErrAPIKeyQuotaExceeded},
+		{name: "rejected again", key: issued.Key, cost: 2, wantUsed: 5, wantErr: domain.ErrAPIKeyQuotaExceeded},
+	}
+	for _, step := range steps {
+		_, used, err := keys.Authorize(ctx, step.key, step.cost)
+		if !errors.Is(err, step.wantErr) {
+			t.Fatalf("%s: err = %v, want %v", step.name, err, step.wantErr)
+		}
+		if used != step.wantUsed {
+			t.Errorf("%s: used = %d, want %d", step.name, used, step.wantUsed)
+		}
+	}
+
+	// Rejected calls are not counted, so the admin list reports served
+	// requests only.
+	list, err := keys.List(ctx)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(list) != 1 || list[0].UsageToday != 5 || list[0].LastUsedAt == nil {
+		t.Errorf("List = %+v, want usage_today 5 and a last use", list)
+	}
+}
+
+func TestAPIKeyRotateAndRevoke(t *testing.T) {
+	ctx := context.Background()
+	keys := service.NewAPIKeyService(newFakeAPIKeyRepo(), service.NewTokenService(), 1000)
+	issued, err := keys.Create(ctx, domain.APIKeyInput{Name: "mobile"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	rotated, err := keys.Rotate(ctx, issued.ID)
+	if err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	if _, _, err := keys.Authorize(ctx, issued.Key, 1); !errors.Is(err, domain.ErrAPIKeyInvalid) {
+		t.Errorf("old key after rotation: err = %v, want %v", err, domain.ErrAPIKeyInvalid)
+	}
+	if _, _, err := keys.Authorize(ctx, rotated.Key, 1); err != nil {
+		t.Errorf("rotated key: %v", err)
+	}
+
+	if err := keys.Revoke(ctx, issued.ID); err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+	if _, _, err := keys.Authorize(ctx, rotated.Key, 1); !errors.Is(err, domain.ErrAPIKeyInvalid) {
+		t.Errorf("revoked key: err = %v, want %v", err, domain.ErrAPIKeyInvalid)
+	}
+	if _, err := keys.Rotate(ctx, issued.ID); !errors.Is(err, domain.ErrAPIKeyRevoked) {
+		t.Errorf("rotating a revoked key: err = %v, want %v", err, domain.ErrAPIKeyRevoked)
+	}
+}
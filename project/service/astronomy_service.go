@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+	"weather/project/astro"
+	"weather/project/client"
+	"weather/project/domain"
+	"weather/project/repository"
+)
+
+type AstronomyService interface {
+	// GetAstronomy returns sunrise, sunset and moon data for a local day
+	// (YYYY-MM-DD, empty for today). While the provider is unavailable the
+	// data is computed locally for places with known coordinates.
+	GetAstronomy(ctx context.Context, location domain.Location, date string) (*domain.Astronomy, error)
+}
+
+type astronomyService struct {
+	weatherAPIClient *client.WeatherAPIClient
+	archive          repository.ObservationRepository
+	now              func() time.Time
+}
+
+func NewAstronomyService(apiClient *client.WeatherAPIClient, archive repository.ObservationRepository) AstronomyService {
+	return &astronomyService{weatherAPIClient: apiClient, archive: archive, now: time.Now}
+}
+
+func (s *astronomyService) GetAstronomy(ctx context.Context, location domain.Location, date string) (*domain.Astronomy, error) {
+	astronomy, err := s.weatherAPIClient.GetAstronomy(ctx, location, date)
+	if err == nil {
+		return astronomy, nil
+	}
+	if errors.Is(err, domain.ErrCityNotFound) {
+		return nil, err
+	}
+	slog.WarnContext(ctx, "Error fetching astronomy from API client, computing locally", slog.Any("location", location), slog.Any("error", err))
+
+	place, err := s.knownPlace(ctx, location)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to look up archived location", slog.Any("location", location), slog.Any("error", err))
+	}
+	if place == nil {
+		return nil, domain.ErrFailedToFetchWeather
+	}
+	return s.compute(*place, date), nil
+}
+
+// knownPlace finds coordinates without asking the provider: from the
+// location itself, or from the last archived observation there.
+func (s *astronomyService) knownPlace(ctx context.Context, location domain.Location) (*domain.ResolvedLocation, error) {
+	if lat, lon, ok := location.Coordinates(); ok {
+		return &domain.ResolvedLocation{Lat: lat, Lon: lon}, nil
+	}
+	query := domain.ArchiveQuery(location)
+	if query == "" {
+		return nil, nil
+	}
+	observation, err := s.archive.FindLatestByQuery(ctx, query)
+	if err != nil || observation == nil {
+		return nil, err
+	}
+	return &observation.Location, nil
+}
+
+func (s *astronomyService) compute(place domain.ResolvedLocation, date string) *domain.Astronomy {
+	day, err := time.Parse(time.DateOnly, date)
+	if err != nil {
+		day = s.now().UTC()
+	}
+	astronomy := &domain.Astronomy{
+		Date:     day.Format(time.DateOnly),
+		Source:   domain.AstronomySourceComputed,
+		Location: place,
+	}
+	sunrise, sunset, ok := astro.SunTimes(day, place.Lat, place.Lon)
+	if ok {
+		astronomy.Sunrise, astronomy.Sunset = &sunrise, &sunset
+	}
+	// The phase is taken at local solar noon.
+	noon := time.Date(day.Year(), day.Month(), day.Day(), 12, 0, 0, 0, time.UTC).Add(-time.Duration(place.Lon / 15 * float64(time.Hour)))
+	astronomy.MoonPhase, astronomy.MoonIllumination = astro.MoonPhase(noon)
+	return astronomy
+}
@@ -1,15 +1,21 @@
 package service
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"log/slog"
+	"time"
 	"weather/project/config"
 	"weather/project/domain"
+	"weather/project/i18n"
+	"weather/project/logging"
+	"weather/project/metrics"
 )
 
 type EmailService interface {
-	SendConfirmationEmail(subscription *domain.Subscription, token string) error
-	SendWeatherUpdateEmail(subscription *domain.Subscription, weather *domain.WeatherResponse) error
+	Send(ctx context.Context, to, subject, body string) error
+	SendPrivacyRequestEmail(ctx context.Context, req *domain.PrivacyRequest) error
+	Ping(ctx context.Context) error
 }
 
 type emailService struct {
@@ -21,51 +27,80 @@ func NewEmailService(cfg config.Config) EmailService {
 	return &emailService{cfg: cfg}
 }
 
-func (s *emailService) SendConfirmationEmail(subscription *domain.Subscription, token string) error {
-	if subscription == nil {
-		return fmt.Errorf("subscription cannot be nil")
+func (s *emailService) Send(ctx context.Context, to, subject, body string) error {
+	if to == "" {
+		return fmt.Errorf("recipient cannot be empty")
 	}
-	if token == "" {
-		return fmt.Errorf("token cannot be empty")
-	}
-
-	confirmationLink := fmt.Sprintf("%s/api/confirm/%s", s.cfg.AppBaseURL, token)
-
-	subject := "Confirm your Weather API Subscription"
-	body := fmt.Sprintf("Hello %s,\n\nPlease confirm your subscription for weather updates in %s by clicking the link below:\n%s\n\nIf you did not request this, please ignore this email.\n\nThanks,\nThe Weather API Team",
-		subscription.Email, subscription.City, confirmationLink)
-
-	log.Printf("SIMULATING SENDING EMAIL:")
-	log.Printf("To: %s", subscription.Email)
-	log.Printf("From: %s", "noreply@weatherapp.dev") // s.cfg.EmailFrom if configured
-	log.Printf("Subject: %s", subject)
-	log.Printf("Body:\n%s", body)
 
-	log.Printf("Successfully simulated sending confirmation email to %s for city %s.", subscription.Email, subscription.City)
+	// The body is the only copy of the confirm, unsubscribe and privacy
+	// links, so it keeps its tokens in the log.
+	slog.InfoContext(ctx, "SIMULATING SENDING EMAIL",
+		slog.String("to", to),
+		slog.String("from", "noreply@weatherapp.dev"), // s.cfg.EmailFrom if configured
+		slog.String("subject", subject),
+		slog.Any("body", logging.Unredacted(body)),
+	)
 	return nil
 }
 
-func (s *emailService) SendWeatherUpdateEmail(subscription *domain.Subscription, weather *domain.WeatherResponse) error {
-	if subscription == nil || weather == nil {
-		return fmt.Errorf("subscription and weather data cannot be nil")
+func (s *emailService) SendPrivacyRequestEmail(ctx context.Context, req *domain.PrivacyRequest) error {
+	if req == nil || req.Token == "" {
+		return fmt.Errorf("privacy request and token cannot be empty")
 	}
 
-	unsubscribeToken := "some_unsubscribe_token_placeholder" // This should come from subscription.UnsubscribeToken
-	if subscription.UnsubscribeToken != nil {
-		unsubscribeToken = *subscription.UnsubscribeToken
+	// Privacy requests are not tied to a subscription, so the language is
+	// the one the request was made in.
+	lang := i18n.FromContext(ctx)
+	var subject, action string
+	switch req.Kind {
+	case domain.PrivacyRequestExport:
+		subject = lang.T("Download your Weather API data")
+		action = lang.Tf("To download a copy of all data we hold for this address, open:\n%s/api/v1/privacy/export/%s", s.cfg.AppBaseURL, req.Token)
+	case domain.PrivacyRequestErasure:
+		subject = lang.T("Confirm deletion of your Weather API data")
+		action = lang.Tf("To permanently delete all data we hold for this address, open:\n%s/api/v1/privacy/erasure/%s\nThis cannot be undone.", s.cfg.AppBaseURL, req.Token)
+	default:
+		return fmt.Errorf("unsupported privacy request kind %q", req.Kind)
 	}
-	unsubscribeLink := fmt.Sprintf("%s/api/unsubscribe/%s", s.cfg.AppBaseURL, unsubscribeToken)
+	body := lang.Tf("Hello %s,\n\n%s\n\nThe link expires at %s. If you did not request this, please ignore this email.\n\nThanks,\nThe Weather API Team",
+		req.Email, action, req.ExpiresAt.UTC().Format(time.RFC1123))
+
+	return s.Send(ctx, req.Email, subject, body)
+}
+
+// Ping reports whether the mail transport can accept messages. Sending is
+// simulated through the application log for now, so it is always available.
+func (s *emailService) Ping(ctx context.Context) error {
+	return ctx.Err()
+}
 
-	subject := fmt.Sprintf("Weather Update for %s", subscription.City)
-	body := fmt.Sprintf("Hello %s,\n\nHere's your weather update for %s:\nTemperature: %.1f°C\nHumidity: %.0f%%\nDescription: %s\n\nTo stop receiving these updates, click here: %s\n\nThanks,\nThe Weather API Team",
-		subscription.Email, subscription.City, weather.Temperature, weather.Humidity, weather.Description, unsubscribeLink)
+type emailNotifier struct {
+	email EmailService
+}
+
+// NewEmailNotifier delivers rendered messages as plain-text email.
+func NewEmailNotifier(email EmailService) Notifier {
+	return &emailNotifier{email: email}
+}
 
-	log.Printf("SIMULATING SENDING WEATHER UPDATE EMAIL:")
-	log.Printf("To: %s", subscription.Email)
-	log.Printf("From: %s", "noreply@weatherapp.dev")
-	log.Printf("Subject: %s", subject)
-	log.Printf("Body:\n%s", body)
+func (n *emailNotifier) Channel() domain.DeliveryChannel {
+	return domain.ChannelEmail
+}
 
-	log.Printf("Successfully simulated sending weather update to %s for %s.", subscription.Email, subscription.City)
+// Verify accepts every address; the confirmation email is what proves it.
+func (n *emailNotifier) Verify(context.Context, domain.SubscriptionChannel) error {
 	return nil
 }
+
+func (n *emailNotifier) Send(ctx context.Context, channel domain.SubscriptionChannel, msg *Message) error {
+	to := channel.Target
+	if to == "" {
+		to = msg.Subscription.EmailAddress()
+	}
+	if to == "" {
+		return fmt.Errorf("service.emailNotifier.Send: subscription %s has no email address", msg.Subscription.ID)
+	}
+	err := n.email.Send(ctx, to, msg.Subject, msg.Body)
+	metrics.ObserveEmail(string(msg.Kind), err)
+	return err
+}
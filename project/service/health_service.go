@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+	"weather/project/domain"
+)
+
+type HealthChecker interface {
+	Name() string
+	Check(ctx context.Context) domain.ComponentHealth
+}
+
+type HealthService interface {
+	Liveness() domain.HealthReport
+	Readiness(ctx context.Context) domain.HealthReport
+}
+
+type healthService struct {
+	checkers []HealthChecker
+	timeout  time.Duration
+}
+
+func NewHealthService(timeout time.Duration, checkers ...HealthChecker) HealthService {
+	return &healthService{checkers: checkers, timeout: timeout}
+}
+
+func (s *healthService) Liveness() domain.HealthReport {
+	return domain.HealthReport{Status: domain.HealthStatusUp}
+}
+
+func (s *healthService) Readiness(ctx context.Context) domain.HealthReport {
+	report := domain.HealthReport{
+		Status:     domain.HealthStatusUp,
+		Components: make(map[string]domain.ComponentHealth, len(s.checkers)),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, checker := range s.checkers {
+		wg.Add(1)
+		go func(checker HealthChecker) {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, s.timeout)
+			defer cancel()
+			result := checker.Check(checkCtx)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Components[checker.Name()] = result
+			if result.Status != domain.HealthStatusUp {
+				slog.WarnContext(ctx, "Readiness check failed", slog.String("component", checker.Name()), slog.String("error", result.Error))
+				report.Status = domain.HealthStatusDown
+			}
+		}(checker)
+	}
+	wg.Wait()
+
+	return report
+}
+
+type healthCheck struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+// NewHealthCheck adapts a plain ping function into a HealthChecker.
+func NewHealthCheck(name string, check func(ctx context.Context) error) HealthChecker {
+	return &healthCheck{name: name, check: check}
+}
+
+func (c *healthCheck) Name() string {
+	return c.name
+}
+
+func (c *healthCheck) Check(ctx context.Context) domain.ComponentHealth {
+	started := time.Now()
+	err := c.check(ctx)
+	result := domain.ComponentHealth{
+		Status:    domain.HealthStatusUp,
+		LatencyMs: time.Since(started).Milliseconds(),
+		CheckedAt: started,
+	}
+	if err != nil {
+		result.Status = domain.HealthStatusDown
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// failedHealthTTL caps how long a failed result is cached, so readiness
+// recovers soon after the dependency does.
+const failedHealthTTL = 5 * time.Second
+
+type cachedHealthCheck struct {
+	HealthChecker
+	ttl time.Duration
+
+	mu     sync.Mutex
+	last   domain.ComponentHealth
+	expiry time.Time
+	// running is closed when the check in flight finishes; nil when idle.
+	running chan struct{}
+}
+
+// NewCachedHealthCheck remembers the last result of checker for ttl, so that
+// frequent probes don't hammer dependencies that are slow or billed per call.
+// Concurrent probes share a single check.
+func NewCachedHealthCheck(checker HealthChecker, ttl time.Duration) HealthChecker {
+	return &cachedHealthCheck{HealthChecker: checker, ttl: ttl}
+}
+
+func (c *cachedHealthCheck) Check(ctx context.Context) domain.ComponentHealth {
+	c.mu.Lock()
+	if time.Now().Before(c.expiry) {
+		cached := c.last
+		cached.Cached = true
+		c.mu.Unlock()
+		return cached
+	}
+	if running := c.running; running != nil {
+		c.mu.Unlock()
+		select {
+		case <-running:
+		case <-ctx.Done():
+			return domain.ComponentHealth{Status: domain.HealthStatusDown, Error: ctx.Err().Error(), CheckedAt: time.Now()}
+		}
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return c.last
+	}
+	running := make(chan struct{})
+	c.running = running
+	c.mu.Unlock()
+
+	result := c.HealthChecker.Check(ctx)
+
+	ttl := c.ttl
+	if result.Status != domain.HealthStatusUp {
+		ttl = min(ttl, failedHealthTTL)
+	}
+	c.mu.Lock()
+	c.last = result
+	c.expiry = time.Now().Add(ttl)
+	c.running = nil
+	c.mu.Unlock()
+	close(running)
+	return result
+}
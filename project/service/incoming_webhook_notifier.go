@@ -0,0 +1,212 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+	"weather/project/config"
+	"weather/project/domain"
+	"weather/project/i18n"
+)
+
+// Discord embed colours by severity.
+const (
+	discordColorCalm   = 0x3498db
+	discordColorWarn   = 0xe67e22
+	discordColorSevere = 0xe74c3c
+)
+
+// incomingWebhookNotifier posts to the incoming webhooks of chat services,
+// which take a fixed JSON shape and answer with a bare 2xx. Their URLs are
+// the only credential, so they are kept out of errors and logs.
+type incomingWebhookNotifier struct {
+	channel    domain.DeliveryChannel
+	httpClient *http.Client
+	// test and update build the payloads of the subscribe-time test message
+	// and of a weather update.
+	test   func(text string) any
+	update func(msg *Message) any
+}
+
+// NewSlackNotifier posts weather updates as Block Kit messages.
+func NewSlackNotifier(cfg config.Config) Notifier {
+	return &incomingWebhookNotifier{
+		channel:    domain.ChannelSlack,
+		httpClient: newWebhookHTTPClient(cfg),
+		test:       func(text string) any { return map[string]string{"text": text} },
+		update:     slackWeatherUpdate,
+	}
+}
+
+// NewDiscordNotifier posts weather updates as embeds.
+func NewDiscordNotifier(cfg config.Config) Notifier {
+	return &incomingWebhookNotifier{
+		channel:    domain.ChannelDiscord,
+		httpClient: newWebhookHTTPClient(cfg),
+		test:       func(text string) any { return map[string]string{"content": text} },
+		update:     discordWeatherUpdate,
+	}
+}
+
+func (n *incomingWebhookNotifier) Channel() domain.DeliveryChannel {
+	return n.channel
+}
+
+// Verify posts a test message in the language of the request, so the
+// subscriber sees right away that the channel works.
+func (n *incomingWebhookNotifier) Verify(ctx context.Context, channel domain.SubscriptionChannel) error {
+	lang := i18n.FromContext(ctx)
+	if err := n.post(ctx, channel, n.test(lang.T("Weather API will post weather updates to this channel."))); err != nil {
+		slog.WarnContext(ctx, "Incoming webhook rejected the test message", slog.String("channel", string(n.channel)), slog.Any("error", err))
+		return fmt.Errorf("service.Verify: %w: %v", domain.ErrChatWebhookRejected, err)
+	}
+	return nil
+}
+
+func (n *incomingWebhookNotifier) Send(ctx context.Context, channel domain.SubscriptionChannel, msg *Message) error {
+	if msg.Kind != MessageWeatherUpdate {
+		return fmt.Errorf("service.Send: %s does not carry %s messages", n.channel, msg.Kind)
+	}
+	if err := n.post(ctx, channel, n.update(msg)); err != nil {
+		return fmt.Errorf("service.Send: %w", err)
+	}
+	return nil
+}
+
+func (n *incomingWebhookNotifier) post(ctx context.Context, channel domain.SubscriptionChannel, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, channel.Target, bytes.NewReader(body))
+	if err != nil {
+		return errors.New("invalid webhook URL")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", webhookUserAgent)
+
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		response, _ := io.ReadAll(io.LimitReader(resp.Body, maxWebhookResponseBytes))
+		return fmt.Errorf("%s responded with status %d: %s", n.channel, resp.StatusCode, bytes.TrimSpace(response))
+	}
+	return nil
+}
+
+type weatherFact struct {
+	name, value string
+}
+
+// weatherFacts lists what both chat services show, labelled in the
+// subscriber's language.
+func weatherFacts(msg *Message) []weatherFact {
+	lang, weather := msg.Lang, msg.Weather
+	facts := []weatherFact{
+		{lang.T("Temperature"), fmt.Sprintf("%.1f°C", weather.Temperature)},
+		{lang.T("Humidity"), fmt.Sprintf("%.0f%%", weather.Humidity)},
+		{lang.T("Conditions"), weather.Description},
+	}
+	if f := weather.Forecast; f != nil {
+		facts = append(facts,
+			weatherFact{lang.T("Today"), lang.Tf("%.0f to %.0f°C, %s", f.MinTempC, f.MaxTempC, f.Description)},
+			weatherFact{lang.T("Chance of rain"), fmt.Sprintf("%d%%", f.ChanceOfRain)},
+		)
+	}
+	if aq := weather.AirQuality; aq != nil && msg.Subscription.IncludeAirQuality {
+		facts = append(facts, weatherFact{lang.T("Air quality"), lang.Tf("%s (US EPA index %d)", lang.T(aq.USEPACategory), aq.USEPAIndex)})
+	}
+	if pollen := weather.Pollen; pollen != nil && msg.Subscription.IncludePollen {
+		highest := pollen.Highest()
+		facts = append(facts, weatherFact{lang.T("Pollen"), lang.Tf("%s, %.0f grains/m³", lang.T(highest.Plant), highest.GrainsPerM3)})
+	}
+	return facts
+}
+
+func slackWeatherUpdate(msg *Message) any {
+	type text struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	type block struct {
+		Type     string `json:"type"`
+		Text     *text  `json:"text,omitempty"`
+		Fields   []text `json:"fields,omitempty"`
+		Elements []text `json:"elements,omitempty"`
+	}
+
+	facts := weatherFacts(msg)
+	fields := make([]text, len(facts))
+	for i, fact := range facts {
+		fields[i] = text{Type: "mrkdwn", Text: fmt.Sprintf("*%s*\n%s", fact.name, fact.value)}
+	}
+	blocks := []block{
+		{Type: "header", Text: &text{Type: "plain_text", Text: msg.Subject}},
+		{Type: "section", Fields: fields},
+	}
+	if msg.UnsubscribeURL != "" {
+		blocks = append(blocks, block{Type: "context", Elements: []text{
+			{Type: "mrkdwn", Text: fmt.Sprintf("<%s|%s>", msg.UnsubscribeURL, msg.Lang.T("Unsubscribe"))},
+		}})
+	}
+	// text is what notifications and clients without Block Kit show.
+	return map[string]any{"text": msg.Subject, "blocks": blocks}
+}
+
+func discordWeatherUpdate(msg *Message) any {
+	type field struct {
+		Name   string `json:"name"`
+		Value  string `json:"value"`
+		Inline bool   `json:"inline"`
+	}
+	type footer struct {
+		Text string `json:"text"`
+	}
+	type embed struct {
+		Title       string  `json:"title"`
+		Description string  `json:"description,omitempty"`
+		Color       int     `json:"color"`
+		Fields      []field `json:"fields"`
+		Footer      footer  `json:"footer"`
+		Timestamp   string  `json:"timestamp"`
+	}
+
+	facts := weatherFacts(msg)
+	fields := make([]field, len(facts))
+	for i, fact := range facts {
+		fields[i] = field{Name: fact.name, Value: fact.value, Inline: true}
+	}
+	color := discordColorCalm
+	switch {
+	case msg.Weather.Severity >= domain.SeveritySevere:
+		color = discordColorSevere
+	case msg.Weather.Severity >= domain.SeverityModerate:
+		color = discordColorWarn
+	}
+	e := embed{
+		Title:     msg.Subject,
+		Color:     color,
+		Fields:    fields,
+		Footer:    footer{Text: "Weather API"},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	}
+	if msg.UnsubscribeURL != "" {
+		e.Description = fmt.Sprintf("[%s](%s)", msg.Lang.T("Unsubscribe"), msg.UnsubscribeURL)
+	}
+	return map[string]any{"embeds": []embed{e}}
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"weather/project/client"
+	"weather/project/domain"
+)
+
+type LocationService interface {
+	// Search returns candidate places for autocomplete, best match first.
+	Search(ctx context.Context, query string) ([]domain.ResolvedLocation, error)
+	// Resolve canonicalizes a location, returning domain.ErrCityNotFound for
+	// places the provider does not know.
+	Resolve(ctx context.Context, location domain.Location) (*domain.ResolvedLocation, error)
+}
+
+type locationService struct {
+	weatherAPIClient *client.WeatherAPIClient
+}
+
+func NewLocationService(apiClient *client.WeatherAPIClient) LocationService {
+	return &locationService{weatherAPIClient: apiClient}
+}
+
+func (s *locationService) Search(ctx context.Context, query string) ([]domain.ResolvedLocation, error) {
+	matches, err := s.weatherAPIClient.SearchLocations(ctx, query)
+	if err != nil {
+		slog.WarnContext(ctx, "Location search failed", slog.String("query", query), slog.Any("error", err))
+		return nil, domain.ErrFailedToFetchWeather
+	}
+	if matches == nil {
+		matches = []domain.ResolvedLocation{}
+	}
+	return matches, nil
+}
+
+func (s *locationService) Resolve(ctx context.Context, location domain.Location) (*domain.ResolvedLocation, error) {
+	if location.Kind != domain.LocationCity {
+		// Only city names are ambiguous enough to need the search endpoint.
+		resolved, err := s.weatherAPIClient.LookupLocation(ctx, location)
+		if err != nil {
+			if errors.Is(err, domain.ErrCityNotFound) {
+				return nil, domain.ErrCityNotFound
+			}
+			slog.WarnContext(ctx, "Location lookup failed", slog.Any("location", location), slog.Any("error", err))
+			return nil, fmt.Errorf("%w: %w", domain.ErrFailedToFetchWeather, err)
+		}
+		return resolved, nil
+	}
+
+	matches, err := s.weatherAPIClient.SearchLocations(ctx, location.Value)
+	if err != nil {
+		slog.WarnContext(ctx, "Location search failed", slog.Any("location", location), slog.Any("error", err))
+		return nil, fmt.Errorf("%w: %w", domain.ErrFailedToFetchWeather, err)
+	}
+	if len(matches) == 0 {
+		slog.InfoContext(ctx, "City could not be resolved", slog.Any("location", location))
+		return nil, domain.ErrCityNotFound
+	}
+	return &matches[0], nil
+}
@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"weather/project/config"
+	"weather/project/domain"
+	"weather/project/i18n"
+)
+
+// Notifier delivers messages over one transport. Messages are rendered
+// before they reach a notifier, so every transport shares the same wording
+// and translations.
+type Notifier interface {
+	Channel() domain.DeliveryChannel
+	// Verify runs at subscribe time and checks that the destination is
+	// willing to receive messages.
+	Verify(ctx context.Context, channel domain.SubscriptionChannel) error
+	Send(ctx context.Context, channel domain.SubscriptionChannel, msg *Message) error
+}
+
+type MessageKind string
+
+const (
+	MessageConfirmation  MessageKind = "confirmation"
+	MessageWeatherUpdate MessageKind = "weather_update"
+)
+
+// Message is rendered once and handed to each channel of a subscription.
+// Email sends Subject and Body; chat transports send Subject and Summary;
+// structured ones may build their own payload from Weather and the links.
+type Message struct {
+	Kind         MessageKind
+	Lang         i18n.Lang
+	Subscription *domain.Subscription
+	Subject      string
+	Body         string
+	// Summary is the weather part of Body, without greeting or links.
+	Summary        string
+	Weather        *domain.WeatherResponse
+	ConfirmURL     string
+	UnsubscribeURL string
+}
+
+type NotificationService interface {
+	// Verify checks a channel with its notifier; channels without one are
+	// rejected as unavailable.
+	Verify(ctx context.Context, channel domain.SubscriptionChannel) error
+	// SendConfirmation always goes by email, since the address is what a
+	// confirmation proves.
+	SendConfirmation(ctx context.Context, subscription *domain.Subscription, token string) error
+	// SendWeatherUpdate sends to every channel of the subscription. A failing
+	// channel does not stop the others; their errors are joined. Weather
+	// should be fetched with the subscriber's language in ctx, see
+	// i18n.WithLang, so the description matches the rest of the message.
+	SendWeatherUpdate(ctx context.Context, subscription *domain.Subscription, weather *domain.WeatherResponse) error
+}
+
+type notificationService struct {
+	cfg       config.Config
+	notifiers map[domain.DeliveryChannel]Notifier
+}
+
+// NewNotificationService registers one notifier per channel; a later
+// notifier for the same channel replaces an earlier one.
+func NewNotificationService(cfg config.Config, notifiers ...Notifier) NotificationService {
+	registry := make(map[domain.DeliveryChannel]Notifier, len(notifiers))
+	for _, n := range notifiers {
+		registry[n.Channel()] = n
+	}
+	return &notificationService{cfg: cfg, notifiers: registry}
+}
+
+func (s *notificationService) Verify(ctx context.Context, channel domain.SubscriptionChannel) error {
+	notifier, ok := s.notifiers[channel.Kind]
+	if !ok {
+		return domain.NewFieldError("channels", "is not available on this server")
+	}
+	return notifier.Verify(ctx, channel)
+}
+
+func (s *notificationService) SendConfirmation(ctx context.Context, subscription *domain.Subscription, token string) error {
+	if subscription == nil || token == "" {
+		return fmt.Errorf("subscription and token cannot be empty")
+	}
+	notifier, ok := s.notifiers[domain.ChannelEmail]
+	if !ok {
+		return fmt.Errorf("service.SendConfirmation: no email notifier registered")
+	}
+
+	msg := s.renderConfirmation(subscription, token)
+	if err := notifier.Send(ctx, domain.SubscriptionChannel{Kind: domain.ChannelEmail}, msg); err != nil {
+		return fmt.Errorf("service.SendConfirmation: %w", err)
+	}
+	slog.InfoContext(ctx, "Confirmation sent", slog.String("email", subscription.EmailAddress()), slog.String("city", subscription.City))
+	return nil
+}
+
+func (s *notificationService) SendWeatherUpdate(ctx context.Context, subscription *domain.Subscription, weather *domain.WeatherResponse) error {
+	if subscription == nil || weather == nil {
+		return fmt.Errorf("subscription and weather data cannot be nil")
+	}
+
+	msg := s.renderWeatherUpdate(subscription, weather)
+	var errs []error
+	for _, channel := range subscription.Channels {
+		notifier, ok := s.notifiers[channel.Kind]
+		if !ok {
+			errs = append(errs, fmt.Errorf("no notifier registered for channel %q", channel.Kind))
+			continue
+		}
+		if err := notifier.Send(ctx, channel, msg); err != nil {
+			slog.ErrorContext(ctx, "Failed to send weather update",
+				slog.String("subscription_id", subscription.ID.String()),
+				slog.String("channel", string(channel.Kind)),
+				slog.Any("error", err))
+			errs = append(errs, fmt.Errorf("%s: %w", channel.Kind, err))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("service.SendWeatherUpdate: %w", err)
+	}
+	return nil
+}
+
+func (s *notificationService) renderConfirmation(subscription *domain.Subscription, token string) *Message {
+	lang := subscriberLang(subscription)
+	msg := &Message{
+		Kind:         MessageConfirmation,
+		Lang:         lang,
+		Subscription: subscription,
+		ConfirmURL:   fmt.Sprintf("%s/api/v1/confirm/%s", s.cfg.AppBaseURL, token),
+	}
+	msg.Subject = lang.T("Confirm your Weather API Subscription")
+	msg.Body = lang.Tf("Hello %s,\n\nPlease confirm your subscription for weather updates in %s by clicking the link below:\n%s\n\nIf you did not request this, please ignore this email.\n\nThanks,\nThe Weather API Team",
+		subscription.EmailAddress(), subscription.City, msg.ConfirmURL)
+	return msg
+}
+
+// renderWeatherUpdate includes air quality and pollen when the subscriber
+// asked for them and the forecast when there is one; weather should be fetched with
+// subscription.WeatherOptions().
+func (s *notificationService) renderWeatherUpdate(subscription *domain.Subscription, weather *domain.WeatherResponse) *Message {
+	lang := subscriberLang(subscription)
+	msg := &Message{
+		Kind:         MessageWeatherUpdate,
+		Lang:         lang,
+		Subscription: subscription,
+		Weather:      weather,
+	}
+	if subscription.UnsubscribeToken != nil {
+		msg.UnsubscribeURL = fmt.Sprintf("%s/api/v1/unsubscribe/%s", s.cfg.AppBaseURL, *subscription.UnsubscribeToken)
+	}
+
+	var airQuality string
+	if subscription.IncludeAirQuality && weather.AirQuality != nil {
+		aq := weather.AirQuality
+		airQuality = lang.Tf("\nAir quality: %s (US EPA index %d)\nPM2.5: %.1f µg/m³\nPM10: %.1f µg/m³\nO3: %.1f µg/m³\nNO2: %.1f µg/m³\n",
+			lang.T(aq.USEPACategory), aq.USEPAIndex, aq.PM25, aq.PM10, aq.O3, aq.NO2)
+	}
+	var pollen string
+	if subscription.IncludePollen && weather.Pollen != nil {
+		pollen = lang.T("\nPollen (grains/m³):\n")
+		for _, plant := range weather.Pollen.Plants() {
+			pollen += fmt.Sprintf("%s: %.0f\n", lang.T(plant.Plant), plant.GrainsPerM3)
+		}
+	}
+
+	msg.Subject = lang.Tf("Weather Update for %s: %s", subscription.City, lang.T(weather.Condition.Label()))
+	if weather.Severity >= domain.SeveritySevere {
+		msg.Subject = lang.Tf("Severe weather in %s: %s", subscription.City, lang.T(weather.Condition.Label()))
+	}
+	var forecast string
+	if f := weather.Forecast; f != nil {
+		forecast = lang.Tf("Today: %.0f to %.0f°C, %s, %d%% chance of rain\n", f.MinTempC, f.MaxTempC, f.Description, f.ChanceOfRain)
+	}
+
+	msg.Summary = lang.Tf("Temperature: %.1f°C\nHumidity: %.0f%%\nDescription: %s\n", weather.Temperature, weather.Humidity, weather.Description) + forecast + airQuality + pollen
+	msg.Body = lang.Tf("Hello %s,\n\nHere's your weather update for %s:\n%s\nTo stop receiving these updates, click here: %s\n\nThanks,\nThe Weather API Team",
+		subscription.EmailAddress(), subscription.City, msg.Summary, msg.UnsubscribeURL)
+	return msg
+}
+
+func subscriberLang(subscription *domain.Subscription) i18n.Lang {
+	if lang, ok := i18n.Parse(subscription.Language); ok {
+		return lang
+	}
+	return i18n.Default
+}
@@ -0,0 +1,225 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+	"weather/project/domain"
+	"weather/project/repository"
+)
+
+type PrivacyService interface {
+	// RequestExport and RequestErasure email a verification link to the
+	// address. They succeed whether or not any data is held for it, so the
+	// endpoints cannot be used to probe for subscribers.
+	RequestExport(ctx context.Context, email string) error
+	RequestErasure(ctx context.Context, email string) error
+	Export(ctx context.Context, token string) (*domain.PersonalDataExport, error)
+	// PendingErasure checks an erasure token without acting on it, so the
+	// emailed link can ask for confirmation first.
+	PendingErasure(ctx context.Context, token string) (*domain.PrivacyRequest, error)
+	Erase(ctx context.Context, token string) (*domain.ErasureResult, error)
+}
+
+type privacyService struct {
+	repo         repository.PrivacyRepository
+	tokenService TokenService
+	emailService EmailService
+	tokenTTL     time.Duration
+	now          func() time.Time
+}
+
+func NewPrivacyService(
+	repo repository.PrivacyRepository,
+	tokenService TokenService,
+	emailService EmailService,
+	tokenTTL time.Duration,
+) PrivacyService {
+	return &privacyService{
+		repo:         repo,
+		tokenService: tokenService,
+		emailService: emailService,
+		tokenTTL:     tokenTTL,
+		now:          time.Now,
+	}
+}
+
+func (s *privacyService) RequestExport(ctx context.Context, email string) error {
+	return s.createRequest(ctx, email, domain.PrivacyRequestExport)
+}
+
+func (s *privacyService) RequestErasure(ctx context.Context, email string) error {
+	return s.createRequest(ctx, email, domain.PrivacyRequestErasure)
+}
+
+func (s *privacyService) createRequest(ctx context.Context, email string, kind domain.PrivacyRequestKind) error {
+	token, err := s.tokenService.GenerateToken(32)
+	if err != nil {
+		return fmt.Errorf("failed to generate privacy request token: %w", err)
+	}
+
+	req := &domain.PrivacyRequest{
+		Email:     strings.TrimSpace(email),
+		Kind:      kind,
+		Token:     token,
+		ExpiresAt: s.now().Add(s.tokenTTL),
+	}
+	if err := s.repo.CreateRequest(ctx, req); err != nil {
+		slog.ErrorContext(ctx, "Error creating privacy request", slog.String("email", req.Email), slog.Any("error", err))
+		return fmt.Errorf("failed to create privacy request: %w", err)
+	}
+
+	go func(ctx context.Context) {
+		if err := s.emailService.SendPrivacyRequestEmail(ctx, req); err != nil {
+			slog.ErrorContext(ctx, "Async SendPrivacyRequestEmail: failed to send email", slog.String("email", req.Email), slog.Any("error", err))
+		}
+	}(context.WithoutCancel(ctx))
+
+	slog.InfoContext(ctx, "Privacy request created", slog.String("kind", string(kind)), slog.String("email", req.Email))
+	return nil
+}
+
+func (s *privacyService) verify(ctx context.Context, token string, kind domain.PrivacyRequestKind) (*domain.PrivacyRequest, error) {
+	if token == "" {
+		return nil, domain.ErrPrivacyRequestInvalid
+	}
+	req, err := s.repo.FindRequestByToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	// Each link works once: an export is marked completed when downloaded,
+	// an erasure removes the request along with the rest of the data.
+	if req.Kind != kind || req.CompletedAt != nil || s.now().After(req.ExpiresAt) {
+		return nil, domain.ErrPrivacyRequestInvalid
+	}
+	return req, nil
+}
+
+func (s *privacyService) Export(ctx context.Context, token string) (*domain.PersonalDataExport, error) {
+	req, err := s.verify(ctx, token, domain.PrivacyRequestExport)
+	if err != nil {
+		return nil, err
+	}
+
+	subs, err := s.repo.FindSubscriptionsByEmail(ctx, req.Email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load subscriptions for export: %w", err)
+	}
+	if err := s.repo.MarkRequestCompleted(ctx, req.ID, s.now()); err != nil {
+		if errors.Is(err, domain.ErrPrivacyRequestInvalid) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to mark privacy request completed: %w", err)
+	}
+	reqs, err := s.repo.FindRequestsByEmail(ctx, req.Email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load privacy requests for export: %w", err)
+	}
+	deliveries, err := s.repo.FindWebhookDeliveriesByEmail(ctx, req.Email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load webhook deliveries for export: %w", err)
+	}
+
+	export := &domain.PersonalDataExport{
+		Email:             req.Email,
+		GeneratedAt:       s.now().UTC(),
+		Subscriptions:     make([]domain.SubscriptionExport, 0, len(subs)),
+		PrivacyRequests:   reqs,
+		WebhookDeliveries: deliveries,
+	}
+	for _, sub := range subs {
+		record := domain.SubscriptionExport{
+			ID:                sub.ID,
+			City:              sub.City,
+			LocationKind:      sub.Location().Kind,
+			Location:          sub.Resolved,
+			Frequency:         sub.Frequency,
+			IncludeAirQuality: sub.IncludeAirQuality,
+			IncludePollen:     sub.IncludePollen,
+			Language:          sub.Language,
+			Channels:          sub.Channels,
+			Confirmed:         sub.Confirmed,
+			CreatedAt:         sub.CreatedAt,
+			UpdatedAt:         sub.UpdatedAt,
+		}
+		if sub.DeletedAt.Valid {
+			record.UnsubscribedAt = &sub.DeletedAt.Time
+		}
+		export.Subscriptions = append(export.Subscriptions, record)
+	}
+
+	slog.InfoContext(ctx, "Personal data exported", slog.String("email", req.Email), slog.Int("subscriptions", len(subs)))
+	return export, nil
+}
+
+func (s *privacyService) PendingErasure(ctx context.Context, token string) (*domain.PrivacyRequest, error) {
+	return s.verify(ctx, token, domain.PrivacyRequestErasure)
+}
+
+func (s *privacyService) Erase(ctx context.Context, token string) (*domain.ErasureResult, error) {
+	req, err := s.verify(ctx, token, domain.PrivacyRequestErasure)
+	if err != nil {
+		return nil, err
+	}
+
+	deleted, err := s.repo.EraseByEmail(ctx, req.Email)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error erasing personal data", slog.String("email", req.Email), slog.Any("error", err))
+		return nil, fmt.Errorf("failed to erase personal data: %w", err)
+	}
+
+	slog.InfoContext(ctx, "Personal data erased", slog.String("email", req.Email), slog.Any("deleted", deleted))
+	return &domain.ErasureResult{Email: req.Email, Deleted: deleted}, nil
+}
+
+type RetentionService interface {
+	// PurgeExpired permanently removes unsubscribed rows older than the
+	// retention period, together with expired privacy requests.
+	PurgeExpired(ctx context.Context) error
+	Run(ctx context.Context, interval time.Duration)
+}
+
+type retentionService struct {
+	repo      repository.PrivacyRepository
+	retention time.Duration
+	now       func() time.Time
+}
+
+func NewRetentionService(repo repository.PrivacyRepository, retention time.Duration) RetentionService {
+	return &retentionService{repo: repo, retention: retention, now: time.Now}
+}
+
+func (s *retentionService) PurgeExpired(ctx context.Context) error {
+	cutoff := s.now().Add(-s.retention)
+
+	subs, subsErr := s.repo.PurgeSoftDeletedSubscriptions(ctx, cutoff)
+	if subsErr != nil {
+		subsErr = fmt.Errorf("failed to purge soft-deleted subscriptions: %w", subsErr)
+	}
+	reqs, reqsErr := s.repo.PurgeExpiredRequests(ctx, s.now())
+	if reqsErr != nil {
+		reqsErr = fmt.Errorf("failed to purge expired privacy requests: %w", reqsErr)
+	}
+
+	slog.InfoContext(ctx, "Retention purge finished", slog.Int64("subscriptions", subs), slog.Int64("privacy_requests", reqs))
+	return errors.Join(subsErr, reqsErr)
+}
+
+func (s *retentionService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.PurgeExpired(ctx); err != nil {
+			slog.ErrorContext(ctx, "Retention purge failed", slog.Any("error", err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"weather/project/domain"
+	"weather/project/metrics"
+	"weather/project/repository"
+
+	"github.com/google/uuid"
+)
+
+type SubscriptionAdminService interface {
+	List(ctx context.Context, filter domain.SubscriptionFilter) (*domain.SubscriptionPage, error)
+	Get(ctx context.Context, id uuid.UUID) (*domain.Subscription, error)
+	Confirm(ctx context.Context, id uuid.UUID) error
+	Unsubscribe(ctx context.Context, id uuid.UUID) error
+	Delete(ctx context.Context, id uuid.UUID) error
+	// Bulk applies one action to many subscriptions; failures are reported
+	// per ID and do not stop the remaining ones.
+	Bulk(ctx context.Context, input domain.BulkSubscriptionInput) (*domain.BulkSubscriptionResult, error)
+	// Deliveries returns the most recent webhook delivery attempts.
+	Deliveries(ctx context.Context, id uuid.UUID) ([]domain.WebhookDelivery, error)
+}
+
+const adminDeliveriesLimit = 100
+
+type subscriptionAdminService struct {
+	repo         repository.SubscriptionRepository
+	tokenService TokenService
+	deliveries   repository.WebhookDeliveryRepository
+}
+
+// NewSubscriptionAdminService shares the confirmation logic with the public
+// subscription flow, so that manual confirmation issues an unsubscribe token too.
+func NewSubscriptionAdminService(repo repository.SubscriptionRepository, tokenService TokenService, deliveries repository.WebhookDeliveryRepository) SubscriptionAdminService {
+	return &subscriptionAdminService{
+		repo:         repo,
+		tokenService: tokenService,
+		deliveries:   deliveries,
+	}
+}
+
+func (s *subscriptionAdminService) List(ctx context.Context, filter domain.SubscriptionFilter) (*domain.SubscriptionPage, error) {
+	filter.Normalize()
+	subs, total, err := s.repo.List(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list subscriptions: %w", err)
+	}
+	if subs == nil {
+		subs = []domain.Subscription{}
+	}
+	return &domain.SubscriptionPage{
+		Items:    subs,
+		Total:    total,
+		Page:     filter.Page,
+		PageSize: filter.PageSize,
+	}, nil
+}
+
+func (s *subscriptionAdminService) Get(ctx context.Context, id uuid.UUID) (*domain.Subscription, error) {
+	return s.repo.FindByID(ctx, id)
+}
+
+func (s *subscriptionAdminService) Confirm(ctx context.Context, id uuid.UUID) error {
+	sub, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if sub.Confirmed {
+		return nil
+	}
+
+	if err := markConfirmed(ctx, s.repo, s.tokenService, sub); err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "Subscription confirmed by admin", slog.String("subscription_id", id.String()))
+	return nil
+}
+
+func (s *subscriptionAdminService) Unsubscribe(ctx context.Context, id uuid.UUID) error {
+	if _, err := s.repo.FindByID(ctx, id); err != nil {
+		return err
+	}
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return fmt.Errorf("failed to unsubscribe: %w", err)
+	}
+
+	metrics.SubscriptionEventsTotal.WithLabelValues(metrics.SubscriptionUnsubscribed).Inc()
+	slog.InfoContext(ctx, "Subscription unsubscribed by admin", slog.String("subscription_id", id.String()))
+	return nil
+}
+
+func (s *subscriptionAdminService) Delete(ctx context.Context, id uuid.UUID) error {
+	if err := s.repo.HardDelete(ctx, id); err != nil {
+		if errors.Is(err, domain.ErrSubscriptionNotFound) {
+			return err
+		}
+		return fmt.Errorf("failed to delete subscription: %w", err)
+	}
+
+	slog.InfoContext(ctx, "Subscription hard-deleted by admin", slog.String("subscription_id", id.String()))
+	return nil
+}
+
+func (s *subscriptionAdminService) Bulk(ctx context.Context, input domain.BulkSubscriptionInput) (*domain.BulkSubscriptionResult, error) {
+	var apply func(context.Context, uuid.UUID) error
+	switch input.Action {
+	case domain.BulkActionConfirm:
+		apply = s.Confirm
+	case domain.BulkActionUnsubscribe:
+		apply = s.Unsubscribe
+	case domain.BulkActionDelete:
+		apply = s.Delete
+	default:
+		return nil, domain.NewFieldError("action", fmt.Sprintf("unsupported bulk action %q", input.Action))
+	}
+
+	result := &domain.BulkSubscriptionResult{Succeeded: []uuid.UUID{}}
+	for _, id := range input.IDs {
+		if err := apply(ctx, id); err != nil {
+			if result.Failed == nil {
+				result.Failed = make(map[string]string)
+			}
+			result.Failed[id.String()] = err.Error()
+			continue
+		}
+		result.Succeeded = append(result.Succeeded, id)
+	}
+	return result, nil
+}
+
+func (s *subscriptionAdminService) Deliveries(ctx context.Context, id uuid.UUID) ([]domain.WebhookDelivery, error) {
+	if _, err := s.repo.FindByID(ctx, id); err != nil {
+		return nil, err
+	}
+	deliveries, err := s.deliveries.ListBySubscription(ctx, id, adminDeliveriesLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	if deliveries == nil {
+		deliveries = []domain.WebhookDelivery{}
+	}
+	return deliveries, nil
+}
@@ -1,165 +1,325 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"strconv"
 	"time"
 	"weather/project/domain"
+	"weather/project/i18n"
+	"weather/project/metrics"
 	"weather/project/repository"
 )
 
 type SubscriptionService interface {
-	Subscribe(input domain.SubscriptionInput) (*domain.Subscription, error)
-	ConfirmSubscription(token string) error
-	UnsubscribeByToken(token string) error
+	Subscribe(ctx context.Context, input domain.SubscriptionInput) (*domain.Subscription, error)
+	ConfirmSubscription(ctx context.Context, token string) error
+	UnsubscribeByToken(ctx context.Context, token string) error
+	// SubscribeChat subscribes a Telegram chat to a city. Talking to the bot
+	// proves the chat, so the subscription is confirmed right away; a chat
+	// already following the city only has its frequency changed.
+	SubscribeChat(ctx context.Context, chatID int64, city string, frequency domain.SubscriptionFrequency) (*domain.Subscription, error)
+	ListChat(ctx context.Context, chatID int64) ([]domain.Subscription, error)
+	// UnsubscribeChat removes the chat's subscription to city, or all of
+	// them when city is empty, and reports how many were removed.
+	UnsubscribeChat(ctx context.Context, chatID int64, city string) (int64, error)
 }
 
 type subscriptionService struct {
-	repo         repository.SubscriptionRepository
-	tokenService TokenService
-	emailService EmailService
+	repo            repository.SubscriptionRepository
+	tokenService    TokenService
+	notifications   NotificationService
+	locationService LocationService
 }
 
 func NewSubscriptionService(
 	repo repository.SubscriptionRepository,
 	tokenService TokenService,
-	emailService EmailService,
+	notifications NotificationService,
+	locationService LocationService,
 ) SubscriptionService {
 	return &subscriptionService{
-		repo:         repo,
-		tokenService: tokenService,
-		emailService: emailService,
+		repo:            repo,
+		tokenService:    tokenService,
+		notifications:   notifications,
+		locationService: locationService,
 	}
 }
 
-func (s *subscriptionService) Subscribe(input domain.SubscriptionInput) (*domain.Subscription, error) {
-	existingSub, err := s.repo.FindByEmail(input.Email)
+func (s *subscriptionService) Subscribe(ctx context.Context, input domain.SubscriptionInput) (*domain.Subscription, error) {
+	location, err := input.Location()
+	if err != nil {
+		return nil, err
+	}
+	channels, err := input.Channels()
+	if err != nil {
+		return nil, err
+	}
+	resolved, err := s.locationService.Resolve(ctx, location)
+	if err != nil {
+		if errors.Is(err, domain.ErrCityNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to resolve subscription location: %w", err)
+	}
+	switch location.Kind {
+	case domain.LocationCity:
+		// Store "Kyiv" rather than whatever spelling the subscriber typed.
+		location.Value = resolved.Name
+	case domain.LocationIP:
+		// The address identifies the subscriber; keep only where it resolved.
+		location = domain.CoordinatesOf(resolved.Lat, resolved.Lon)
+	}
+
+	language := input.Language
+	if language == "" {
+		language = string(i18n.FromContext(ctx))
+	}
+
+	existingSub, err := s.repo.FindByEmail(ctx, input.Email)
 
 	if err != nil && !errors.Is(err, domain.ErrSubscriptionNotFound) {
-		log.Printf("Error finding subscription by email %s: %v", input.Email, err)
+		slog.ErrorContext(ctx, "Error finding subscription by email", slog.String("email", input.Email), slog.Any("error", err))
 		return nil, fmt.Errorf("failed to check for existing subscription: %w", err)
 	}
 
-	if existingSub != nil {
-		if existingSub.Confirmed {
-			log.Printf("Attempt to subscribe with already confirmed email: %s", input.Email)
-			return nil, domain.ErrEmailAlreadySubscribed
+	if existingSub != nil && existingSub.Confirmed {
+		slog.InfoContext(ctx, "Attempt to subscribe with already confirmed email", slog.String("email", input.Email))
+		return nil, domain.ErrEmailAlreadySubscribed
+	}
+
+	// The email confirmation proves who owns the subscription; verifying a
+	// channel proves its destination agreed to receive the updates. Chat
+	// channels are sent their test message in the subscriber's language.
+	verifyCtx := ctx
+	if lang, ok := i18n.Parse(language); ok {
+		verifyCtx = i18n.WithLang(ctx, lang)
+	}
+	for _, channel := range channels {
+		if err := s.notifications.Verify(verifyCtx, channel); err != nil {
+			return nil, err
 		}
+	}
 
-		log.Printf("Email %s exists but not confirmed. Updating and re-sending confirmation.", input.Email)
+	if existingSub != nil {
+		slog.InfoContext(ctx, "Email exists but not confirmed. Updating and re-sending confirmation.", slog.String("email", input.Email))
 
 		confirmToken, tokenErr := s.tokenService.GenerateToken(32)
 		if tokenErr != nil {
-			log.Printf("Error generating new confirmation token for %s: %v", input.Email, tokenErr)
+			slog.ErrorContext(ctx, "Error generating new confirmation token", slog.String("email", input.Email), slog.Any("error", tokenErr))
 			return nil, fmt.Errorf("failed to generate confirmation token: %w", tokenErr)
 		}
 
-		existingSub.City = input.City
+		existingSub.City = location.Value
+		existingSub.LocationKind = location.Kind
+		existingSub.Resolved = *resolved
 		existingSub.Frequency = domain.SubscriptionFrequency(input.Frequency)
+		existingSub.IncludeAirQuality = input.IncludeAirQuality
+		existingSub.IncludePollen = input.IncludePollen
+		existingSub.Language = language
+		existingSub.Channels = channels
 		existingSub.ConfirmToken = &confirmToken
 		existingSub.UpdatedAt = time.Now()
 
-		if updateErr := s.repo.Update(existingSub); updateErr != nil {
-			log.Printf("Error updating existing unconfirmed subscription for %s: %v", input.Email, updateErr)
+		if updateErr := s.repo.ReplaceChannels(ctx, existingSub); updateErr != nil {
+			slog.ErrorContext(ctx, "Error updating existing unconfirmed subscription", slog.String("email", input.Email), slog.Any("error", updateErr))
 			return nil, fmt.Errorf("failed to update subscription: %w", updateErr)
 		}
 
-		go s.sendConfirmationEmailAsync(existingSub, confirmToken)
+		go s.sendConfirmationAsync(context.WithoutCancel(ctx), existingSub, confirmToken)
 		return existingSub, nil
 	}
 
 	confirmToken, err := s.tokenService.GenerateToken(32)
 	if err != nil {
-		log.Printf("Error generating confirmation token for new sub %s: %v", input.Email, err)
+		slog.ErrorContext(ctx, "Error generating confirmation token for new subscription", slog.String("email", input.Email), slog.Any("error", err))
 		return nil, fmt.Errorf("failed to generate confirmation token: %w", err)
 	}
 
 	newSub := &domain.Subscription{
 
-		Email:        input.Email,
-		City:         input.City,
-		Frequency:    domain.SubscriptionFrequency(input.Frequency),
-		Confirmed:    false,
-		ConfirmToken: &confirmToken,
+		Email:             &input.Email,
+		City:              location.Value,
+		LocationKind:      location.Kind,
+		Resolved:          *resolved,
+		Frequency:         domain.SubscriptionFrequency(input.Frequency),
+		IncludeAirQuality: input.IncludeAirQuality,
+		IncludePollen:     input.IncludePollen,
+		Language:          language,
+		Channels:          channels,
+		Confirmed:         false,
+		ConfirmToken:      &confirmToken,
 	}
 
-	if err := s.repo.Create(newSub); err != nil {
+	if err := s.repo.Create(ctx, newSub); err != nil {
 
-		log.Printf("Error creating new subscription for %s: %v", input.Email, err)
+		slog.ErrorContext(ctx, "Error creating new subscription", slog.String("email", input.Email), slog.Any("error", err))
 		return nil, fmt.Errorf("failed to create subscription: %w", err)
 	}
 
-	go s.sendConfirmationEmailAsync(newSub, confirmToken)
+	metrics.SubscriptionEventsTotal.WithLabelValues(metrics.SubscriptionCreated).Inc()
+	go s.sendConfirmationAsync(context.WithoutCancel(ctx), newSub, confirmToken)
 
-	log.Printf("New subscription initiated for %s, city %s. Confirmation pending.", newSub.Email, newSub.City)
+	slog.InfoContext(ctx, "New subscription initiated. Confirmation pending.", slog.String("email", input.Email), slog.Any("location", location))
 	return newSub, nil
 }
 
-func (s *subscriptionService) sendConfirmationEmailAsync(sub *domain.Subscription, token string) {
-	if s.emailService != nil {
-		err := s.emailService.SendConfirmationEmail(sub, token)
-		if err != nil {
-			log.Printf("Async sendConfirmationEmail: Failed to send email to %s: %v", sub.Email, err)
+// sendConfirmationAsync runs after the request has completed, so ctx must
+// not be cancelled together with it; it is only used to carry the request ID.
+func (s *subscriptionService) sendConfirmationAsync(ctx context.Context, sub *domain.Subscription, token string) {
+	if s.notifications != nil {
+		if err := s.notifications.SendConfirmation(ctx, sub, token); err != nil {
+			slog.ErrorContext(ctx, "Async sendConfirmation: failed to send confirmation", slog.String("email", sub.EmailAddress()), slog.Any("error", err))
 		}
 	} else {
-		log.Printf("Async sendConfirmationEmail: EmailService is nil. Email to %s not sent.", sub.Email)
+		slog.WarnContext(ctx, "Async sendConfirmation: NotificationService is nil. Confirmation not sent.", slog.String("email", sub.EmailAddress()))
 	}
 }
 
-func (s *subscriptionService) ConfirmSubscription(token string) error {
+func (s *subscriptionService) ConfirmSubscription(ctx context.Context, token string) error {
 	if token == "" {
 		return domain.ErrTokenInvalidOrExpired
 	}
-	sub, err := s.repo.FindByConfirmToken(token)
+	sub, err := s.repo.FindByConfirmToken(ctx, token)
 	if err != nil {
 
-		log.Printf("Error finding subscription by confirm token %s: %v", token, err)
+		slog.WarnContext(ctx, "Error finding subscription by confirm token", slog.String("token", token), slog.Any("error", err))
 		return err
 	}
 
 	if sub.Confirmed {
-		log.Printf("Subscription for email %s already confirmed.", sub.Email)
+		slog.InfoContext(ctx, "Subscription already confirmed", slog.String("email", sub.EmailAddress()))
 		return nil
 	}
 
+	return markConfirmed(ctx, s.repo, s.tokenService, sub)
+}
+
+// markConfirmed is shared by the public and admin confirmation, so both
+// issue an unsubscribe token.
+func markConfirmed(ctx context.Context, repo repository.SubscriptionRepository, tokenService TokenService, sub *domain.Subscription) error {
 	sub.Confirmed = true
 	sub.ConfirmToken = nil
 	sub.UpdatedAt = time.Now()
 
-	unsubscribeToken, tokenErr := s.tokenService.GenerateToken(32)
+	unsubscribeToken, tokenErr := tokenService.GenerateToken(32)
 	if tokenErr != nil {
 
-		log.Printf("Error generating unsubscribe token for %s after confirmation: %v", sub.Email, tokenErr)
+		slog.ErrorContext(ctx, "Error generating unsubscribe token after confirmation", slog.String("email", sub.EmailAddress()), slog.Any("error", tokenErr))
 	} else {
 		sub.UnsubscribeToken = &unsubscribeToken
 	}
 
-	if err := s.repo.Update(sub); err != nil {
-		log.Printf("Error updating subscription to confirmed for %s: %v", sub.Email, err)
+	if err := repo.Update(ctx, sub); err != nil {
+		slog.ErrorContext(ctx, "Error updating subscription to confirmed", slog.String("email", sub.EmailAddress()), slog.Any("error", err))
 		return fmt.Errorf("failed to confirm subscription in DB: %w", err)
 	}
 
-	log.Printf("Subscription for %s confirmed successfully.", sub.Email)
+	metrics.SubscriptionEventsTotal.WithLabelValues(metrics.SubscriptionConfirmed).Inc()
+	slog.InfoContext(ctx, "Subscription confirmed successfully", slog.String("email", sub.EmailAddress()))
 	return nil
 }
 
-func (s *subscriptionService) UnsubscribeByToken(token string) error {
+func (s *subscriptionService) UnsubscribeByToken(ctx context.Context, token string) error {
 	if token == "" {
 		return domain.ErrTokenInvalidOrExpired
 	}
-	sub, err := s.repo.FindByUnsubscribeToken(token)
+	sub, err := s.repo.FindByUnsubscribeToken(ctx, token)
 	if err != nil {
-		log.Printf("Error finding subscription by unsubscribe token %s: %v", token, err)
+		slog.WarnContext(ctx, "Error finding subscription by unsubscribe token", slog.String("token", token), slog.Any("error", err))
 		return err
 	}
 
-	if err := s.repo.Delete(sub.ID); err != nil {
-		log.Printf("Error deleting (unsubscribing) subscription ID %s for email %s: %v", sub.ID, sub.Email, err)
+	if err := s.repo.Delete(ctx, sub.ID); err != nil {
+		slog.ErrorContext(ctx, "Error deleting (unsubscribing) subscription", slog.String("subscription_id", sub.ID.String()), slog.String("email", sub.EmailAddress()), slog.Any("error", err))
 		return fmt.Errorf("failed to unsubscribe: %w", err)
 	}
 
-	log.Printf("Email %s (ID: %s) unsubscribed successfully using token.", sub.Email, sub.ID)
+	metrics.SubscriptionEventsTotal.WithLabelValues(metrics.SubscriptionUnsubscribed).Inc()
+	slog.InfoContext(ctx, "Unsubscribed successfully using token", slog.String("email", sub.EmailAddress()), slog.String("subscription_id", sub.ID.String()))
 
 	return nil
 }
+
+func (s *subscriptionService) SubscribeChat(ctx context.Context, chatID int64, city string, frequency domain.SubscriptionFrequency) (*domain.Subscription, error) {
+	location := domain.NewCityLocation(city)
+	resolved, err := s.locationService.Resolve(ctx, location)
+	if err != nil {
+		if errors.Is(err, domain.ErrCityNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to resolve subscription location: %w", err)
+	}
+	location.Value = resolved.Name
+	language := string(i18n.FromContext(ctx))
+
+	existing, err := s.repo.FindByChat(ctx, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list chat subscriptions: %w", err)
+	}
+	for i := range existing {
+		sub := &existing[i]
+		if sub.City != location.Value {
+			continue
+		}
+		sub.Frequency = frequency
+		sub.Language = language
+		sub.UpdatedAt = time.Now()
+		if err := s.repo.Update(ctx, sub); err != nil {
+			return nil, fmt.Errorf("failed to update chat subscription: %w", err)
+		}
+		slog.InfoContext(ctx, "Chat subscription updated", slog.String("subscription_id", sub.ID.String()), slog.String("frequency", string(frequency)))
+		return sub, nil
+	}
+	if len(existing) >= domain.MaxSubscriptionsPerChat {
+		return nil, domain.ErrChatSubscriptionLimit
+	}
+
+	newSub := &domain.Subscription{
+		TelegramChatID: &chatID,
+		City:           location.Value,
+		LocationKind:   location.Kind,
+		Resolved:       *resolved,
+		Frequency:      frequency,
+		Language:       language,
+		Channels:       []domain.SubscriptionChannel{{Kind: domain.ChannelTelegram, Target: strconv.FormatInt(chatID, 10)}},
+		Confirmed:      true,
+	}
+	if err := s.repo.Create(ctx, newSub); err != nil {
+		return nil, fmt.Errorf("failed to create chat subscription: %w", err)
+	}
+
+	metrics.SubscriptionEventsTotal.WithLabelValues(metrics.SubscriptionCreated).Inc()
+	metrics.SubscriptionEventsTotal.WithLabelValues(metrics.SubscriptionConfirmed).Inc()
+	slog.InfoContext(ctx, "Chat subscription created", slog.String("subscription_id", newSub.ID.String()), slog.Any("location", location))
+	return newSub, nil
+}
+
+func (s *subscriptionService) ListChat(ctx context.Context, chatID int64) ([]domain.Subscription, error) {
+	subs, err := s.repo.FindByChat(ctx, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list chat subscriptions: %w", err)
+	}
+	return subs, nil
+}
+
+func (s *subscriptionService) UnsubscribeChat(ctx context.Context, chatID int64, city string) (int64, error) {
+	city = domain.NewCityLocation(city).Value
+	removed, err := s.repo.DeleteByChat(ctx, chatID, city)
+	if err == nil && removed == 0 && city != "" {
+		// Subscriptions store the provider's name, so "Kiev" finds "Kyiv".
+		resolved, resolveErr := s.locationService.Resolve(ctx, domain.NewCityLocation(city))
+		if resolveErr == nil && resolved.Name != city {
+			removed, err = s.repo.DeleteByChat(ctx, chatID, resolved.Name)
+		}
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to unsubscribe chat: %w", err)
+	}
+
+	metrics.SubscriptionEventsTotal.WithLabelValues(metrics.SubscriptionUnsubscribed).Add(float64(removed))
+	slog.InfoContext(ctx, "Chat unsubscribed", slog.String("city", city), slog.Int64("removed", removed))
+	return removed, nil
+}
@@ -0,0 +1,165 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+	"weather/project/config"
+	"weather/project/domain"
+	"weather/project/i18n"
+	"weather/project/service"
+
+	"github.com/google/uuid"
+)
+
+func (r *fakeSubscriptionRepo) Create(_ context.Context, sub *domain.Subscription) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sub.ID = uuid.New()
+	sub.CreatedAt = time.Now()
+	r.subs = append(r.subs, *sub)
+	return nil
+}
+
+func (r *fakeSubscriptionRepo) Update(_ context.Context, sub *domain.Subscription) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.subs {
+		if r.subs[i].ID == sub.ID {
+			r.subs[i] = *sub
+		}
+	}
+	return nil
+}
+
+func (r *fakeSubscriptionRepo) FindByEmail(_ context.Context, email string) (*domain.Subscription, error) {
+	return r.find(func(sub domain.Subscription) bool { return sub.EmailAddress() == email })
+}
+
+func (r *fakeSubscriptionRepo) FindByConfirmToken(_ context.Context, token string) (*domain.Subscription, error) {
+	return r.find(func(sub domain.Subscription) bool { return sub.ConfirmToken != nil && *sub.ConfirmToken == token })
+}
+
+func (r *fakeSubscriptionRepo) find(match func(domain.Subscription) bool) (*domain.Subscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, sub := range r.subs {
+		if match(sub) {
+			return &sub, nil
+		}
+	}
+	return nil, domain.ErrSubscriptionNotFound
+}
+
+type fakeLocations struct{ service.LocationService }
+
+func (fakeLocations) Resolve(_ context.Context, location domain.Location) (*domain.ResolvedLocation, error) {
+	return &domain.ResolvedLocation{Name: location.Value, Lon: 30.5}, nil
+}
+
+// chatNotifier stands in for Slack: it records test messages and weather
+// updates, and rejects test messages while reject is set.
+type chatNotifier struct {
+	mu      sync.Mutex
+	reject  bool
+	tests   []i18n.Lang
+	updates int
+}
+
+func (n *chatNotifier) Channel() domain.DeliveryChannel { return domain.ChannelSlack }
+
+func (n *chatNotifier) Verify(ctx context.Context, _ domain.SubscriptionChannel) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.reject {
+		return domain.ErrChatWebhookRejected
+	}
+	n.tests = append(n.tests, i18n.FromContext(ctx))
+	return nil
+}
+
+func (n *chatNotifier) Send(_ context.Context, _ domain.SubscriptionChannel, msg *service.Message) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if msg.Kind == service.MessageWeatherUpdate {
+		n.updates++
+	}
+	return nil
+}
+
+func TestChatTestMessageOnSubscribe(t *testing.T) {
+	ctx := context.Background()
+	repo := &fakeSubscriptionRepo{}
+	slack := &chatNotifier{reject: true}
+	notifications := service.NewNotificationService(config.Config{}, &recordingNotifier{}, slack)
+	subscriptions := service.NewSubscriptionService(repo, service.NewTokenService(), notifications, fakeLocations{})
+	input := domain.SubscriptionInput{
+		Email:         "someone@example.com",
+		LocationInput: domain.LocationInput{City: "Kyiv"},
+		Frequency:     string(domain.FrequencyDaily),
+		Language:      "uk",
+		Channel:       string(domain.ChannelSlack),
+		WebhookURL:    "https://hooks.slack.com/services/T000/B000/secret",
+	}
+
+	// A webhook that rejects the test message fails the request and nothing
+	// is stored.
+	if _, err := subscriptions.Subscribe(ctx, input); !errors.Is(err, domain.ErrChatWebhookRejected) {
+		t.Fatalf("subscribe with a rejecting webhook: err = %v, want %v", err, domain.ErrChatWebhookRejected)
+	}
+	if len(repo.subs) != 0 {
+		t.Fatalf("subscription stored although its webhook rejected the test message: %+v", repo.subs)
+	}
+
+	slack.reject = false
+	sub, err := subscriptions.Subscribe(ctx, input)
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	if len(slack.tests) != 1 || slack.tests[0] != i18n.Ukrainian {
+		t.Errorf("test messages = %v, want one in uk", slack.tests)
+	}
+	if err := subscriptions.ConfirmSubscription(ctx, *sub.ConfirmToken); err != nil {
+		t.Fatalf("ConfirmSubscription: %v", err)
+	}
+	if len(slack.tests) != 1 {
+		t.Errorf("confirmation posted another test message: %v", slack.tests)
+	}
+
+	// The first morning post goes out at 7:00 in Kyiv, 5:00 UTC by its
+	// longitude.
+	tomorrow := time.Now().UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+	scheduler := service.NewWeatherUpdateScheduler(repo, &countingWeather{calls: make(map[string]int)}, notifications, 7, 1)
+	for _, now := range []time.Time{tomorrow.Add(4 * time.Hour), tomorrow.Add(5 * time.Hour), tomorrow.Add(6 * time.Hour)} {
+		if err := scheduler.SendDue(ctx, now); err != nil {
+			t.Fatalf("SendDue: %v", err)
+		}
+	}
+	if slack.updates != 1 {
+		t.Errorf("morning posts = %d, want 1", slack.updates)
+	}
+}
+
+func TestSubscribeByIPStoresTheResolvedPlace(t *testing.T) {
+	ctx := context.Background()
+	repo := &fakeSubscriptionRepo{}
+	notifications := service.NewNotificationService(config.Config{}, &recordingNotifier{})
+	subscriptions := service.NewSubscriptionService(repo, service.NewTokenService(), notifications, fakeLocations{})
+
+	sub, err := subscriptions.Subscribe(ctx, domain.SubscriptionInput{
+		Email:         "someone@example.com",
+		LocationInput: domain.LocationInput{IP: "203.0.113.7"},
+		Frequency:     string(domain.FrequencyDaily),
+	})
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	stored, _ := repo.FindByEmail(ctx, "someone@example.com")
+	for _, got := range []*domain.Subscription{sub, stored} {
+		if got.LocationKind != domain.LocationCoordinates || got.City != "0,30.5" {
+			t.Errorf("subscription kept %s %q, want the resolved coordinates", got.LocationKind, got.City)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"weather/project/client"
+	"weather/project/domain"
+)
+
+type telegramNotifier struct {
+	client *client.TelegramClient
+}
+
+// NewTelegramNotifier sends weather updates to the chats that subscribed
+// through the bot.
+func NewTelegramNotifier(client *client.TelegramClient) Notifier {
+	return &telegramNotifier{client: client}
+}
+
+func (n *telegramNotifier) Channel() domain.DeliveryChannel {
+	return domain.ChannelTelegram
+}
+
+// Verify accepts every chat; only the bot creates telegram channels, for the
+// chat it is talking to.
+func (n *telegramNotifier) Verify(context.Context, domain.SubscriptionChannel) error {
+	return nil
+}
+
+func (n *telegramNotifier) Send(ctx context.Context, channel domain.SubscriptionChannel, msg *Message) error {
+	if msg.Kind != MessageWeatherUpdate {
+		return fmt.Errorf("service.telegramNotifier.Send: unsupported message kind %q", msg.Kind)
+	}
+	chatID, err := strconv.ParseInt(channel.Target, 10, 64)
+	if err != nil {
+		return fmt.Errorf("service.telegramNotifier.Send: invalid chat ID %q: %w", channel.Target, err)
+	}
+	text := msg.Subject + "\n\n" + msg.Summary + "\n" + msg.Lang.T("Send /unsubscribe to stop receiving these updates.")
+	if err := n.client.SendMessage(ctx, chatID, text); err != nil {
+		return fmt.Errorf("service.telegramNotifier.Send: %w", err)
+	}
+	return nil
+}
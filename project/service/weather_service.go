@@ -1,45 +1,262 @@
 package service
 
 import (
+	"context"
 	"errors"
-	"log"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
 	"weather/project/client"
 	"weather/project/domain"
+	"weather/project/i18n"
+	"weather/project/repository"
 )
 
 type WeatherService interface {
-	GetWeatherForCity(city string) (*domain.WeatherResponse, error)
+	GetWeather(ctx context.Context, location domain.Location, opts domain.WeatherOptions) (*domain.WeatherResponse, error)
+	// GetCachedWeather is GetWeather without options, answered from a
+	// reading fetched within the cache TTL when there is one.
+	GetCachedWeather(ctx context.Context, location domain.Location) (*domain.WeatherResponse, error)
+	// GetWeatherForCities resolves every city concurrently and returns one
+	// lookup per input city, in the same order. Failures are per city.
+	GetWeatherForCities(ctx context.Context, cities []string) []domain.WeatherLookup
+	// GetHistory returns the weather of a past local day (YYYY-MM-DD). With
+	// HistorySourceAuto the local archive is preferred over the provider
+	// once it covers enough hours of the day; a thinner archive is only
+	// returned, marked partial, when the provider fails.
+	GetHistory(ctx context.Context, location domain.Location, date string, source domain.HistorySource) (*domain.WeatherHistory, error)
 }
 
+// maxCachedWeather is how many readings the cache holds before expired ones
+// are dropped.
+const maxCachedWeather = 1000
+
 type weatherService struct {
 	weatherAPIClient *client.WeatherAPIClient
+	archive          repository.ObservationRepository
+	batchConcurrency int
+	archiveMinHours  int
+	cacheTTL         time.Duration
+
+	// mu guards cache, which holds the latest plain reading per location
+	// and language.
+	mu    sync.Mutex
+	cache map[string]cachedWeather
+}
+
+type cachedWeather struct {
+	weather   *domain.WeatherResponse
+	fetchedAt time.Time
 }
 
-func NewWeatherService(apiClient *client.WeatherAPIClient) WeatherService {
+func NewWeatherService(apiClient *client.WeatherAPIClient, archive repository.ObservationRepository, batchConcurrency, archiveMinHours int, cacheTTL time.Duration) WeatherService {
+	if batchConcurrency < 1 {
+		batchConcurrency = 1
+	}
 	return &weatherService{
 		weatherAPIClient: apiClient,
+		archive:          archive,
+		batchConcurrency: batchConcurrency,
+		archiveMinHours:  min(max(archiveMinHours, 1), 24),
+		cacheTTL:         cacheTTL,
+		cache:            make(map[string]cachedWeather),
 	}
 }
 
-func (s *weatherService) GetWeatherForCity(city string) (*domain.WeatherResponse, error) {
-	if city == "" {
+func (s *weatherService) GetWeather(ctx context.Context, location domain.Location, opts domain.WeatherOptions) (*domain.WeatherResponse, error) {
+	if location.Value == "" {
 		return nil, domain.ErrCityNotFound
 	}
 	if s.weatherAPIClient == nil {
-		log.Println("WeatherService: weatherAPIClient is nil")
+		slog.ErrorContext(ctx, "WeatherService: weatherAPIClient is nil")
 		return nil, errors.New("weather service is not properly initialized")
 	}
 
-	log.Printf("Fetching weather for city: %s", city)
-	weather, err := s.weatherAPIClient.GetCurrentWeather(city)
+	slog.DebugContext(ctx, "Fetching weather", slog.Any("location", location))
+	observation, err := s.weatherAPIClient.GetCurrentObservation(ctx, location, opts)
 	if err != nil {
-		log.Printf("Error fetching weather for city %s from API client: %v", city, err)
+		slog.WarnContext(ctx, "Error fetching weather from API client", slog.Any("location", location), slog.Any("error", err))
 		if errors.Is(err, domain.ErrCityNotFound) {
 			return nil, domain.ErrCityNotFound
 		}
 		return nil, domain.ErrFailedToFetchWeather
 	}
 
-	log.Printf("Successfully fetched weather for %s: %+v", city, weather)
+	// Archiving is best effort; the caller still gets the reading.
+	if err := s.archive.Save(ctx, observation); err != nil {
+		slog.WarnContext(ctx, "Failed to archive weather observation", slog.Any("location", location), slog.Any("error", err))
+	}
+
+	weather := observation.Summary()
+	if opts.Forecast {
+		forecast, err := s.weatherAPIClient.GetForecast(ctx, location)
+		if err != nil {
+			slog.WarnContext(ctx, "Error fetching forecast from API client", slog.Any("location", location), slog.Any("error", err))
+			return nil, domain.ErrFailedToFetchWeather
+		}
+		weather.Forecast = forecast
+	}
+	if opts == (domain.WeatherOptions{}) {
+		s.remember(weatherCacheKey(ctx, location), weather)
+	}
+	slog.InfoContext(ctx, "Successfully fetched weather", slog.Any("location", location), slog.Any("weather", weather))
 	return weather, nil
 }
+
+func (s *weatherService) GetCachedWeather(ctx context.Context, location domain.Location) (*domain.WeatherResponse, error) {
+	s.mu.Lock()
+	cached, ok := s.cache[weatherCacheKey(ctx, location)]
+	s.mu.Unlock()
+	if ok && time.Since(cached.fetchedAt) < s.cacheTTL {
+		return cached.weather, nil
+	}
+	return s.GetWeather(ctx, location, domain.WeatherOptions{})
+}
+
+// remember caches a reading, first dropping expired ones once the cache is
+// full.
+func (s *weatherService) remember(key string, weather *domain.WeatherResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.cache) >= maxCachedWeather {
+		for k, cached := range s.cache {
+			if time.Since(cached.fetchedAt) >= s.cacheTTL {
+				delete(s.cache, k)
+			}
+		}
+	}
+	s.cache[key] = cachedWeather{weather: weather, fetchedAt: time.Now()}
+}
+
+// weatherCacheKey tells readings apart by place and by the language of their
+// description.
+func weatherCacheKey(ctx context.Context, location domain.Location) string {
+	return strings.ToLower(location.Query()) + "|" + string(i18n.FromContext(ctx))
+}
+
+func (s *weatherService) GetWeatherForCities(ctx context.Context, cities []string) []domain.WeatherLookup {
+	// The same city spelled twice is fetched once.
+	var unique []string
+	indexOf := make(map[string]int, len(cities))
+	for _, city := range cities {
+		key := strings.ToLower(strings.TrimSpace(city))
+		if _, ok := indexOf[key]; !ok {
+			indexOf[key] = len(unique)
+			unique = append(unique, strings.TrimSpace(city))
+		}
+	}
+
+	lookups := make([]domain.WeatherLookup, len(unique))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(s.batchConcurrency, len(unique)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				weather, err := s.GetWeather(ctx, domain.NewCityLocation(unique[i]), domain.WeatherOptions{})
+				lookups[i] = domain.WeatherLookup{City: unique[i], Weather: weather, Err: err}
+			}
+		}()
+	}
+	for i := range unique {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	results := make([]domain.WeatherLookup, len(cities))
+	for i, city := range cities {
+		results[i] = lookups[indexOf[strings.ToLower(strings.TrimSpace(city))]]
+		results[i].City = city
+	}
+	return results
+}
+
+func (s *weatherService) GetHistory(ctx context.Context, location domain.Location, date string, source domain.HistorySource) (*domain.WeatherHistory, error) {
+	// partial holds archived readings that cover too little of the day to
+	// be preferred over the provider.
+	var partial *domain.WeatherHistory
+	query := domain.ArchiveQuery(location)
+	if source != domain.HistorySourceProvider && query != "" {
+		observations, err := s.archive.FindByQueryAndDate(ctx, query, date)
+		switch {
+		case err != nil && source == domain.HistorySourceArchive:
+			return nil, fmt.Errorf("failed to read weather archive: %w", err)
+		case err != nil:
+			slog.WarnContext(ctx, "Weather archive lookup failed, asking the provider", slog.Any("location", location), slog.Any("error", err))
+		case len(observations) == 0:
+		case source == domain.HistorySourceArchive:
+			return historyFromArchive(i18n.FromContext(ctx), date, observations, s.archiveMinHours), nil
+		default:
+			history := historyFromArchive(i18n.FromContext(ctx), date, observations, s.archiveMinHours)
+			if !history.Partial {
+				return history, nil
+			}
+			partial = history
+		}
+	}
+	if source == domain.HistorySourceArchive {
+		return nil, domain.ErrHistoryUnavailable
+	}
+
+	history, err := s.weatherAPIClient.GetHistory(ctx, location, date)
+	if err != nil {
+		if partial != nil {
+			slog.WarnContext(ctx, "Error fetching weather history from API client, returning partial archive", slog.Any("location", location), slog.String("date", date), slog.Any("error", err))
+			return partial, nil
+		}
+		if errors.Is(err, domain.ErrCityNotFound) || errors.Is(err, domain.ErrHistoryUnavailable) {
+			return nil, err
+		}
+		slog.WarnContext(ctx, "Error fetching weather history from API client", slog.Any("location", location), slog.String("date", date), slog.Any("error", err))
+		return nil, domain.ErrFailedToFetchWeather
+	}
+	return history, nil
+}
+
+// historyFromArchive summarizes archived readings ordered by time; the day is
+// partial when they fall into fewer than minHours distinct hours. The archive
+// holds no descriptions, so they are given in lang from the condition.
+func historyFromArchive(lang i18n.Lang, date string, observations []domain.WeatherObservation, minHours int) *domain.WeatherHistory {
+	history := &domain.WeatherHistory{
+		Date:     date,
+		Source:   domain.HistorySourceArchive,
+		Location: observations[len(observations)-1].Location,
+		Hours:    make([]domain.HourlyConditions, len(observations)),
+	}
+
+	day := &history.Day
+	conditions := make(map[domain.Condition]int)
+	var common domain.Condition
+	hours := make(map[time.Time]struct{})
+	for i, obs := range observations {
+		c := obs.Conditions
+		c.Description = lang.T(c.Condition.Label())
+		history.Hours[i] = domain.HourlyConditions{Time: obs.ObservedAt, Conditions: c}
+		hours[obs.ObservedAt.Truncate(time.Hour)] = struct{}{}
+		if i == 0 || c.TemperatureC < day.MinTempC {
+			day.MinTempC = c.TemperatureC
+		}
+		if i == 0 || c.TemperatureC > day.MaxTempC {
+			day.MaxTempC = c.TemperatureC
+		}
+		day.MaxWindKph = max(day.MaxWindKph, c.WindKph)
+		day.AvgTempC += c.TemperatureC
+		day.AvgHumidity += c.Humidity
+		conditions[c.Condition]++
+		if conditions[c.Condition] > conditions[common] {
+			common = c.Condition
+		}
+		if i == 0 || c.Severity > day.Severity {
+			day.Condition, day.Severity = c.Condition, c.Severity
+		}
+	}
+	day.Description = lang.T(common.Label())
+	day.AvgTempC /= float64(len(observations))
+	day.AvgHumidity /= float64(len(observations))
+	history.Partial = len(hours) < minHours
+	return history
+}
@@ -0,0 +1,149 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+	"weather/project/client"
+	"weather/project/config"
+	"weather/project/domain"
+	"weather/project/i18n"
+	"weather/project/service"
+)
+
+const historyDate = "2026-10-13"
+
+type fakeArchive struct {
+	observations []domain.WeatherObservation
+}
+
+func (f *fakeArchive) Save(context.Context, *domain.WeatherObservation) error { return nil }
+
+func (f *fakeArchive) FindByQueryAndDate(context.Context, string, string) ([]domain.WeatherObservation, error) {
+	return f.observations, nil
+}
+
+func (f *fakeArchive) FindLatestByQuery(context.Context, string) (*domain.WeatherObservation, error) {
+	return nil, nil
+}
+
+// archivedHours returns one reading at the start of each of the first n
+// hours of the day, and a second one in the first hour.
+func archivedHours(n int) []domain.WeatherObservation {
+	start := time.Date(2026, 10, 13, 0, 0, 0, 0, time.UTC)
+	observations := []domain.WeatherObservation{}
+	for i := range n {
+		observedAt := start.Add(time.Duration(i) * time.Hour)
+		observations = append(observations, domain.WeatherObservation{
+			Location:   domain.ResolvedLocation{Name: "Kyiv"},
+			LocalDate:  historyDate,
+			ObservedAt: observedAt,
+			Conditions: domain.Conditions{TemperatureC: float64(i), Condition: domain.ConditionClear},
+		})
+		if i == 0 {
+			observations = append(observations, domain.WeatherObservation{ObservedAt: observedAt.Add(30 * time.Minute), LocalDate: historyDate})
+		}
+	}
+	return observations
+}
+
+// redirectTransport sends every request to the fake provider.
+type redirectTransport struct {
+	target *url.URL
+	base   http.RoundTripper
+}
+
+func (t *redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	redirected := req.Clone(req.Context())
+	redirected.URL.Scheme, redirected.URL.Host = t.target.Scheme, t.target.Host
+	return t.base.RoundTrip(redirected)
+}
+
+// fakeProvider answers history requests with a one-hour day, or with 500
+// while *down is set. It counts the requests it gets in *calls.
+func fakeProvider(t *testing.T, down *bool, calls *int) *client.WeatherAPIClient {
+	t.Helper()
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*calls++
+		if *down {
+			http.Error(w, "unavailable", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"location":{"name":"Kyiv","country":"Ukraine"},"forecast":{"forecastday":[{"date":"2026-10-13","day":{"maxtemp_c":15,"mintemp_c":8,"condition":{"text":"Sunny","code":1000}},"hour":[{"time_epoch":1791849600,"temp_c":9,"condition":{"text":"Clear","code":1000}}]}]}}`))
+	}))
+	t.Cleanup(provider.Close)
+
+	target, _ := url.Parse(provider.URL)
+	// The client wraps http.DefaultTransport when it is built.
+	base := http.DefaultTransport
+	http.DefaultTransport = &redirectTransport{target: target, base: base}
+	t.Cleanup(func() { http.DefaultTransport = base })
+	return client.NewWeatherAPIClient(config.Config{WeatherAPIKey: "history-test-key"})
+}
+
+func TestGetHistoryArchiveCoverage(t *testing.T) {
+	cases := []struct {
+		name         string
+		archived     int
+		source       domain.HistorySource
+		providerDown bool
+		wantSource   domain.HistorySource
+		wantPartial  bool
+		wantErr      error
+		wantCalls    int
+	}{
+		{name: "full archive", archived: 24, source: domain.HistorySourceAuto, wantSource: domain.HistorySourceArchive, wantCalls: 0},
+		{name: "thin archive", archived: 3, source: domain.HistorySourceAuto, wantSource: domain.HistorySourceProvider, wantCalls: 1},
+		{name: "thin archive, provider down", archived: 3, source: domain.HistorySourceAuto, providerDown: true, wantSource: domain.HistorySourceArchive, wantPartial: true, wantCalls: 1},
+		{name: "thin archive asked for", archived: 3, source: domain.HistorySourceArchive, wantSource: domain.HistorySourceArchive, wantPartial: true, wantCalls: 0},
+		{name: "no archive, provider down", archived: 0, source: domain.HistorySourceAuto, providerDown: true, wantErr: domain.ErrFailedToFetchWeather, wantCalls: 1},
+		{name: "no archive asked for", archived: 0, source: domain.HistorySourceArchive, wantErr: domain.ErrHistoryUnavailable, wantCalls: 0},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls int
+			down := tc.providerDown
+			weatherService := service.NewWeatherService(fakeProvider(t, &down, &calls), &fakeArchive{observations: archivedHours(tc.archived)}, 1, 24, time.Minute)
+
+			history, err := weatherService.GetHistory(context.Background(), domain.NewCityLocation("Kyiv"), historyDate, tc.source)
+			if calls != tc.wantCalls {
+				t.Errorf("provider calls = %d, want %d", calls, tc.wantCalls)
+			}
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetHistory: %v", err)
+			}
+			if history.Source != tc.wantSource || history.Partial != tc.wantPartial {
+				t.Errorf("source = %s, partial = %v; want %s, %v", history.Source, history.Partial, tc.wantSource, tc.wantPartial)
+			}
+		})
+	}
+}
+
+func TestArchivedHistoryIsDescribedInTheRequestLanguage(t *testing.T) {
+	var calls int
+	down := false
+	weatherService := service.NewWeatherService(fakeProvider(t, &down, &calls), &fakeArchive{observations: archivedHours(24)}, 1, 24, time.Minute)
+
+	for lang, want := range map[i18n.Lang]string{i18n.English: "Clear", i18n.Ukrainian: "Ясно"} {
+		ctx := i18n.WithLang(context.Background(), lang)
+		history, err := weatherService.GetHistory(ctx, domain.NewCityLocation("Kyiv"), historyDate, domain.HistorySourceArchive)
+		if err != nil {
+			t.Fatalf("%s: GetHistory: %v", lang, err)
+		}
+		if history.Day.Description != want || history.Hours[len(history.Hours)-1].Description != want {
+			t.Errorf("%s: day %q, last hour %q, want %q", lang, history.Day.Description, history.Hours[len(history.Hours)-1].Description, want)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+	"weather/project/domain"
+	"weather/project/i18n"
+	"weather/project/metrics"
+)
+
+// WeatherStream shares live weather between any number of listeners: each
+// location and language is polled by a single goroutine, started by the
+// first listener and stopped when the last one leaves.
+type WeatherStream interface {
+	// Subscribe returns a channel that carries the current weather and then
+	// every change to it, and is closed once ctx is done. The first reading
+	// is fetched before it returns, so an unknown city fails right away.
+	Subscribe(ctx context.Context, location domain.Location) (<-chan *domain.WeatherResponse, error)
+}
+
+type weatherStream struct {
+	weather  WeatherService
+	interval time.Duration
+
+	// mu guards pollers and the listeners and latest reading of each.
+	mu      sync.Mutex
+	pollers map[string]*weatherPoller
+}
+
+type weatherPoller struct {
+	listeners map[chan *domain.WeatherResponse]struct{}
+	latest    *domain.WeatherResponse
+	// ready is closed after the first fetch; err is its failure.
+	ready chan struct{}
+	err   error
+	stop  context.CancelFunc
+}
+
+// NewWeatherStream checks for a new reading every interval. Readings come
+// through the weather service's cache, so the provider is asked at most once
+// per cache TTL, while readings other requests fetched show up sooner.
+func NewWeatherStream(weather WeatherService, interval time.Duration) WeatherStream {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	return &weatherStream{
+		weather:  weather,
+		interval: interval,
+		pollers:  make(map[string]*weatherPoller),
+	}
+}
+
+func (s *weatherStream) Subscribe(ctx context.Context, location domain.Location) (<-chan *domain.WeatherResponse, error) {
+	lang := i18n.FromContext(ctx)
+	key := strings.ToLower(location.Value) + "|" + string(lang)
+	updates := make(chan *domain.WeatherResponse, 1)
+
+	s.mu.Lock()
+	poller, ok := s.pollers[key]
+	if !ok {
+		pollCtx, stop := context.WithCancel(i18n.WithLang(context.Background(), lang))
+		poller = &weatherPoller{
+			listeners: make(map[chan *domain.WeatherResponse]struct{}),
+			ready:     make(chan struct{}),
+			stop:      stop,
+		}
+		s.pollers[key] = poller
+		metrics.WeatherStreamPollers.Inc()
+		go s.poll(pollCtx, key, poller, location)
+	}
+	poller.listeners[updates] = struct{}{}
+	if poller.latest != nil {
+		updates <- poller.latest
+	}
+	metrics.WeatherStreamListeners.Inc()
+	s.mu.Unlock()
+
+	select {
+	case <-poller.ready:
+	case <-ctx.Done():
+		s.leave(key, poller, updates)
+		return nil, ctx.Err()
+	}
+	if poller.err != nil {
+		s.leave(key, poller, updates)
+		return nil, poller.err
+	}
+
+	go func() {
+		<-ctx.Done()
+		s.leave(key, poller, updates)
+	}()
+	return updates, nil
+}
+
+// leave closes the listener's channel and stops the poller once nobody
+// listens to it anymore.
+func (s *weatherStream) leave(key string, poller *weatherPoller, updates chan *domain.WeatherResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(poller.listeners, updates)
+	close(updates)
+	metrics.WeatherStreamListeners.Dec()
+	if len(poller.listeners) == 0 && s.pollers[key] == poller {
+		s.forget(key, poller)
+	}
+}
+
+// forget must be called with mu held.
+func (s *weatherStream) forget(key string, poller *weatherPoller) {
+	delete(s.pollers, key)
+	poller.stop()
+	metrics.WeatherStreamPollers.Dec()
+}
+
+func (s *weatherStream) poll(ctx context.Context, key string, poller *weatherPoller, location domain.Location) {
+	weather, err := s.weather.GetCachedWeather(ctx, location)
+	s.mu.Lock()
+	if err != nil {
+		// Listeners waiting for the first reading get the error; the next
+		// one to arrive starts over with a new poller.
+		poller.err = err
+		if s.pollers[key] == poller {
+			s.forget(key, poller)
+		}
+	} else {
+		s.publish(poller, weather)
+	}
+	close(poller.ready)
+	s.mu.Unlock()
+	if err != nil {
+		return
+	}
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		weather, err := s.weather.GetCachedWeather(ctx, location)
+		if err != nil {
+			if ctx.Err() == nil {
+				slog.WarnContext(ctx, "Weather stream poll failed, keeping the last reading", slog.Any("location", location), slog.Any("error", err))
+			}
+			continue
+		}
+		s.mu.Lock()
+		if !reflect.DeepEqual(poller.latest, weather) {
+			s.publish(poller, weather)
+		}
+		s.mu.Unlock()
+	}
+}
+
+// publish must be called with mu held. A listener that has not taken the
+// previous reading yet gets the new one in its place, so a slow client never
+// holds up the others.
+func (s *weatherStream) publish(poller *weatherPoller, weather *domain.WeatherResponse) {
+	poller.latest = weather
+	for updates := range poller.listeners {
+		select {
+		case updates <- weather:
+		default:
+			select {
+			case <-updates:
+			default:
+			}
+			updates <- weather
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package service_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+	"weather/project/domain"
+	"weather/project/i18n"
+	"weather/project/service"
+)
+
+// scriptedWeather returns the temperatures of readings one poll after the
+// other, repeating the last one, and counts the polls per language.
+type scriptedWeather struct {
+	service.WeatherService
+	readings []float64
+
+	mu    sync.Mutex
+	calls map[i18n.Lang]int
+}
+
+func (w *scriptedWeather) GetCachedWeather(ctx context.Context, _ domain.Location) (*domain.WeatherResponse, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	lang := i18n.FromContext(ctx)
+	reading := w.readings[min(w.calls[lang], len(w.readings)-1)]
+	w.calls[lang]++
+	return &domain.WeatherResponse{Temperature: reading, Description: "Clear", Condition: domain.ConditionClear}, nil
+}
+
+func (w *scriptedWeather) totalCalls() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	total := 0
+	for _, n := range w.calls {
+		total += n
+	}
+	return total
+}
+
+func TestWeatherStream(t *testing.T) {
+	cases := []struct {
+		name      string
+		interval  time.Duration
+		readings  []float64
+		listeners []i18n.Lang
+		// wantCalls is the number of polls once every listener is in.
+		wantCalls int
+		// wantReceived is what the first listener gets, in order.
+		wantReceived []float64
+	}{
+		{
+			name:         "one place and language share a poller",
+			interval:     time.Hour,
+			readings:     []float64{10},
+			listeners:    []i18n.Lang{i18n.English, i18n.English},
+			wantCalls:    1,
+			wantReceived: []float64{10},
+		},
+		{
+			name:         "each language has its own poller",
+			interval:     time.Hour,
+			readings:     []float64{10},
+			listeners:    []i18n.Lang{i18n.English, i18n.Ukrainian},
+			wantCalls:    2,
+			wantReceived: []float64{10},
+		},
+		{
+			name:         "unchanged readings are not published again",
+			interval:     5 * time.Millisecond,
+			readings:     []float64{10, 10, 10, 11},
+			listeners:    []i18n.Lang{i18n.English},
+			wantReceived: []float64{10, 11},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			weather := &scriptedWeather{readings: tc.readings, calls: make(map[i18n.Lang]int)}
+			stream := service.NewWeatherStream(weather, tc.interval)
+			location := domain.NewCityLocation("Kyiv")
+
+			var cancels []context.CancelFunc
+			var channels []<-chan *domain.WeatherResponse
+			for _, lang := range tc.listeners {
+				ctx, cancel := context.WithCancel(i18n.WithLang(context.Background(), lang))
+				cancels = append(cancels, cancel)
+				updates, err := stream.Subscribe(ctx, location)
+				if err != nil {
+					t.Fatalf("Subscribe: %v", err)
+				}
+				channels = append(channels, updates)
+			}
+			if tc.wantCalls != 0 && weather.totalCalls() != tc.wantCalls {
+				t.Errorf("polls = %d, want %d", weather.totalCalls(), tc.wantCalls)
+			}
+
+			last := tc.wantReceived[len(tc.wantReceived)-1]
+			var received []float64
+			for len(received) == 0 || received[len(received)-1] != last {
+				select {
+				case weather := <-channels[0]:
+					received = append(received, weather.Temperature)
+				case <-time.After(time.Second):
+					t.Fatalf("received %v, still waiting for %v", received, last)
+				}
+			}
+			quiet := min(10*tc.interval, 50*time.Millisecond)
+			select {
+			case weather := <-channels[0]:
+				received = append(received, weather.Temperature)
+			case <-time.After(quiet):
+			}
+			if len(received) != len(tc.wantReceived) {
+				t.Errorf("received %v, want %v", received, tc.wantReceived)
+			}
+
+			// Once the last listener leaves, polling stops and the next
+			// listener starts over.
+			for i, cancel := range cancels {
+				cancel()
+				for range channels[i] {
+				}
+			}
+			// A poll already under way may still finish.
+			time.Sleep(quiet)
+			stopped := weather.totalCalls()
+			time.Sleep(quiet)
+			if got := weather.totalCalls(); got != stopped {
+				t.Errorf("polls after the last listener left = %d, want %d", got-stopped, 0)
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if _, err := stream.Subscribe(ctx, location); err != nil {
+				t.Fatalf("Subscribe again: %v", err)
+			}
+			if weather.totalCalls() == stopped {
+				t.Errorf("a new listener joined the stopped poller")
+			}
+		})
+	}
+}
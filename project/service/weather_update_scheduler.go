@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+	"weather/project/domain"
+	"weather/project/i18n"
+	"weather/project/metrics"
+	"weather/project/repository"
+)
+
+// WeatherUpdateScheduler delivers the weather updates subscribers signed up
+// for, away from any request: webhook, Slack and Discord deliveries retry
+// with backoff and may take a while.
+type WeatherUpdateScheduler interface {
+	// SendDue sends an update to every confirmed subscription due at now,
+	// see domain.Subscription.UpdateDue, and returns once all are sent.
+	SendDue(ctx context.Context, now time.Time) error
+	Run(ctx context.Context, interval time.Duration)
+}
+
+// updateClaimTTL is how long a replica may take to send one update before
+// another may try; it is also how soon an update that failed on a channel is
+// tried again.
+const updateClaimTTL = 15 * time.Minute
+
+type weatherUpdateScheduler struct {
+	repo          repository.SubscriptionRepository
+	weather       WeatherService
+	notifications NotificationService
+	dailyHour     int
+	workers       int
+}
+
+func NewWeatherUpdateScheduler(repo repository.SubscriptionRepository, weather WeatherService, notifications NotificationService, dailyHour, workers int) WeatherUpdateScheduler {
+	if workers < 1 {
+		workers = 1
+	}
+	return &weatherUpdateScheduler{
+		repo:          repo,
+		weather:       weather,
+		notifications: notifications,
+		dailyHour:     dailyHour,
+		workers:       workers,
+	}
+}
+
+func (s *weatherUpdateScheduler) SendDue(ctx context.Context, now time.Time) error {
+	hourly, hourlyErr := s.repo.FindAwaitingUpdate(ctx, domain.FrequencyHourly, now.Truncate(time.Hour))
+	if hourlyErr != nil {
+		hourlyErr = fmt.Errorf("failed to list hourly subscriptions: %w", hourlyErr)
+	}
+	daily, dailyErr := s.repo.FindAwaitingUpdate(ctx, domain.FrequencyDaily, now)
+	if dailyErr != nil {
+		dailyErr = fmt.Errorf("failed to list daily subscriptions: %w", dailyErr)
+	}
+
+	var due []*domain.Subscription
+	for _, subs := range [][]domain.Subscription{hourly, daily} {
+		for i := range subs {
+			if subs[i].UpdateDue(now, s.dailyHour) {
+				due = append(due, &subs[i])
+			}
+		}
+	}
+	if len(due) > 0 {
+		s.deliver(ctx, now, due)
+	}
+	if err := errors.Join(hourlyErr, dailyErr); err != nil {
+		return fmt.Errorf("service.SendDue: %w", err)
+	}
+	return nil
+}
+
+// deliver fetches the weather once per location, language and set of
+// options, then sends the updates with a fixed number of workers.
+func (s *weatherUpdateScheduler) deliver(ctx context.Context, now time.Time, due []*domain.Subscription) {
+	type weatherResult struct {
+		once    sync.Once
+		weather *domain.WeatherResponse
+		err     error
+	}
+	var mu sync.Mutex
+	results := make(map[string]*weatherResult)
+	fetch := func(ctx context.Context, sub *domain.Subscription) (*domain.WeatherResponse, error) {
+		opts := sub.WeatherOptions()
+		key := fmt.Sprintf("%s|%s|%+v", strings.ToLower(sub.Location().Query()), i18n.FromContext(ctx), opts)
+		mu.Lock()
+		result, ok := results[key]
+		if !ok {
+			result = &weatherResult{}
+			results[key] = result
+		}
+		mu.Unlock()
+		result.once.Do(func() { result.weather, result.err = s.weather.GetWeather(ctx, sub.Location(), opts) })
+		return result.weather, result.err
+	}
+
+	jobs := make(chan *domain.Subscription)
+	var wg sync.WaitGroup
+	for range min(s.workers, len(due)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for sub := range jobs {
+				s.send(i18n.WithLang(ctx, subscriberLang(sub)), now, sub, fetch)
+			}
+		}()
+	}
+	for _, sub := range due {
+		jobs <- sub
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// send claims the subscription first, so replicas running the scheduler side
+// by side do not send the same update twice. It is marked as updated only once
+// every channel accepted the update; otherwise the claim runs out and a later
+// run sends it again, to every channel. When the weather cannot be fetched
+// nothing was sent, so the claim is released for the next run to try again.
+func (s *weatherUpdateScheduler) send(ctx context.Context, now time.Time, sub *domain.Subscription, fetch func(context.Context, *domain.Subscription) (*domain.WeatherResponse, error)) {
+	claimed, err := s.repo.ClaimUpdate(ctx, sub, now, now.Add(updateClaimTTL))
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to claim weather update", slog.String("subscription_id", sub.ID.String()), slog.Any("error", err))
+		return
+	}
+	if !claimed {
+		return
+	}
+
+	frequency := string(sub.Frequency)
+	weather, err := fetch(ctx, sub)
+	if err != nil {
+		slog.WarnContext(ctx, "Could not fetch weather for update", slog.String("subscription_id", sub.ID.String()), slog.Any("location", sub.Location()), slog.Any("error", err))
+		metrics.WeatherUpdatesTotal.WithLabelValues(frequency, metrics.OutcomeError).Inc()
+		if err := s.repo.ReleaseUpdate(ctx, sub.ID); err != nil {
+			slog.ErrorContext(ctx, "Failed to release weather update claim", slog.String("subscription_id", sub.ID.String()), slog.Any("error", err))
+		}
+		return
+	}
+
+	if err := s.notifications.SendWeatherUpdate(ctx, sub, weather); err != nil {
+		slog.WarnContext(ctx, "Weather update failed on a channel, sending again once the claim runs out", slog.String("subscription_id", sub.ID.String()), slog.Any("error", err))
+		metrics.WeatherUpdatesTotal.WithLabelValues(frequency, metrics.OutcomeError).Inc()
+		return
+	}
+	metrics.WeatherUpdatesTotal.WithLabelValues(frequency, metrics.OutcomeSuccess).Inc()
+	if err := s.repo.MarkUpdateSent(ctx, sub.ID, now); err != nil {
+		slog.ErrorContext(ctx, "Failed to record weather update", slog.String("subscription_id", sub.ID.String()), slog.Any("error", err))
+	}
+}
+
+func (s *weatherUpdateScheduler) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.SendDue(ctx, time.Now()); err != nil {
+			slog.ErrorContext(ctx, "Scheduled weather updates failed", slog.Any("error", err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
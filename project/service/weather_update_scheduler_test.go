@@ -0,0 +1,289 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+	"weather/project/config"
+	"weather/project/domain"
+	"weather/project/repository"
+	"weather/project/service"
+
+	"github.com/google/uuid"
+)
+
+type fakeSubscriptionRepo struct {
+	repository.SubscriptionRepository
+	mu   sync.Mutex
+	subs []domain.Subscription
+}
+
+func (r *fakeSubscriptionRepo) FindAwaitingUpdate(_ context.Context, frequency domain.SubscriptionFrequency, before time.Time) ([]domain.Subscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var found []domain.Subscription
+	for _, sub := range r.subs {
+		last := sub.CreatedAt
+		if sub.LastUpdateAt != nil {
+			last = *sub.LastUpdateAt
+		}
+		if sub.Confirmed && sub.Frequency == frequency && last.Before(before) {
+			found = append(found, sub)
+		}
+	}
+	return found, nil
+}
+
+// ClaimUpdate has the guards of the conditional UPDATE in the database.
+func (r *fakeSubscriptionRepo) ClaimUpdate(_ context.Context, sub *domain.Subscription, now, until time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.subs {
+		stored := &r.subs[i]
+		if stored.ID != sub.ID {
+			continue
+		}
+		if !sameTime(stored.LastUpdateAt, sub.LastUpdateAt) || (stored.UpdateClaimedUntil != nil && stored.UpdateClaimedUntil.After(now)) {
+			return false, nil
+		}
+		stored.UpdateClaimedUntil = &until
+		return true, nil
+	}
+	return false, nil
+}
+
+func sameTime(a, b *time.Time) bool {
+	return a == nil && b == nil || a != nil && b != nil && a.Equal(*b)
+}
+
+func (r *fakeSubscriptionRepo) ReleaseUpdate(_ context.Context, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.subs {
+		if r.subs[i].ID == id {
+			r.subs[i].UpdateClaimedUntil = nil
+		}
+	}
+	return nil
+}
+
+func (r *fakeSubscriptionRepo) MarkUpdateSent(_ context.Context, id uuid.UUID, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.subs {
+		if r.subs[i].ID == id {
+			r.subs[i].LastUpdateAt = &at
+			r.subs[i].UpdateClaimedUntil = nil
+		}
+	}
+	return nil
+}
+
+type countingWeather struct {
+	service.WeatherService
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func (w *countingWeather) GetWeather(_ context.Context, location domain.Location, _ domain.WeatherOptions) (*domain.WeatherResponse, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.calls[location.Value]++
+	if location.Value == "Nowhere" {
+		return nil, domain.ErrFailedToFetchWeather
+	}
+	return &domain.WeatherResponse{Temperature: 12, Description: "Sunny", Condition: domain.ConditionClear}, nil
+}
+
+// recordingNotifier stands in for the email channel and remembers who it
+// sent updates to.
+type recordingNotifier struct {
+	mu   sync.Mutex
+	sent []string
+}
+
+func (n *recordingNotifier) Channel() domain.DeliveryChannel { return domain.ChannelEmail }
+
+func (n *recordingNotifier) Verify(context.Context, domain.SubscriptionChannel) error { return nil }
+
+func (n *recordingNotifier) Send(_ context.Context, _ domain.SubscriptionChannel, msg *service.Message) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.sent = append(n.sent, msg.Subscription.EmailAddress())
+	return nil
+}
+
+func (n *recordingNotifier) take() map[string]bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	sent := make(map[string]bool, len(n.sent))
+	for _, email := range n.sent {
+		sent[email] = true
+	}
+	n.sent = nil
+	return sent
+}
+
+func scheduledSubscription(email, city string, lon float64, frequency domain.SubscriptionFrequency, createdAt time.Time) domain.Subscription {
+	return domain.Subscription{
+		ID:        uuid.New(),
+		Email:     &email,
+		City:      city,
+		Resolved:  domain.ResolvedLocation{Name: city, Lon: lon},
+		Frequency: frequency,
+		Language:  "en",
+		Confirmed: true,
+		Channels:  []domain.SubscriptionChannel{{Kind: domain.ChannelEmail}},
+		CreatedAt: createdAt,
+	}
+}
+
+func TestWeatherUpdateSchedulerSendsDueUpdates(t *testing.T) {
+	yesterday := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	// Kyiv is two hours ahead of UTC by its longitude, so 7:00 there is
+	// 5:00 UTC; London's 7:00 is two hours later.
+	now := time.Date(2026, 10, 19, 5, 0, 30, 0, time.UTC)
+
+	unconfirmed := scheduledSubscription("unconfirmed@example.com", "Kyiv", 30.5, domain.FrequencyHourly, yesterday)
+	unconfirmed.Confirmed = false
+	repo := &fakeSubscriptionRepo{subs: []domain.Subscription{
+		scheduledSubscription("hourly@example.com", "Kyiv", 30.5, domain.FrequencyHourly, now.Add(-40*time.Minute)),
+		scheduledSubscription("new-hourly@example.com", "Kyiv", 30.5, domain.FrequencyHourly, now.Add(-10*time.Second)),
+		scheduledSubscription("kyiv-daily@example.com", "Kyiv", 30.5, domain.FrequencyDaily, yesterday),
+		scheduledSubscription("kyiv-daily-2@example.com", "Kyiv", 30.5, domain.FrequencyDaily, yesterday),
+		scheduledSubscription("london-daily@example.com", "London", -0.1, domain.FrequencyDaily, yesterday),
+		scheduledSubscription("nowhere@example.com", "Nowhere", 0, domain.FrequencyHourly, yesterday),
+		unconfirmed,
+	}}
+	weather := &countingWeather{calls: make(map[string]int)}
+	notifier := &recordingNotifier{}
+	scheduler := service.NewWeatherUpdateScheduler(repo, weather, service.NewNotificationService(config.Config{}, notifier), 7, 3)
+
+	if err := scheduler.SendDue(context.Background(), now); err != nil {
+		t.Fatalf("SendDue: %v", err)
+	}
+	sent := notifier.take()
+	for _, email := range []string{"hourly@example.com", "kyiv-daily@example.com", "kyiv-daily-2@example.com"} {
+		if !sent[email] {
+			t.Errorf("no update sent to %s", email)
+		}
+	}
+	if len(sent) != 3 {
+		t.Errorf("updates sent to %v, want 3", sent)
+	}
+	// The Kyiv subscribers want the same data and share one reading.
+	if weather.calls["Kyiv"] != 1 || weather.calls["London"] != 0 {
+		t.Errorf("weather fetched %v", weather.calls)
+	}
+
+	// Nothing is sent twice in the same hour, and a failed fetch is retried.
+	if err := scheduler.SendDue(context.Background(), now.Add(10*time.Minute)); err != nil {
+		t.Fatalf("SendDue: %v", err)
+	}
+	if sent := notifier.take(); len(sent) != 0 {
+		t.Errorf("updates sent again: %v", sent)
+	}
+	if weather.calls["Nowhere"] != 2 {
+		t.Errorf("failed fetch was retried %d times, want 2 calls", weather.calls["Nowhere"])
+	}
+
+	// London's morning and the next hour come two hours later.
+	if err := scheduler.SendDue(context.Background(), now.Add(2*time.Hour)); err != nil {
+		t.Fatalf("SendDue: %v", err)
+	}
+	sent = notifier.take()
+	for _, email := range []string{"hourly@example.com", "new-hourly@example.com", "london-daily@example.com"} {
+		if !sent[email] {
+			t.Errorf("no update sent to %s two hours later", email)
+		}
+	}
+	if sent["kyiv-daily@example.com"] || len(sent) != 3 {
+		t.Errorf("updates sent two hours later to %v", sent)
+	}
+}
+
+func TestWeatherUpdateSchedulerReplicasSendOnce(t *testing.T) {
+	now := time.Date(2026, 10, 19, 5, 0, 30, 0, time.UTC)
+	repo := &fakeSubscriptionRepo{}
+	for _, email := range []string{"a@example.com", "b@example.com", "c@example.com", "d@example.com"} {
+		repo.subs = append(repo.subs, scheduledSubscription(email, "Kyiv", 30.5, domain.FrequencyHourly, now.Add(-2*time.Hour)))
+	}
+	notifier := &recordingNotifier{}
+	notifications := service.NewNotificationService(config.Config{}, notifier)
+
+	var wg sync.WaitGroup
+	for range 3 {
+		replica := service.NewWeatherUpdateScheduler(repo, &countingWeather{calls: make(map[string]int)}, notifications, 7, 2)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := replica.SendDue(context.Background(), now); err != nil {
+				t.Errorf("SendDue: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(notifier.sent) != len(repo.subs) {
+		t.Errorf("updates sent %v, want one per subscription", notifier.sent)
+	}
+}
+
+// failingNotifier is an email channel that rejects updates while fail is set.
+type failingNotifier struct {
+	mu    sync.Mutex
+	fail  bool
+	sends int
+}
+
+func (n *failingNotifier) Channel() domain.DeliveryChannel { return domain.ChannelEmail }
+
+func (n *failingNotifier) Verify(context.Context, domain.SubscriptionChannel) error { return nil }
+
+func (n *failingNotifier) Send(context.Context, domain.SubscriptionChannel, *service.Message) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.sends++
+	if n.fail {
+		return errors.New("mailbox unavailable")
+	}
+	return nil
+}
+
+func TestWeatherUpdateSchedulerRetriesFailedChannels(t *testing.T) {
+	now := time.Date(2026, 10, 19, 5, 0, 30, 0, time.UTC)
+	repo := &fakeSubscriptionRepo{subs: []domain.Subscription{
+		scheduledSubscription("someone@example.com", "Kyiv", 30.5, domain.FrequencyHourly, now.Add(-2*time.Hour)),
+	}}
+	notifier := &failingNotifier{fail: true}
+	scheduler := service.NewWeatherUpdateScheduler(repo, &countingWeather{calls: make(map[string]int)}, service.NewNotificationService(config.Config{}, notifier), 7, 1)
+
+	steps := []struct {
+		name      string
+		at        time.Duration
+		fail      bool
+		wantSends int
+		wantSent  bool
+	}{
+		{name: "channel fails", at: 0, fail: true, wantSends: 1},
+		{name: "claimed while the failure cools down", at: 10 * time.Minute, wantSends: 1},
+		{name: "sent again once the claim runs out", at: 16 * time.Minute, wantSends: 2, wantSent: true},
+		{name: "not again in the same hour", at: 20 * time.Minute, wantSends: 2, wantSent: true},
+	}
+	for _, step := range steps {
+		notifier.mu.Lock()
+		notifier.fail = step.fail
+		notifier.mu.Unlock()
+		if err := scheduler.SendDue(context.Background(), now.Add(step.at)); err != nil {
+			t.Fatalf("%s: SendDue: %v", step.name, err)
+		}
+		if notifier.sends != step.wantSends {
+			t.Errorf("%s: sends = %d, want %d", step.name, notifier.sends, step.wantSends)
+		}
+		if sent := repo.subs[0].LastUpdateAt != nil; sent != step.wantSent {
+			t.Errorf("%s: marked as updated = %v, want %v", step.name, sent, step.wantSent)
+		}
+	}
+}
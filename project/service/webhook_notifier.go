@@ -0,0 +1,277 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	"weather/project/config"
+	"weather/project/domain"
+	"weather/project/metrics"
+	"weather/project/repository"
+
+	"github.com/google/uuid"
+)
+
+const (
+	webhookUserAgent = "WeatherAPI-Webhooks/1.0"
+	// maxWebhookResponseBytes bounds how much of a response is read, which is
+	// only ever needed for the challenge echo.
+	maxWebhookResponseBytes = 4096
+	maxDeliveryErrorLength  = 512
+)
+
+type webhookNotifier struct {
+	cfg          config.Config
+	repo         repository.WebhookDeliveryRepository
+	tokenService TokenService
+	httpClient   *http.Client
+	now          func() time.Time
+	sleep        func(ctx context.Context, d time.Duration) error
+}
+
+// NewWebhookNotifier posts signed JSON events. Verify sends a challenge and
+// fails with domain.ErrWebhookVerification unless the endpoint echoes it
+// back. Send retries a weather.update with exponential backoff until it is
+// accepted or the attempts run out, recording every attempt; it blocks while
+// retrying.
+func NewWebhookNotifier(cfg config.Config, repo repository.WebhookDeliveryRepository, tokenService TokenService) Notifier {
+	return &webhookNotifier{
+		cfg:          cfg,
+		repo:         repo,
+		tokenService: tokenService,
+		httpClient:   newWebhookHTTPClient(cfg),
+		now:          time.Now,
+		sleep:        sleepContext,
+	}
+}
+
+// newWebhookHTTPClient is shared by every notifier that posts to URLs given
+// by subscribers.
+func newWebhookHTTPClient(cfg config.Config) *http.Client {
+	dialer := &net.Dialer{Timeout: cfg.WebhookTimeout}
+	if !cfg.WebhookAllowPrivateTargets {
+		dialer.Control = denyPrivateAddresses
+	}
+	return &http.Client{
+		Timeout: cfg.WebhookTimeout,
+		// No proxy, so the dialer sees the real target address.
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: cfg.WebhookTimeout,
+			MaxIdleConnsPerHost: 2,
+		},
+		// A redirect is reported as the non-2xx response it is, rather than
+		// followed to a target that was never verified.
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+}
+
+// denyPrivateAddresses runs after DNS resolution, so a public hostname that
+// resolves to an internal address is refused too.
+func denyPrivateAddresses(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return fmt.Errorf("webhook target %s is not a public address", addr)
+	}
+	return nil
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (s *webhookNotifier) Channel() domain.DeliveryChannel {
+	return domain.ChannelWebhook
+}
+
+func (s *webhookNotifier) Verify(ctx context.Context, channel domain.SubscriptionChannel) error {
+	challenge, err := s.tokenService.GenerateToken(24)
+	if err != nil {
+		return fmt.Errorf("service.Verify: %w", err)
+	}
+	event := domain.WebhookChallenge{
+		ID:        uuid.NewString(),
+		Type:      domain.WebhookEventChallenge,
+		Challenge: challenge,
+	}
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("service.Verify: %w", err)
+	}
+
+	status, response, err := s.post(ctx, channel, event.ID, event.Type, body)
+	metrics.ObserveWebhookDelivery(event.Type, err)
+	if err != nil {
+		slog.WarnContext(ctx, "Webhook verification request failed", slog.String("url", channel.Target), slog.Any("error", err))
+		return fmt.Errorf("service.Verify: %w: %v", domain.ErrWebhookVerification, err)
+	}
+	if !echoesChallenge(response, challenge) {
+		slog.WarnContext(ctx, "Webhook did not echo the challenge", slog.String("url", channel.Target), slog.Int("status", status))
+		return fmt.Errorf("service.Verify: %w", domain.ErrWebhookVerification)
+	}
+	return nil
+}
+
+// echoesChallenge accepts the challenge as the plain response body or as
+// {"challenge": "..."}.
+func echoesChallenge(response []byte, challenge string) bool {
+	if strings.TrimSpace(string(response)) == challenge {
+		return true
+	}
+	var echo struct {
+		Challenge string `json:"challenge"`
+	}
+	return json.Unmarshal(response, &echo) == nil && echo.Challenge == challenge
+}
+
+func (s *webhookNotifier) Send(ctx context.Context, channel domain.SubscriptionChannel, msg *Message) error {
+	if msg.Kind != MessageWeatherUpdate {
+		return fmt.Errorf("service.Send: webhooks do not carry %s messages", msg.Kind)
+	}
+	subscription := msg.Subscription
+	event := domain.WebhookWeatherUpdate{
+		ID:             uuid.NewString(),
+		Type:           domain.WebhookEventWeatherUpdate,
+		CreatedAt:      s.now().UTC(),
+		SubscriptionID: subscription.ID,
+		City:           subscription.City,
+		Location:       subscription.Resolved,
+		Weather:        msg.Weather,
+		UnsubscribeURL: msg.UnsubscribeURL,
+	}
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("service.Send: %w", err)
+	}
+
+	maxAttempts := max(s.cfg.WebhookMaxAttempts, 1)
+	backoff := s.cfg.WebhookInitialBackoff
+	for attempt := 1; ; attempt++ {
+		started := s.now()
+		status, _, err := s.post(ctx, channel, event.ID, event.Type, body)
+		metrics.ObserveWebhookDelivery(event.Type, err)
+		s.record(ctx, &domain.WebhookDelivery{
+			SubscriptionID: subscription.ID,
+			EventID:        event.ID,
+			EventType:      event.Type,
+			Attempt:        attempt,
+			URL:            channel.Target,
+			StatusCode:     status,
+			Error:          deliveryError(err),
+			Succeeded:      err == nil,
+			DurationMs:     s.now().Sub(started).Milliseconds(),
+		})
+		if err == nil {
+			return nil
+		}
+		if attempt >= maxAttempts {
+			return fmt.Errorf("service.Send: giving up after %d attempts: %w", attempt, err)
+		}
+
+		slog.WarnContext(ctx, "Webhook delivery failed, retrying",
+			slog.String("subscription_id", subscription.ID.String()),
+			slog.Int("attempt", attempt),
+			slog.Duration("backoff", backoff),
+			slog.Any("error", err))
+		if err := s.sleep(ctx, backoff); err != nil {
+			return fmt.Errorf("service.Send: %w", err)
+		}
+		backoff *= 2
+	}
+}
+
+// record never fails the delivery itself; a lost record only costs
+// visibility.
+func (s *webhookNotifier) record(ctx context.Context, delivery *domain.WebhookDelivery) {
+	if err := s.repo.Create(ctx, delivery); err != nil {
+		slog.ErrorContext(ctx, "Failed to record webhook delivery", slog.String("subscription_id", delivery.SubscriptionID.String()), slog.Any("error", err))
+	}
+}
+
+// post signs and sends body. A non-2xx response is an error; its status is
+// still returned.
+func (s *webhookNotifier) post(ctx context.Context, channel domain.SubscriptionChannel, eventID, eventType string, body []byte) (int, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, channel.Target, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", webhookUserAgent)
+	req.Header.Set(domain.WebhookEventHeader, eventType)
+	req.Header.Set(domain.WebhookIDHeader, eventID)
+	req.Header.Set(domain.WebhookSignatureHeader, SignWebhook(channel.Secret, s.now(), body))
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	response, err := io.ReadAll(io.LimitReader(resp.Body, maxWebhookResponseBytes))
+	if err != nil {
+		return resp.StatusCode, nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, response, fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, response, nil
+}
+
+// SignWebhook returns the X-Webhook-Signature value for body sent at t.
+// Receivers recompute the HMAC over "<t>.<body>" and should reject stale
+// timestamps to prevent replays.
+func SignWebhook(secret string, t time.Time, body []byte) string {
+	timestamp := strconv.FormatInt(t.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "t=" + timestamp + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func deliveryError(err error) string {
+	if err == nil {
+		return ""
+	}
+	message := err.Error()
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		// The URL is stored separately; repeating it here would only eat
+		// into the length limit.
+		message = urlErr.Err.Error()
+	}
+	if len(message) > maxDeliveryErrorLength {
+		message = message[:maxDeliveryErrorLength]
+	}
+	return message
+}
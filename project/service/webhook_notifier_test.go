@@ -0,0 +1,158 @@
+package service_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"weather/project/config"
+	"weather/project/domain"
+	"weather/project/repository"
+	"weather/project/service"
+
+	"github.com/google/uuid"
+)
+
+const webhookSecret = "0123456789abcdef-secret"
+
+// recordingDeliveries keeps the delivery attempts the notifier records.
+type recordingDeliveries struct {
+	repository.WebhookDeliveryRepository
+	mu         sync.Mutex
+	deliveries []domain.WebhookDelivery
+}
+
+func (r *recordingDeliveries) Create(_ context.Context, delivery *domain.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.deliveries = append(r.deliveries, *delivery)
+	return nil
+}
+
+type webhookRequest struct {
+	at        time.Time
+	id        string
+	signature string
+	body      []byte
+}
+
+// verifySignature checks a signature the way a receiver would.
+func verifySignature(signature string, body []byte) bool {
+	timestamp, mac, ok := strings.Cut(signature, ",v1=")
+	timestamp, found := strings.CutPrefix(timestamp, "t=")
+	if !ok || !found {
+		return false
+	}
+	if _, err := strconv.ParseInt(timestamp, 10, 64); err != nil {
+		return false
+	}
+	expected := hmac.New(sha256.New, []byte(webhookSecret))
+	expected.Write([]byte(timestamp + "."))
+	expected.Write(body)
+	got, err := hex.DecodeString(mac)
+	return err == nil && hmac.Equal(got, expected.Sum(nil))
+}
+
+func TestWebhookNotifierSend(t *testing.T) {
+	const backoff = 20 * time.Millisecond
+	cases := []struct {
+		name        string
+		statuses    []int
+		maxAttempts int
+		wantErr     bool
+	}{
+		{name: "accepted right away", statuses: []int{200}, maxAttempts: 3},
+		{name: "accepted on the third attempt", statuses: []int{500, 502, 204}, maxAttempts: 3},
+		{name: "attempts run out", statuses: []int{500, 500, 500}, maxAttempts: 2, wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var requests []webhookRequest
+			endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				mu.Lock()
+				defer mu.Unlock()
+				requests = append(requests, webhookRequest{
+					at:        time.Now(),
+					id:        r.Header.Get(domain.WebhookIDHeader),
+					signature: r.Header.Get(domain.WebhookSignatureHeader),
+					body:      body,
+				})
+				w.WriteHeader(tc.statuses[len(requests)-1])
+			}))
+			defer endpoint.Close()
+
+			deliveries := &recordingDeliveries{}
+			notifier := service.NewWebhookNotifier(config.Config{
+				WebhookMaxAttempts:         tc.maxAttempts,
+				WebhookInitialBackoff:      backoff,
+				WebhookTimeout:             time.Second,
+				WebhookAllowPrivateTargets: true,
+			}, deliveries, service.NewTokenService())
+
+			sub := &domain.Subscription{ID: uuid.New(), City: "Kyiv"}
+			channel := domain.SubscriptionChannel{Kind: domain.ChannelWebhook, Target: endpoint.URL, Secret: webhookSecret}
+			err := notifier.Send(context.Background(), channel, &service.Message{
+				Kind:         service.MessageWeatherUpdate,
+				Subscription: sub,
+				Weather:      &domain.WeatherResponse{Temperature: 12, Condition: domain.ConditionClear},
+			})
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Send: err = %v, want error %v", err, tc.wantErr)
+			}
+
+			wantAttempts := min(len(tc.statuses), tc.maxAttempts)
+			if len(requests) != wantAttempts {
+				t.Fatalf("requests = %d, want %d", len(requests), wantAttempts)
+			}
+			for i, req := range requests {
+				if !verifySignature(req.signature, req.body) {
+					t.Errorf("attempt %d: signature %q does not match the body", i+1, req.signature)
+				}
+				if req.id == "" || req.id != requests[0].id {
+					t.Errorf("attempt %d: event ID %q, want the first attempt's %q", i+1, req.id, requests[0].id)
+				}
+				// The pause doubles after every failed attempt.
+				if i > 0 {
+					wantPause := backoff << (i - 1)
+					if pause := req.at.Sub(requests[i-1].at); pause < wantPause {
+						t.Errorf("attempt %d came %v after the previous one, want at least %v", i+1, pause, wantPause)
+					}
+				}
+			}
+
+			if len(deliveries.deliveries) != wantAttempts {
+				t.Fatalf("recorded deliveries = %d, want %d", len(deliveries.deliveries), wantAttempts)
+			}
+			for i, delivery := range deliveries.deliveries {
+				wantSucceeded := !tc.wantErr && i == wantAttempts-1
+				if delivery.Attempt != i+1 || delivery.StatusCode != tc.statuses[i] || delivery.Succeeded != wantSucceeded || delivery.SubscriptionID != sub.ID {
+					t.Errorf("delivery %d = %+v", i+1, delivery)
+				}
+			}
+		})
+	}
+}
+
+func TestSignWebhookRejectsOtherSecretsAndBodies(t *testing.T) {
+	body := []byte(`{"type":"weather.update"}`)
+	signature := service.SignWebhook(webhookSecret, time.Unix(1791849600, 0), body)
+	if !strings.HasPrefix(signature, "t=1791849600,v1=") || !verifySignature(signature, body) {
+		t.Fatalf("signature %q does not verify", signature)
+	}
+	if verifySignature(signature, []byte(`{"type":"weather.update","x":1}`)) {
+		t.Errorf("signature verifies a different body")
+	}
+	if verifySignature(service.SignWebhook("another-secret-value", time.Unix(1791849600, 0), body), body) {
+		t.Errorf("signature made with another secret verifies")
+	}
+}
@@ -0,0 +1,176 @@
+// Package telegram is the chat interface of the service: a long-polling
+// Telegram bot that looks up weather and manages the chat's subscriptions.
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+	"weather/project/client"
+	"weather/project/domain"
+	"weather/project/i18n"
+	"weather/project/logging"
+	"weather/project/service"
+)
+
+const (
+	// pollRetryDelay is how long the bot waits after a failed getUpdates.
+	pollRetryDelay = 5 * time.Second
+	// commandTimeout bounds the handling of a single message.
+	commandTimeout = 30 * time.Second
+)
+
+type Bot struct {
+	client        *client.TelegramClient
+	weather       service.WeatherService
+	subscriptions service.SubscriptionService
+}
+
+func NewBot(client *client.TelegramClient, weather service.WeatherService, subscriptions service.SubscriptionService) *Bot {
+	return &Bot{client: client, weather: weather, subscriptions: subscriptions}
+}
+
+// Run polls for messages and answers them one at a time until ctx is
+// cancelled.
+func (b *Bot) Run(ctx context.Context) {
+	slog.InfoContext(ctx, "Telegram bot started")
+	var offset int64
+	for ctx.Err() == nil {
+		updates, err := b.client.GetUpdates(ctx, offset)
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			slog.ErrorContext(ctx, "Telegram polling failed", slog.Any("error", err))
+			select {
+			case <-ctx.Done():
+			case <-time.After(pollRetryDelay):
+			}
+			continue
+		}
+		for _, update := range updates {
+			offset = update.UpdateID + 1
+			if update.Message != nil {
+				b.handle(logging.WithRequestID(ctx, fmt.Sprintf("telegram-%d", update.UpdateID)), update.Message)
+			}
+		}
+	}
+	slog.InfoContext(ctx, "Telegram bot stopped")
+}
+
+func (b *Bot) handle(ctx context.Context, msg *domain.TelegramMessage) {
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+
+	lang := i18n.Default
+	if msg.From != nil {
+		if parsed, ok := i18n.Parse(msg.From.LanguageCode); ok {
+			lang = parsed
+		}
+	}
+	ctx = i18n.WithLang(ctx, lang)
+
+	var reply string
+	name, args, ok := msg.Command()
+	switch {
+	case !ok:
+		reply = lang.T(helpText)
+	case name == "start" || name == "help":
+		reply = lang.T(helpText)
+	case name == "weather":
+		reply = b.weatherCommand(ctx, lang, args)
+	case name == "subscribe":
+		reply = b.subscribeCommand(ctx, lang, msg.Chat.ID, args)
+	case name == "unsubscribe":
+		reply = b.unsubscribeCommand(ctx, lang, msg.Chat.ID, args)
+	case name == "list":
+		reply = b.listCommand(ctx, lang, msg.Chat.ID)
+	default:
+		reply = lang.T("Unknown command.") + "\n\n" + lang.T(helpText)
+	}
+
+	if err := b.client.SendMessage(ctx, msg.Chat.ID, reply); err != nil {
+		slog.ErrorContext(ctx, "Failed to answer Telegram message", slog.String("command", name), slog.Any("error", err))
+	}
+}
+
+const helpText = "Commands:\n/weather <city> - current weather\n/subscribe <city> <hourly|daily> - get regular updates\n/unsubscribe [city] - stop updates for a city, or for all cities\n/list - your subscriptions"
+
+func (b *Bot) weatherCommand(ctx context.Context, lang i18n.Lang, args []string) string {
+	city := strings.Join(args, " ")
+	if city == "" {
+		return lang.T("Usage: /weather <city>")
+	}
+	weather, err := b.weather.GetWeather(ctx, domain.NewCityLocation(city), domain.WeatherOptions{})
+	if err != nil {
+		return errorReply(ctx, lang, city, err)
+	}
+	summary := lang.Tf("Temperature: %.1f°C\nHumidity: %.0f%%\nDescription: %s\n", weather.Temperature, weather.Humidity, weather.Description)
+	return lang.Tf("Weather in %s:\n", city) + strings.TrimSuffix(summary, "\n")
+}
+
+// subscribeCommand takes the frequency last, so city names may contain
+// spaces.
+func (b *Bot) subscribeCommand(ctx context.Context, lang i18n.Lang, chatID int64, args []string) string {
+	if len(args) < 2 {
+		return lang.T("Usage: /subscribe <city> <hourly|daily>")
+	}
+	frequency := domain.SubscriptionFrequency(strings.ToLower(args[len(args)-1]))
+	if frequency != domain.FrequencyHourly && frequency != domain.FrequencyDaily {
+		return lang.T("Usage: /subscribe <city> <hourly|daily>")
+	}
+	city := strings.Join(args[:len(args)-1], " ")
+
+	sub, err := b.subscriptions.SubscribeChat(ctx, chatID, city, frequency)
+	if err != nil {
+		return errorReply(ctx, lang, city, err)
+	}
+	return lang.Tf("You will get %s weather updates for %s.", lang.T(string(sub.Frequency)), sub.City)
+}
+
+func (b *Bot) unsubscribeCommand(ctx context.Context, lang i18n.Lang, chatID int64, args []string) string {
+	city := strings.Join(args, " ")
+	removed, err := b.subscriptions.UnsubscribeChat(ctx, chatID, city)
+	switch {
+	case err != nil:
+		return errorReply(ctx, lang, city, err)
+	case removed == 0 && city == "":
+		return lang.T("You have no subscriptions.")
+	case removed == 0:
+		return lang.Tf("You are not subscribed to %s.", city)
+	case city == "":
+		return lang.T("Unsubscribed from all cities.")
+	default:
+		return lang.Tf("Unsubscribed from %s.", city)
+	}
+}
+
+func (b *Bot) listCommand(ctx context.Context, lang i18n.Lang, chatID int64) string {
+	subs, err := b.subscriptions.ListChat(ctx, chatID)
+	if err != nil {
+		return errorReply(ctx, lang, "", err)
+	}
+	if len(subs) == 0 {
+		return lang.T("You have no subscriptions.")
+	}
+	lines := []string{lang.T("Your subscriptions:")}
+	for _, sub := range subs {
+		lines = append(lines, fmt.Sprintf("• %s — %s", sub.City, lang.T(string(sub.Frequency))))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func errorReply(ctx context.Context, lang i18n.Lang, city string, err error) string {
+	switch {
+	case errors.Is(err, domain.ErrCityNotFound):
+		return lang.Tf("City not found: %s", city)
+	case errors.Is(err, domain.ErrChatSubscriptionLimit):
+		return lang.Tf("You can follow at most %d cities. Remove one with /unsubscribe <city>.", domain.MaxSubscriptionsPerChat)
+	default:
+		slog.ErrorContext(ctx, "Telegram command failed", slog.Any("error", err))
+		return lang.T("Something went wrong. Please try again later.")
+	}
+}
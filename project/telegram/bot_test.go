@@ -0,0 +1,260 @@
+package telegram_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"weather/project/client"
+	"weather/project/config"
+	"weather/project/domain"
+	"weather/project/repository"
+	"weather/project/service"
+	"weather/project/telegram"
+
+	"github.com/google/uuid"
+)
+
+const (
+	botToken = "123456:test-token"
+	chatID   = int64(4242)
+)
+
+// fakeBotAPI serves getUpdates from a queue of messages and records what
+// the bot sends back.
+type fakeBotAPI struct {
+	t       *testing.T
+	mu      sync.Mutex
+	updates []domain.TelegramUpdate
+	nextID  int64
+	replies chan string
+}
+
+func newFakeBotAPI(t *testing.T) (*fakeBotAPI, *httptest.Server) {
+	api := &fakeBotAPI{t: t, nextID: 1, replies: make(chan string, 16)}
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+	return api, server
+}
+
+func (f *fakeBotAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	method, ok := strings.CutPrefix(r.URL.Path, "/bot"+botToken+"/")
+	if !ok {
+		http.Error(w, `{"ok":false,"error_code":401,"description":"Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+	var params struct {
+		Offset int64  `json:"offset"`
+		ChatID int64  `json:"chat_id"`
+		Text   string `json:"text"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		f.t.Errorf("%s: decoding request: %v", method, err)
+	}
+
+	var result any = true
+	switch method {
+	case "getUpdates":
+		result = f.pending(r.Context(), params.Offset)
+	case "sendMessage":
+		if params.ChatID != chatID {
+			f.t.Errorf("sendMessage to chat %d, want %d", params.ChatID, chatID)
+		}
+		f.replies <- params.Text
+	default:
+		f.t.Errorf("unexpected Bot API method %q", method)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"ok": true, "result": result})
+}
+
+// pending returns the updates from offset on, waiting briefly for one as a
+// long poll would.
+func (f *fakeBotAPI) pending(ctx context.Context, offset int64) []domain.TelegramUpdate {
+	deadline := time.After(50 * time.Millisecond)
+	for {
+		f.mu.Lock()
+		var pending []domain.TelegramUpdate
+		for _, update := range f.updates {
+			if update.UpdateID >= offset {
+				pending = append(pending, update)
+			}
+		}
+		f.mu.Unlock()
+		if len(pending) > 0 {
+			return pending
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-deadline:
+			return []domain.TelegramUpdate{}
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+}
+
+// send queues text as a message from the test chat and returns the bot's
+// answer.
+func (f *fakeBotAPI) send(text, language string) string {
+	f.t.Helper()
+	f.mu.Lock()
+	f.updates = append(f.updates, domain.TelegramUpdate{
+		UpdateID: f.nextID,
+		Message: &domain.TelegramMessage{
+			MessageID: f.nextID,
+			From:      &domain.TelegramUser{ID: 7, LanguageCode: language},
+			Chat:      domain.TelegramChat{ID: chatID, Type: "private"},
+			Text:      text,
+		},
+	})
+	f.nextID++
+	f.mu.Unlock()
+
+	select {
+	case reply := <-f.replies:
+		return reply
+	case <-time.After(5 * time.Second):
+		f.t.Fatalf("no reply to %q", text)
+		return ""
+	}
+}
+
+type fakeWeather struct{ service.WeatherService }
+
+func (fakeWeather) GetWeather(_ context.Context, location domain.Location, _ domain.WeatherOptions) (*domain.WeatherResponse, error) {
+	if location.Value == "Nowhere" {
+		return nil, domain.ErrCityNotFound
+	}
+	return &domain.WeatherResponse{Temperature: 12.5, Humidity: 60, Description: "Sunny", Condition: domain.ConditionClear}, nil
+}
+
+// fakeLocations knows every city but Nowhere and spells Kiev the provider's
+// way.
+type fakeLocations struct{ service.LocationService }
+
+func (fakeLocations) Resolve(_ context.Context, location domain.Location) (*domain.ResolvedLocation, error) {
+	switch location.Value {
+	case "Nowhere":
+		return nil, domain.ErrCityNotFound
+	case "Kiev":
+		return &domain.ResolvedLocation{Name: "Kyiv", Country: "Ukraine"}, nil
+	}
+	return &domain.ResolvedLocation{Name: location.Value}, nil
+}
+
+// chatRepo keeps the chat's subscriptions in memory.
+type chatRepo struct {
+	repository.SubscriptionRepository
+	mu   sync.Mutex
+	subs []domain.Subscription
+}
+
+func (r *chatRepo) FindByChat(_ context.Context, chatID int64) ([]domain.Subscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var found []domain.Subscription
+	for _, sub := range r.subs {
+		if *sub.TelegramChatID == chatID {
+			found = append(found, sub)
+		}
+	}
+	return found, nil
+}
+
+func (r *chatRepo) Create(_ context.Context, sub *domain.Subscription) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sub.ID = uuid.New()
+	r.subs = append(r.subs, *sub)
+	return nil
+}
+
+func (r *chatRepo) Update(_ context.Context, sub *domain.Subscription) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.subs {
+		if r.subs[i].ID == sub.ID {
+			r.subs[i] = *sub
+		}
+	}
+	return nil
+}
+
+func (r *chatRepo) DeleteByChat(_ context.Context, chatID int64, city string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var kept []domain.Subscription
+	for _, sub := range r.subs {
+		if *sub.TelegramChatID != chatID || (city != "" && sub.City != city) {
+			kept = append(kept, sub)
+		}
+	}
+	removed := int64(len(r.subs) - len(kept))
+	r.subs = kept
+	return removed, nil
+}
+
+func TestBotCommands(t *testing.T) {
+	api, server := newFakeBotAPI(t)
+	telegramClient := client.NewTelegramClient(config.Config{TelegramBotToken: botToken, TelegramAPIURL: server.URL, TelegramPollTimeout: time.Second})
+	repo := &chatRepo{}
+	subscriptions := service.NewSubscriptionService(repo, service.NewTokenService(), service.NewNotificationService(config.Config{}), fakeLocations{})
+	bot := telegram.NewBot(telegramClient, fakeWeather{}, subscriptions)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		bot.Run(ctx)
+		close(stopped)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-stopped
+	})
+
+	steps := []struct {
+		text, language, want string
+	}{
+		{"/weather Kyiv", "en", "Weather in Kyiv:\nTemperature: 12.5°C\nHumidity: 60%\nDescription: Sunny"},
+		{"/weather Nowhere", "en", "City not found: Nowhere"},
+		{"/weather", "en", "Usage: /weather <city>"},
+		{"/subscribe Ivano Frankivsk daily", "en", "You will get daily weather updates for Ivano Frankivsk."},
+		{"/subscribe Kiev HOURLY", "uk", "Ви отримуватимете щогодинні оновлення погоди для Kyiv."},
+		{"/subscribe Kyiv weekly", "en", "Usage: /subscribe <city> <hourly|daily>"},
+		{"/list", "en", "Your subscriptions:\n• Ivano Frankivsk — daily\n• Kyiv — hourly"},
+		{"/unsubscribe Kiev", "en", "Unsubscribed from Kiev."},
+		{"/unsubscribe Lviv", "en", "You are not subscribed to Lviv."},
+		{"/list", "en", "Your subscriptions:\n• Ivano Frankivsk — daily"},
+		{"/unsubscribe", "en", "Unsubscribed from all cities."},
+		{"/list", "en", "You have no subscriptions."},
+	}
+	for _, step := range steps {
+		if got := api.send(step.text, step.language); got != step.want {
+			t.Errorf("%s: reply = %q, want %q", step.text, got, step.want)
+		}
+	}
+
+	for i := range domain.MaxSubscriptionsPerChat {
+		city := fmt.Sprintf("City %d", i+1)
+		if got, want := api.send("/subscribe "+city+" daily", "en"), "You will get daily weather updates for "+city+"."; got != want {
+			t.Fatalf("subscription %d: reply = %q, want %q", i+1, got, want)
+		}
+	}
+	want := fmt.Sprintf("You can follow at most %d cities. Remove one with /unsubscribe <city>.", domain.MaxSubscriptionsPerChat)
+	if got := api.send("/subscribe One Too Many daily", "en"); got != want {
+		t.Errorf("subscription over the limit: reply = %q, want %q", got, want)
+	}
+	// Changing the frequency of a followed city is not a new subscription.
+	if got := api.send("/subscribe City 3 hourly", "en"); got != "You will get hourly weather updates for City 3." {
+		t.Errorf("frequency change at the limit: reply = %q", got)
+	}
+	if subs, _ := repo.FindByChat(context.Background(), chatID); len(subs) != domain.MaxSubscriptionsPerChat {
+		t.Errorf("chat has %d subscriptions, want %d", len(subs), domain.MaxSubscriptionsPerChat)
+	}
+}
@@ -0,0 +1,53 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"weather/project/config"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// Init installs the global tracer provider and W3C propagators. When tracing
+// is disabled, the no-op provider stays in place and spans cost nothing.
+// The returned function flushes pending spans and must be called on exit.
+func Init(ctx context.Context, cfg config.Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	if !cfg.OTelEnabled {
+		slog.Info("Tracing is disabled.")
+		return func(context.Context) error { return nil }, nil
+	}
+
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpointURL(cfg.OTelExporterEndpoint)}
+	if cfg.OTelExporterInsecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("tracing.Init: failed to create OTLP exporter: %w", err)
+	}
+
+	tp := NewTracerProvider(sdktrace.NewBatchSpanProcessor(exporter), cfg.OTelServiceName, cfg.OTelSampleRatio)
+	otel.SetTracerProvider(tp)
+
+	slog.Info("Tracing enabled", slog.String("endpoint", cfg.OTelExporterEndpoint), slog.Float64("sample_ratio", cfg.OTelSampleRatio))
+	return tp.Shutdown, nil
+}
+
+// NewTracerProvider builds a provider around any span processor, e.g. a
+// synchronous one feeding an in-memory tracetest exporter when asserting on
+// spans.
+func NewTracerProvider(processor sdktrace.SpanProcessor, serviceName string, sampleRatio float64) *sdktrace.TracerProvider {
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithSpanProcessor(processor),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(serviceName))),
+	)
+}
@@ -0,0 +1,142 @@
+package tracing_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"weather/project/client"
+	"weather/project/config"
+	"weather/project/domain"
+	"weather/project/middleware"
+	"weather/project/repository"
+	"weather/project/tracing"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+const testAPIKey = "trace-test-api-key"
+
+// upstreamTransport sends every outgoing request to the fake provider and
+// records the URL it was really called with.
+type upstreamTransport struct {
+	target *url.URL
+	base   http.RoundTripper
+	calls  []string
+}
+
+func (t *upstreamTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.calls = append(t.calls, req.URL.String())
+	redirected := req.Clone(req.Context())
+	redirected.URL.Scheme, redirected.URL.Host = t.target.Scheme, t.target.Host
+	return t.base.RoundTrip(redirected)
+}
+
+func TestRequestSpans(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := tracing.NewTracerProvider(sdktrace.NewSimpleSpanProcessor(exporter), "weather-test", 1)
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(tp)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"location":{"name":"Kyiv","country":"Ukraine"},"current":{"temp_c":12.5,"humidity":60,"condition":{"text":"Sunny","code":1000}}}`))
+	}))
+	t.Cleanup(provider.Close)
+	target, _ := url.Parse(provider.URL)
+	// The client wraps http.DefaultTransport when it is built.
+	upstream := &upstreamTransport{target: target, base: http.DefaultTransport}
+	http.DefaultTransport = upstream
+	t.Cleanup(func() { http.DefaultTransport = upstream.base })
+
+	db, err := gorm.Open(mysql.New(mysql.Config{DSN: "user:pass@tcp(127.0.0.1:1)/weather", SkipInitializeWithVersion: true}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatalf("gorm.Open: %v", err)
+	}
+	if err := repository.RegisterTracingCallbacks(db); err != nil {
+		t.Fatalf("RegisterTracingCallbacks: %v", err)
+	}
+
+	weatherClient := client.NewWeatherAPIClient(config.Config{WeatherAPIKey: testAPIKey})
+	router := gin.New()
+	router.Use(middleware.Tracing())
+	router.GET("/weather", func(c *gin.Context) {
+		ctx := c.Request.Context()
+		if _, err := weatherClient.GetCurrentObservation(ctx, domain.NewCityLocation("Kyiv"), domain.WeatherOptions{}); err != nil {
+			t.Errorf("GetCurrentObservation: %v", err)
+		}
+		var subs []domain.Subscription
+		db.WithContext(ctx).Where("city = ?", "Kyiv").Find(&subs)
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/weather?city=Kyiv", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d", w.Code)
+	}
+
+	spans := exporter.GetSpans()
+	find := func(name string) tracetest.SpanStub {
+		t.Helper()
+		for _, span := range spans {
+			if span.Name == name {
+				return span
+			}
+		}
+		t.Fatalf("no %q span among %d spans", name, len(spans))
+		return tracetest.SpanStub{}
+	}
+
+	server := find("GET /weather")
+	if server.SpanKind != trace.SpanKindServer {
+		t.Errorf("server span kind = %v", server.SpanKind)
+	}
+
+	upstreamSpan := find("WeatherAPI /v1/current.json")
+	if upstreamSpan.SpanKind != trace.SpanKindClient {
+		t.Errorf("upstream span kind = %v", upstreamSpan.SpanKind)
+	}
+	if upstreamSpan.Parent.SpanID() != server.SpanContext.SpanID() {
+		t.Errorf("upstream span is not a child of the request span")
+	}
+	var sawURL bool
+	for _, attr := range upstreamSpan.Attributes {
+		value := attr.Value.Emit()
+		if strings.Contains(value, testAPIKey) || strings.Contains(value, "key=") {
+			t.Errorf("upstream span attribute %s leaks the API key: %s", attr.Key, value)
+		}
+		if attr.Key == "url.full" || attr.Key == "http.url" {
+			sawURL = strings.Contains(value, "q=Kyiv")
+		}
+	}
+	if !sawURL {
+		t.Errorf("upstream span has no URL attribute: %v", upstreamSpan.Attributes)
+	}
+	if len(upstream.calls) != 1 || !strings.Contains(upstream.calls[0], "key="+testAPIKey) {
+		t.Errorf("provider was not called with the API key: %v", upstream.calls)
+	}
+
+	query := find("gorm.query")
+	if query.SpanKind != trace.SpanKindClient {
+		t.Errorf("gorm span kind = %v", query.SpanKind)
+	}
+	if query.Parent.SpanID() != server.SpanContext.SpanID() {
+		t.Errorf("gorm span is not a child of the request span")
+	}
+	for _, attr := range query.Attributes {
+		if attr.Key == "db.query.text" && strings.Contains(attr.Value.Emit(), "Kyiv") {
+			t.Errorf("gorm span records bound values: %s", attr.Value.Emit())
+		}
+	}
+}